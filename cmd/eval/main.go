@@ -0,0 +1,255 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"code-sentinel/internal/config"
+	"code-sentinel/internal/eval"
+	"code-sentinel/internal/service"
+	"code-sentinel/internal/store"
+
+	"go.uber.org/zap"
+)
+
+const usage = `Usage:
+  eval run -dataset DIR [-model NAME] [-tolerance N] [-case NAME] [-out report.json]
+  eval export -dataset DIR [-repo owner/repo] [-start-date YYYY-MM-DD] [-end-date YYYY-MM-DD]
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	// 评测输出写到标准输出，日志只保留警告以上
+	logCfg := zap.NewProductionConfig()
+	logCfg.Level = zap.NewAtomicLevelAt(zap.WarnLevel)
+	logger, _ := logCfg.Build()
+	defer logger.Sync()
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	switch os.Args[1] {
+	case "run":
+		err = runEval(cfg, logger, os.Args[2:])
+	case "export":
+		err = runExport(cfg, logger, os.Args[2:])
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// runEval 将数据集中的每个用例交给真实的审查流水线，输出命中统计和逐用例对比
+func runEval(cfg *config.Config, logger *zap.Logger, args []string) error {
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	dataset := fs.String("dataset", "", "dataset directory")
+	modelName := fs.String("model", "", "override llm.model")
+	tolerance := fs.Int("tolerance", eval.DefaultLineTolerance, "allowed line offset when matching findings")
+	only := fs.String("case", "", "only run cases whose name contains this string")
+	out := fs.String("out", "", "write the JSON report to this file")
+	fs.Parse(args)
+	if *dataset == "" {
+		return fmt.Errorf("-dataset is required")
+	}
+
+	cases, reviewConfig, err := eval.LoadDataset(*dataset)
+	if err != nil {
+		return err
+	}
+	if *modelName != "" {
+		cfg.LLM.Model = *modelName
+	}
+
+	// 使用临时数据库，评测不读写线上的仓库配置、实验和缓存
+	tmpDir, err := os.MkdirTemp("", "code-sentinel-eval-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+	db, err := store.NewSQLiteStore(filepath.Join(tmpDir, "eval.db"))
+	if err != nil {
+		return fmt.Errorf("failed to init database: %w", err)
+	}
+
+	analyzerSvc := newAnalyzer(cfg, db, logger)
+	ctx := context.Background()
+
+	var reports []eval.CaseReport
+	for _, c := range cases {
+		if *only != "" && !strings.Contains(c.Name, *only) {
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "running %s ...\n", c.Name)
+
+		result, err := analyzerSvc.RunPlayground(ctx, &service.PlaygroundRequest{Diff: c.Diff, Config: reviewConfig})
+		if err != nil {
+			report := eval.ScoreCase(c, nil, *tolerance)
+			report.Error = err.Error()
+			if result != nil {
+				report.Usage = result.Usage
+			}
+			reports = append(reports, report)
+			continue
+		}
+
+		var report eval.CaseReport
+		if result.Result != nil {
+			report = eval.ScoreCase(c, result.Result.Issues, *tolerance)
+		} else {
+			report = eval.ScoreCase(c, nil, *tolerance)
+		}
+		report.Skipped = result.Skipped
+		report.Usage = result.Usage
+		reports = append(reports, report)
+	}
+
+	report := eval.Summarize(cfg.LLM.Model, reports)
+	printReport(report)
+
+	if *out != "" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(*out, append(data, '\n'), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runExport 将存储的反馈导出为数据集用例
+func runExport(cfg *config.Config, logger *zap.Logger, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	dataset := fs.String("dataset", "", "output dataset directory")
+	repo := fs.String("repo", "", "only export feedback of this repo")
+	startDate := fs.String("start-date", "", "YYYY-MM-DD")
+	endDate := fs.String("end-date", "", "YYYY-MM-DD")
+	fs.Parse(args)
+	if *dataset == "" {
+		return fmt.Errorf("-dataset is required")
+	}
+
+	db, err := store.NewSQLiteStore(cfg.Database.Path)
+	if err != nil {
+		return fmt.Errorf("failed to init database: %w", err)
+	}
+	githubSvc := service.NewGitHubService(cfg.GitHub, logger)
+
+	n, err := eval.ExportFeedback(context.Background(), db, githubSvc, *dataset, eval.ExportOptions{
+		Repo:      *repo,
+		StartDate: *startDate,
+		EndDate:   *endDate,
+	}, logger)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("exported %d cases to %s\n", n, *dataset)
+	return nil
+}
+
+// newAnalyzer 按服务端相同的方式组装审查服务
+func newAnalyzer(cfg *config.Config, db store.Store, logger *zap.Logger) *service.AnalyzerService {
+	githubSvc := service.NewGitHubService(cfg.GitHub, logger)
+	service.InitTokenizer(cfg.LLM, logger)
+	llmSvc := service.NewLLMService(cfg.LLM, logger)
+	defaultLLMCfg := service.LLMConfig{
+		Provider:  cfg.LLM.Provider,
+		APIKey:    cfg.LLM.APIKey,
+		Model:     cfg.LLM.Model,
+		BaseURL:   cfg.LLM.BaseURL,
+		Timeout:   cfg.LLM.Timeout,
+		MaxTokens: cfg.LLM.MaxTokens,
+		Stream:    cfg.LLM.Stream,
+
+		ContextWindow:    cfg.LLM.ContextWindow,
+		Temperature:      cfg.LLM.Temperature,
+		StructuredOutput: cfg.LLM.StructuredOutput,
+
+		Endpoints: cfg.LLM.Endpoints,
+	}
+	defaultGHCfg := service.GitHubConfig{
+		Token:   cfg.GitHub.Token,
+		BaseURL: cfg.GitHub.BaseURL,
+	}
+	llmCache := service.NewLLMCache(db, config.CacheConfig{}, logger)
+	pricing := service.NewCostCalculator(cfg.Pricing)
+	// 数据集只有 Diff，没有可检出的提交，不执行外部检查工具
+	linters := service.NewLinterRunner(config.LintersConfig{}, logger)
+	return service.NewAnalyzerService(githubSvc, llmSvc, db, llmCache, pricing, linters, logger, defaultLLMCfg, defaultGHCfg)
+}
+
+// printReport 打印汇总指标和逐用例的期望/实际对比
+func printReport(report *eval.Report) {
+	fmt.Printf("model: %s  cases: %d  errors: %d  tokens: %d\n\n",
+		report.Model, report.Cases, report.Errors, report.Usage.TotalTokens)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "GROUP\tKEY\tTP\tFP\tFN\tPRECISION\tRECALL\tF1")
+	printMetrics(w, "overall", "-", &report.Overall)
+	for _, k := range eval.SortedKeys(report.ByCategory) {
+		printMetrics(w, "category", k, report.ByCategory[k])
+	}
+	for _, k := range eval.SortedKeys(report.BySeverity) {
+		printMetrics(w, "severity", k, report.BySeverity[k])
+	}
+	w.Flush()
+
+	for _, c := range report.Details {
+		fmt.Printf("\n== %s\n", c.Name)
+		if c.Error != "" {
+			fmt.Printf("  ! error: %s\n", c.Error)
+		}
+		if c.Skipped != "" {
+			fmt.Printf("  ! skipped: %s\n", c.Skipped)
+		}
+		for _, m := range c.Matched {
+			note := ""
+			if m.SeverityMismatch {
+				note = fmt.Sprintf(" (severity %s, expected %s)", m.Found.Severity, m.Expected.Severity)
+			}
+			fmt.Printf("  ✓ %s:%d [%s/%s] %s%s\n", m.Found.File, m.Found.Line, m.Expected.Category, m.Expected.Severity, m.Found.Title, note)
+		}
+		for _, f := range c.Missed {
+			fmt.Printf("  ✗ %s:%s [%s/%s] %s\n", f.File, lineRange(f), f.Category, f.Severity, f.Title)
+		}
+		for _, e := range c.Extra {
+			note := ""
+			if e.KnownFalsePositive {
+				note = " (known false positive)"
+			}
+			fmt.Printf("  + %s:%d [%s/%s] %s%s\n", e.Found.File, e.Found.Line, e.Found.Category, e.Found.Severity, e.Found.Title, note)
+		}
+		if c.Ignored > 0 {
+			fmt.Printf("  ? %d unlabeled finding(s) not scored (rejected-only case)\n", c.Ignored)
+		}
+	}
+}
+
+func printMetrics(w *tabwriter.Writer, group, key string, m *eval.Metrics) {
+	fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%.3f\t%.3f\t%.3f\n",
+		group, key, m.TruePositives, m.FalsePositives, m.FalseNegatives, m.Precision, m.Recall, m.F1)
+}
+
+func lineRange(f eval.Finding) string {
+	if f.LineEnd > f.LineStart {
+		return fmt.Sprintf("%d-%d", f.LineStart, f.LineEnd)
+	}
+	return fmt.Sprintf("%d", f.LineStart)
+}
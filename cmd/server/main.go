@@ -44,8 +44,12 @@ func main() {
 
 	// 初始化服务层
 	githubSvc := service.NewGitHubService(cfg.GitHub, logger)
+	service.InitTokenizer(cfg.LLM, logger)
 	llmSvc := service.NewLLMService(cfg.LLM, logger)
 	repoSvc := service.NewRepoService(db, logger)
+	ruleSvc := service.NewRuleService(db, logger)
+	promptSvc := service.NewPromptService(db, logger)
+	depSvc := service.NewDependencyService(db, logger)
 
 	// 构建默认配置用于仓库级覆盖
 	defaultLLMCfg := service.LLMConfig{
@@ -55,6 +59,13 @@ func main() {
 		BaseURL:   cfg.LLM.BaseURL,
 		Timeout:   cfg.LLM.Timeout,
 		MaxTokens: cfg.LLM.MaxTokens,
+		Stream:    cfg.LLM.Stream,
+
+		ContextWindow:    cfg.LLM.ContextWindow,
+		Temperature:      cfg.LLM.Temperature,
+		StructuredOutput: cfg.LLM.StructuredOutput,
+
+		Endpoints: cfg.LLM.Endpoints,
 	}
 	defaultGHCfg := service.GitHubConfig{
 		Token:   cfg.GitHub.Token,
@@ -62,10 +73,13 @@ func main() {
 	}
 
 	feedbackSvc := service.NewFeedbackService(db, githubSvc, logger, defaultGHCfg)
-	analyzerSvc := service.NewAnalyzerService(githubSvc, llmSvc, db, logger, defaultLLMCfg, defaultGHCfg)
+	llmCache := service.NewLLMCache(db, cfg.Cache, logger)
+	pricing := service.NewCostCalculator(cfg.Pricing)
+	linters := service.NewLinterRunner(cfg.Linters, logger)
+	analyzerSvc := service.NewAnalyzerService(githubSvc, llmSvc, db, llmCache, pricing, linters, logger, defaultLLMCfg, defaultGHCfg)
 
 	// 初始化 Handler
-	h := handler.NewHandler(analyzerSvc, repoSvc, feedbackSvc, db, cfg, logger)
+	h := handler.NewHandler(analyzerSvc, repoSvc, feedbackSvc, ruleSvc, promptSvc, depSvc, db, cfg, logger)
 
 	// 设置路由
 	router := gin.New()
@@ -103,17 +117,51 @@ func main() {
 		api.DELETE("/repos/:id", h.DeleteRepo)
 		api.PUT("/repos/:id/toggle", h.ToggleRepo)
 
+		// 审查规则
+		api.GET("/repos/:id/rules", h.ListRules)
+		api.POST("/repos/:id/rules", h.CreateRule)
+		api.GET("/repos/:id/rules/stats", h.GetRuleStats)
+		api.PUT("/rules/:id", h.UpdateRule)
+		api.DELETE("/rules/:id", h.DeleteRule)
+		api.GET("/builtin-rules", h.ListBuiltinRules)
+
+		// 漏洞公告库
+		api.GET("/advisories", h.ListAdvisories)
+		api.POST("/advisories/import", h.ImportAdvisories)
+
+		// 提示词版本与实验
+		api.GET("/prompt-versions", h.ListPromptVersions)
+		api.POST("/prompt-versions", h.CreatePromptVersion)
+		api.GET("/prompt-versions/:id", h.GetPromptVersion)
+		api.GET("/repos/:id/experiments", h.ListExperiments)
+		api.POST("/repos/:id/experiments", h.CreateExperiment)
+		api.POST("/experiments/:id/stop", h.StopExperiment)
+		api.GET("/experiments/:id/report", h.GetExperimentReport)
+		api.POST("/playground/run", h.RunPlayground)
+
 		// 审查记录
 		api.GET("/reviews", h.ListReviews)
 		api.GET("/reviews/:id", h.GetReview)
+		api.GET("/reviews/:id/events", h.StreamReviewEvents)
+		api.POST("/reviews/:id/rerun", h.RerunReview)
+		api.GET("/reviews/:id/comparison", h.GetReviewComparison)
+		api.GET("/reviews/:id/tool-calls", h.ListReviewToolCalls)
 
 		// 反馈管理
 		api.GET("/feedbacks", h.ListFeedbacks)
 		api.POST("/feedbacks", h.CreateFeedback)
 		api.GET("/feedbacks/stats", h.GetFeedbackStats)
 
+		// 成本统计
+		api.GET("/stats/cost", h.GetCostSummary)
+		api.GET("/stats/cost/repos", h.GetCostByRepo)
+		api.GET("/stats/cost/models", h.GetCostByModel)
+		api.GET("/stats/cost/daily", h.GetCostByDay)
+		api.GET("/stats/comparison/models", h.GetComparisonModelStats)
+
 		// 配置模板
 		api.GET("/config-templates", h.GetConfigTemplates)
+		api.GET("/language-templates", h.GetLanguageTemplates)
 
 		// 全局配置
 		api.GET("/configs", h.ListConfigs)
@@ -144,6 +192,9 @@ func main() {
 	if err := srv.Shutdown(ctx); err != nil {
 		sugar.Fatalf("Server forced to shutdown: %v", err)
 	}
+	if err := analyzerSvc.WaitComparisons(ctx); err != nil {
+		sugar.Warnf("Pending model comparisons abandoned: %v", err)
+	}
 
 	sugar.Info("Server exited")
 }
@@ -13,7 +13,11 @@ type Config struct {
 	GitHub   GitHubConfig   `mapstructure:"github"`
 	LLM      LLMConfig      `mapstructure:"llm"`
 	Review   ReviewConfig   `mapstructure:"review"`
+	Cache    CacheConfig    `mapstructure:"cache"`
+	Pricing  PricingConfig  `mapstructure:"pricing"`
 	Log      LogConfig      `mapstructure:"log"`
+
+	Linters LintersConfig `mapstructure:"linters"`
 }
 
 type ServerConfig struct {
@@ -47,6 +51,29 @@ type LLMConfig struct {
 	BaseURL   string `mapstructure:"base_url"`
 	Timeout   int    `mapstructure:"timeout"`
 	MaxTokens int    `mapstructure:"max_tokens"`
+	Stream    bool   `mapstructure:"stream"` // 是否使用流式输出
+	// 上下文窗口大小，0 表示按模型查表，表中没有的模型按 8192 保守估计
+	ContextWindow int     `mapstructure:"context_window"`
+	Temperature   float64 `mapstructure:"temperature"`
+	// 结构化输出：auto（按提供商选择）/ json_schema / json_object / off
+	StructuredOutput string `mapstructure:"structured_output"`
+
+	// tiktoken 词表目录（cl100k_base.tiktoken、o200k_base.tiktoken），文件按 SHA-256 校验
+	TokenizerDir string `mapstructure:"tokenizer_dir"`
+	// 是否允许从 OpenAI 下载目录中缺失的词表，默认不访问网络
+	TokenizerDownload bool `mapstructure:"tokenizer_download"`
+
+	// 复核和多模型对比可选用的其他端点，仓库配置只能按名称引用，不能指定地址和凭据
+	Endpoints []LLMEndpoint `mapstructure:"endpoints"`
+}
+
+// LLMEndpoint 服务端定义的模型端点
+type LLMEndpoint struct {
+	Name     string `mapstructure:"name"`     // 仓库配置引用的名称
+	Provider string `mapstructure:"provider"` // 为空时沿用 llm.provider
+	BaseURL  string `mapstructure:"base_url"`
+	APIKey   string `mapstructure:"api_key"`
+	Model    string `mapstructure:"model"` // 仓库配置未指定模型时使用
 }
 
 type ReviewConfig struct {
@@ -55,18 +82,67 @@ type ReviewConfig struct {
 	IgnorePatterns []string `mapstructure:"ignore_patterns"`
 }
 
+// CacheConfig LLM 响应缓存配置
+type CacheConfig struct {
+	Enabled    bool `mapstructure:"enabled"`
+	TTLHours   int  `mapstructure:"ttl_hours"`
+	MaxEntries int  `mapstructure:"max_entries"`
+}
+
+// PricingConfig 模型价格表
+type PricingConfig struct {
+	Currency string       `mapstructure:"currency"`
+	Models   []ModelPrice `mapstructure:"models"`
+}
+
+// ModelPrice 单个模型的价格（每 1K Token）
+type ModelPrice struct {
+	Provider        string  `mapstructure:"provider"` // 为空表示任意提供商
+	Model           string  `mapstructure:"model"`    // 精确匹配或前缀匹配
+	PromptPrice     float64 `mapstructure:"prompt_price"`
+	CompletionPrice float64 `mapstructure:"completion_price"`
+}
+
+// LintersConfig 外部检查工具配置
+// 命令只能在服务端配置，仓库配置只能选择启用哪些工具
+type LintersConfig struct {
+	CloneURL     string       `mapstructure:"clone_url"`     // 仓库地址模板，{repo} 替换为 owner/name，可以是任意 Git 远端或本地裸仓库路径
+	WorkDir      string       `mapstructure:"work_dir"`      // 检出工作区的父目录，为空使用系统临时目录
+	FetchTimeout int          `mapstructure:"fetch_timeout"` // 拉取代码超时（秒）
+	Timeout      int          `mapstructure:"timeout"`       // 单个工具的默认超时（秒）
+	MaxOutput    int          `mapstructure:"max_output"`    // 单个工具输出上限（字节）
+	Env          []string     `mapstructure:"env"`           // 透传给工具的环境变量名，如 GOPATH、GOCACHE，其他环境变量不会传递
+	Tools        []LinterTool `mapstructure:"tools"`
+
+	// Sandbox 执行工具的沙箱命令前缀（如 bwrap、docker run），工具命令追加在其后
+	// 参数中的 {workspace}、{repo}、{home} 替换为工作区、仓库根目录和 HOME；未配置时不执行任何外部工具
+	Sandbox []string `mapstructure:"sandbox"`
+}
+
+// LinterTool 外部检查工具
+type LinterTool struct {
+	Name      string   `mapstructure:"name"`      // 工具名，标记在问题上，仓库配置按名称启用
+	Languages []string `mapstructure:"languages"` // 变更包含这些语言的文件时执行，为空表示总是执行
+	Command   []string `mapstructure:"command"`   // 在仓库根目录直接执行，不经过 shell
+	Format    string   `mapstructure:"format"`    // 输出格式：sarif / golangci / eslint / ruff
+	Timeout   int      `mapstructure:"timeout"`   // 超时（秒），0 使用默认值
+}
+
 type LogConfig struct {
 	Level  string `mapstructure:"level"`
 	Format string `mapstructure:"format"`
 }
 
+// EnvPrefix 覆盖配置项的环境变量前缀，如 CODE_SENTINEL_GITHUB_TOKEN
+const EnvPrefix = "CODE_SENTINEL"
+
 func Load() (*Config, error) {
 	viper.SetConfigName("config")
 	viper.SetConfigType("yaml")
 	viper.AddConfigPath("./configs")
 	viper.AddConfigPath(".")
 
-	viper.SetEnvPrefix("CODE_SENTINEL")
+	viper.SetEnvPrefix(EnvPrefix)
 	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
 	viper.AutomaticEnv()
 
@@ -101,11 +177,25 @@ func setDefaults() {
 	viper.SetDefault("llm.model", "gpt-4")
 	viper.SetDefault("llm.timeout", 60)
 	viper.SetDefault("llm.max_tokens", 4096)
+	viper.SetDefault("llm.stream", true)
+	viper.SetDefault("llm.temperature", 0.3)
+	viper.SetDefault("llm.structured_output", "auto")
 
 	viper.SetDefault("review.languages", []string{"go", "java", "python"})
 	viper.SetDefault("review.max_diff_lines", 500)
 	viper.SetDefault("review.ignore_patterns", []string{"*.md", "*.json", "go.mod", "go.sum"})
 
+	viper.SetDefault("cache.enabled", true)
+	viper.SetDefault("cache.ttl_hours", 168)
+	viper.SetDefault("cache.max_entries", 1000)
+
+	viper.SetDefault("pricing.currency", "USD")
+
+	viper.SetDefault("linters.clone_url", "https://github.com/{repo}.git")
+	viper.SetDefault("linters.fetch_timeout", 120)
+	viper.SetDefault("linters.timeout", 300)
+	viper.SetDefault("linters.max_output", 10<<20)
+
 	viper.SetDefault("log.level", "info")
 	viper.SetDefault("log.format", "json")
 }
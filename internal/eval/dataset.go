@@ -0,0 +1,132 @@
+package eval
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"code-sentinel/internal/model"
+)
+
+// 数据集目录结构：
+//
+//	<dataset>/
+//	  config.json      可选，所有用例共用的 ReviewConfig
+//	  <case>.diff      统一 Diff
+//	  <case>.json      期望结果 CaseSpec
+const configFile = "config.json"
+
+// Finding 期望（或已知误报）的审查问题，行号范围内的问题均视为命中
+type Finding struct {
+	File      string `json:"file"`
+	LineStart int    `json:"line_start"`
+	LineEnd   int    `json:"line_end,omitempty"` // 为 0 时与 line_start 相同
+	Category  string `json:"category"`
+	Severity  string `json:"severity"`
+	Title     string `json:"title,omitempty"`
+}
+
+// CaseSpec 用例的期望结果
+type CaseSpec struct {
+	Description string    `json:"description,omitempty"`
+	Expected    []Finding `json:"expected"`           // 应当报告的问题
+	Rejected    []Finding `json:"rejected,omitempty"` // 已知误报，不应报告
+	Source      *Source   `json:"source,omitempty"`   // 从反馈导出时记录来源
+
+	// RejectedOnly 用例只标注了误报，expected 不完整：只统计命中 rejected 的误报，其余发现的问题不计分
+	RejectedOnly bool `json:"rejected_only,omitempty"`
+}
+
+// Source 用例来源的审查记录
+type Source struct {
+	Repo      string `json:"repo"`
+	PRNumber  int    `json:"pr_number"`
+	ReviewID  uint   `json:"review_id"`
+	CommitSHA string `json:"commit_sha,omitempty"`
+}
+
+// Case 一个评测用例
+type Case struct {
+	Name string
+	Diff string
+	CaseSpec
+}
+
+// LoadDataset 读取数据集目录，用例按名称排序；config.json 不存在时返回 nil 配置
+func LoadDataset(dir string) ([]Case, *model.ReviewConfig, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read dataset: %w", err)
+	}
+
+	var config *model.ReviewConfig
+	var cases []Case
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, ".diff") {
+			continue
+		}
+		c := Case{Name: strings.TrimSuffix(name, ".diff")}
+
+		diff, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		c.Diff = string(diff)
+
+		spec, err := os.ReadFile(filepath.Join(dir, c.Name+".json"))
+		if err != nil {
+			return nil, nil, fmt.Errorf("case %s: missing expected findings: %w", c.Name, err)
+		}
+		if err := json.Unmarshal(spec, &c.CaseSpec); err != nil {
+			return nil, nil, fmt.Errorf("case %s: invalid expected findings: %w", c.Name, err)
+		}
+		for i := range c.Expected {
+			normalizeFinding(&c.Expected[i])
+		}
+		for i := range c.Rejected {
+			normalizeFinding(&c.Rejected[i])
+		}
+		cases = append(cases, c)
+	}
+
+	if data, err := os.ReadFile(filepath.Join(dir, configFile)); err == nil {
+		config = &model.ReviewConfig{}
+		if err := json.Unmarshal(data, config); err != nil {
+			return nil, nil, fmt.Errorf("invalid %s: %w", configFile, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, nil, err
+	}
+
+	sort.Slice(cases, func(i, j int) bool { return cases[i].Name < cases[j].Name })
+	return cases, config, nil
+}
+
+// WriteCase 将用例写入数据集目录，已存在的同名用例会被覆盖
+func WriteCase(dir string, c Case) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, c.Name+".diff"), []byte(c.Diff), 0644); err != nil {
+		return err
+	}
+	spec, err := json.MarshalIndent(c.CaseSpec, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, c.Name+".json"), append(spec, '\n'), 0644)
+}
+
+// normalizeFinding 补全行号范围并统一大小写
+func normalizeFinding(f *Finding) {
+	if f.LineEnd < f.LineStart {
+		f.LineEnd = f.LineStart
+	}
+	f.Category = strings.ToLower(strings.TrimSpace(f.Category))
+	f.Severity = strings.ToUpper(strings.TrimSpace(f.Severity))
+	f.File = strings.TrimPrefix(strings.TrimSpace(f.File), "/")
+}
@@ -0,0 +1,127 @@
+package eval
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"code-sentinel/internal/model"
+	"code-sentinel/internal/service"
+	"code-sentinel/internal/store"
+
+	"go.uber.org/zap"
+)
+
+// exportPageSize 导出时分页读取反馈的页大小
+const exportPageSize = 200
+
+// ExportOptions 反馈导出选项
+type ExportOptions struct {
+	Repo      string // 只导出指定仓库
+	StartDate string // YYYY-MM-DD
+	EndDate   string
+}
+
+// ExportFeedback 将存储的反馈按审查记录导出为数据集用例，返回导出的用例数
+// 误报反馈写入 rejected，带文件位置的漏报反馈写入 expected；未收到反馈的已发布问题无法判断对错，不写入用例
+// 没有带位置的漏报时用例标记为 rejected_only，评测只统计命中 rejected 的误报
+// Diff 按审查记录的提交通过 compare API 获取，与当时审查的内容一致
+func ExportFeedback(ctx context.Context, st store.Store, githubSvc *service.GitHubService, dir string, opts ExportOptions, logger *zap.Logger) (int, error) {
+	byReview := make(map[uint][]model.Feedback)
+	var order []uint
+	filter := &store.FeedbackFilter{RepoFullName: opts.Repo, StartDate: opts.StartDate, EndDate: opts.EndDate}
+	for page := 1; ; page++ {
+		feedbacks, total, err := st.ListFeedbacks(ctx, filter, page, exportPageSize)
+		if err != nil {
+			return 0, err
+		}
+		for _, f := range feedbacks {
+			if _, ok := byReview[f.ReviewID]; !ok {
+				order = append(order, f.ReviewID)
+			}
+			byReview[f.ReviewID] = append(byReview[f.ReviewID], f)
+		}
+		if len(feedbacks) == 0 || int64(page*exportPageSize) >= total {
+			break
+		}
+	}
+
+	exported := 0
+	for _, reviewID := range order {
+		review, err := st.GetReview(ctx, reviewID)
+		if err != nil {
+			logger.Warn("Skipping feedback without review", zap.Uint("review_id", reviewID), zap.Error(err))
+			continue
+		}
+
+		c := caseFromFeedback(review, byReview[reviewID])
+		if len(c.Expected) == 0 && len(c.Rejected) == 0 {
+			continue
+		}
+
+		c.Diff, err = githubSvc.GetReviewDiff(ctx, review)
+		if err != nil {
+			logger.Warn("Failed to fetch diff for feedback case",
+				zap.String("repo", review.RepoFullName),
+				zap.Int("pr_number", review.PRNumber),
+				zap.Error(err),
+			)
+			continue
+		}
+
+		if err := WriteCase(dir, c); err != nil {
+			return exported, err
+		}
+		exported++
+	}
+	return exported, nil
+}
+
+// caseFromFeedback 根据一次审查收到的反馈生成用例，同一位置的重复反馈只保留一条
+func caseFromFeedback(review *model.Review, feedbacks []model.Feedback) Case {
+	c := Case{
+		Name: fmt.Sprintf("%s-pr%d-r%d", strings.ReplaceAll(review.RepoFullName, "/", "__"), review.PRNumber, review.ID),
+		CaseSpec: CaseSpec{
+			Description: review.PRTitle,
+			Expected:    []Finding{},
+			Source: &Source{
+				Repo:      review.RepoFullName,
+				PRNumber:  review.PRNumber,
+				ReviewID:  review.ID,
+				CommitSHA: review.CommitSHA,
+			},
+		},
+	}
+
+	seen := make(map[string]bool)
+	for _, f := range feedbacks {
+		// 漏报反馈针对整个审查，没有文件位置，无法作为期望问题；
+		// 旧数据中漏报反馈的 is_false_positive 可能被错误记为 true，按类别一并排除
+		if f.File == "" || f.Category == "missed" {
+			continue
+		}
+		finding := Finding{
+			File:      f.File,
+			LineStart: f.Line,
+			LineEnd:   f.Line,
+			Category:  f.Category,
+			Severity:  f.Severity,
+			Title:     f.Title,
+		}
+		normalizeFinding(&finding)
+
+		key := fmt.Sprintf("%t:%s:%d:%s", f.IsFalsePositive, finding.File, finding.LineStart, finding.Category)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		if f.IsFalsePositive {
+			c.Rejected = append(c.Rejected, finding)
+		} else {
+			c.Expected = append(c.Expected, finding)
+		}
+	}
+	c.RejectedOnly = len(c.Expected) == 0
+	return c
+}
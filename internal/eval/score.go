@@ -0,0 +1,210 @@
+package eval
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"code-sentinel/internal/model"
+)
+
+// DefaultLineTolerance 发现的问题行号与期望范围的默认允许偏差
+const DefaultLineTolerance = 3
+
+// Metrics 一组问题的命中统计
+type Metrics struct {
+	TruePositives  int     `json:"tp"`
+	FalsePositives int     `json:"fp"`
+	FalseNegatives int     `json:"fn"`
+	Precision      float64 `json:"precision"`
+	Recall         float64 `json:"recall"`
+	F1             float64 `json:"f1"`
+}
+
+// Match 命中的期望问题
+type Match struct {
+	Expected Finding           `json:"expected"`
+	Found    model.ReviewIssue `json:"found"`
+	// SeverityMismatch 位置和类别一致但严重程度不同，仍计为命中
+	SeverityMismatch bool `json:"severity_mismatch,omitempty"`
+}
+
+// Extra 未匹配任何期望的问题
+type Extra struct {
+	Found model.ReviewIssue `json:"found"`
+	// KnownFalsePositive 与用例中记录的已知误报位置一致
+	KnownFalsePositive bool `json:"known_false_positive,omitempty"`
+}
+
+// CaseReport 单个用例期望与实际结果的对比
+type CaseReport struct {
+	Name    string      `json:"name"`
+	Matched []Match     `json:"matched,omitempty"`
+	Missed  []Finding   `json:"missed,omitempty"`
+	Extra   []Extra     `json:"extra,omitempty"`
+	Error   string      `json:"error,omitempty"` // 审查失败时的错误，此时期望问题全部计为漏报
+	Skipped string      `json:"skipped,omitempty"`
+	Usage   model.Usage `json:"usage"`
+
+	Ignored int `json:"ignored,omitempty"` // 只标注误报的用例中未命中 rejected、不计分的问题数
+}
+
+// Report 评测汇总
+type Report struct {
+	Model      string              `json:"model"`
+	Cases      int                 `json:"cases"`
+	Errors     int                 `json:"errors"`
+	Overall    Metrics             `json:"overall"`
+	ByCategory map[string]*Metrics `json:"by_category"`
+	BySeverity map[string]*Metrics `json:"by_severity"`
+	Usage      model.Usage         `json:"usage"`
+	Details    []CaseReport        `json:"details"`
+}
+
+// ScoreCase 将审查发现的问题与期望逐一匹配
+// 同一文件、同一类别且行号落在期望范围（含 tolerance 偏差）内视为命中，每个期望和每个问题最多匹配一次，优先匹配行号最近的
+// RejectedOnly 的用例无法判断未标注的问题是否正确，只有命中 rejected 的问题计为误报
+func ScoreCase(c Case, found []model.ReviewIssue, tolerance int) CaseReport {
+	report := CaseReport{Name: c.Name}
+	used := make([]bool, len(found))
+
+	for _, exp := range c.Expected {
+		best, bestDist := -1, 0
+		for i, issue := range found {
+			if used[i] || !sameFile(exp.File, issue.File) || exp.Category != issue.Category {
+				continue
+			}
+			if d := lineDistance(exp, issue.Line); d <= tolerance && (best < 0 || d < bestDist) {
+				best, bestDist = i, d
+			}
+		}
+		if best < 0 {
+			report.Missed = append(report.Missed, exp)
+			continue
+		}
+		used[best] = true
+		report.Matched = append(report.Matched, Match{
+			Expected:         exp,
+			Found:            found[best],
+			SeverityMismatch: exp.Severity != "" && exp.Severity != found[best].Severity,
+		})
+	}
+
+	for i, issue := range found {
+		if used[i] {
+			continue
+		}
+		extra := Extra{Found: issue}
+		for _, rej := range c.Rejected {
+			if sameFile(rej.File, issue.File) && lineDistance(rej, issue.Line) <= tolerance {
+				extra.KnownFalsePositive = true
+				break
+			}
+		}
+		if c.RejectedOnly && !extra.KnownFalsePositive {
+			report.Ignored++
+			continue
+		}
+		report.Extra = append(report.Extra, extra)
+	}
+	return report
+}
+
+// Summarize 汇总各用例结果，按类别和严重程度分别计算 precision/recall/F1
+// 命中和漏报按期望问题的类别和严重程度归类，误报按发现问题的类别和严重程度归类
+func Summarize(modelName string, cases []CaseReport) *Report {
+	report := &Report{
+		Model:      modelName,
+		Cases:      len(cases),
+		ByCategory: make(map[string]*Metrics),
+		BySeverity: make(map[string]*Metrics),
+		Details:    cases,
+	}
+	bucket := func(m map[string]*Metrics, key string) *Metrics {
+		if key == "" {
+			key = "unknown"
+		}
+		if m[key] == nil {
+			m[key] = &Metrics{}
+		}
+		return m[key]
+	}
+
+	for _, c := range cases {
+		if c.Error != "" {
+			report.Errors++
+		}
+		report.Usage.PromptTokens += c.Usage.PromptTokens
+		report.Usage.CompletionTokens += c.Usage.CompletionTokens
+		report.Usage.TotalTokens += c.Usage.TotalTokens
+
+		for _, m := range c.Matched {
+			report.Overall.TruePositives++
+			bucket(report.ByCategory, m.Expected.Category).TruePositives++
+			bucket(report.BySeverity, m.Expected.Severity).TruePositives++
+		}
+		for _, f := range c.Missed {
+			report.Overall.FalseNegatives++
+			bucket(report.ByCategory, f.Category).FalseNegatives++
+			bucket(report.BySeverity, f.Severity).FalseNegatives++
+		}
+		for _, e := range c.Extra {
+			report.Overall.FalsePositives++
+			bucket(report.ByCategory, e.Found.Category).FalsePositives++
+			bucket(report.BySeverity, e.Found.Severity).FalsePositives++
+		}
+	}
+
+	report.Overall.compute()
+	for _, m := range report.ByCategory {
+		m.compute()
+	}
+	for _, m := range report.BySeverity {
+		m.compute()
+	}
+	return report
+}
+
+// SortedKeys 返回统计维度的键，按字母序
+func SortedKeys(m map[string]*Metrics) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// compute 根据 TP/FP/FN 计算 precision、recall 和 F1，分母为 0 时对应指标为 0
+func (m *Metrics) compute() {
+	if m.TruePositives+m.FalsePositives > 0 {
+		m.Precision = float64(m.TruePositives) / float64(m.TruePositives+m.FalsePositives)
+	}
+	if m.TruePositives+m.FalseNegatives > 0 {
+		m.Recall = float64(m.TruePositives) / float64(m.TruePositives+m.FalseNegatives)
+	}
+	if m.Precision+m.Recall > 0 {
+		m.F1 = 2 * m.Precision * m.Recall / (m.Precision + m.Recall)
+	}
+}
+
+// lineDistance 行号与期望范围的距离，落在范围内为 0；文件级问题（行号 0）只匹配文件级期望
+func lineDistance(f Finding, line int) int {
+	if line == 0 || f.LineStart == 0 {
+		if line == f.LineStart {
+			return 0
+		}
+		return math.MaxInt
+	}
+	switch {
+	case line < f.LineStart:
+		return f.LineStart - line
+	case line > f.LineEnd:
+		return line - f.LineEnd
+	}
+	return 0
+}
+
+func sameFile(a, b string) bool {
+	return strings.TrimPrefix(a, "/") == strings.TrimPrefix(b, "/")
+}
@@ -2,14 +2,17 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
 	"code-sentinel/internal/model"
+	"code-sentinel/internal/service"
 	"code-sentinel/internal/store"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
 // Repo handlers
@@ -131,6 +134,15 @@ func (h *Handler) UpdateRepo(c *gin.Context) {
 	}
 	if req.Config != nil {
 		configJSON, _ := json.Marshal(req.Config)
+		var reviewConfig model.ReviewConfig
+		if err := json.Unmarshal(configJSON, &reviewConfig); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid config: " + err.Error()})
+			return
+		}
+		if err := service.ValidateReviewConfig(&reviewConfig); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		repo.Config = string(configJSON)
 	}
 	if req.Enabled != nil {
@@ -223,6 +235,70 @@ func (h *Handler) GetReview(c *gin.Context) {
 	})
 }
 
+// RerunReview 手动重新审查，bypass_cache 为 true 时跳过 LLM 响应缓存
+func (h *Handler) RerunReview(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "invalid id"})
+		return
+	}
+
+	var req struct {
+		BypassCache bool `json:"bypass_cache"`
+	}
+	// 请求体可选
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": err.Error()})
+			return
+		}
+	}
+
+	review, err := h.analyzerSvc.RerunReview(c.Request.Context(), uint(id), service.AnalyzeOptions{
+		BypassCache: req.BypassCache,
+	})
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "review not found"})
+		return
+	case errors.Is(err, service.ErrRepoDisabled):
+		c.JSON(http.StatusConflict, gin.H{"code": 409, "message": err.Error()})
+		return
+	case err != nil:
+		h.logger.Error("Failed to rerun review", zap.Uint64("review_id", id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "failed to rerun review"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    review,
+	})
+}
+
+// ListReviewToolCalls 获取工具调用审查的调用记录
+func (h *Handler) ListReviewToolCalls(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "invalid id"})
+		return
+	}
+
+	traces, err := h.store.ListToolCallTraces(c.Request.Context(), uint(id))
+	if err != nil {
+		h.logger.Error("Failed to list tool call traces", zap.Uint64("review_id", id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "failed to list tool calls"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    traces,
+	})
+}
+
 // Config handlers
 
 func (h *Handler) ListConfigs(c *gin.Context) {
@@ -327,13 +403,22 @@ func (h *Handler) ListFeedbacks(c *gin.Context) {
 	})
 }
 
+// createFeedbackRequest 创建反馈请求，is_false_positive 缺省时视为误报
+type createFeedbackRequest struct {
+	model.Feedback
+	IsFalsePositive *bool `json:"is_false_positive"`
+}
+
 func (h *Handler) CreateFeedback(c *gin.Context) {
-	var feedback model.Feedback
-	if err := c.ShouldBindJSON(&feedback); err != nil {
+	var req createFeedbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": err.Error()})
 		return
 	}
 
+	feedback := req.Feedback
+	feedback.IsFalsePositive = req.IsFalsePositive == nil || *req.IsFalsePositive
+
 	if err := h.feedbackSvc.CreateFeedback(c.Request.Context(), &feedback); err != nil {
 		h.logger.Error("Failed to create feedback", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "failed to create feedback"})
@@ -381,6 +466,27 @@ func (h *Handler) GetConfigTemplates(c *gin.Context) {
 	})
 }
 
+// GetLanguageTemplates 获取语言专项检查模板，指定 repo 时返回应用仓库覆盖后的模板
+func (h *Handler) GetLanguageTemplates(c *gin.Context) {
+	var overrides map[string]string
+	if repo := c.Query("repo"); repo != "" {
+		config, err := h.repoSvc.GetRepoConfig(c.Request.Context(), repo)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "repo not found"})
+			return
+		}
+		overrides = config.LanguagePrompts
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"templates": h.analyzerSvc.LanguageTemplates(overrides),
+		},
+	})
+}
+
 // Helper functions
 
 func splitFullName(fullName string) []string {
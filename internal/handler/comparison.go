@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"code-sentinel/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// GetReviewComparison 获取多模型对比报告
+func (h *Handler) GetReviewComparison(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "invalid id"})
+		return
+	}
+
+	report, err := h.analyzerSvc.GetComparisonReport(c.Request.Context(), uint(id))
+	if errors.Is(err, service.ErrNoComparison) {
+		c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "no comparison for review"})
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to get comparison report", zap.Uint64("review_id", id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "failed to get comparison report"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    report,
+	})
+}
+
+// GetComparisonModelStats 按模型汇总对比模式下的表现
+func (h *Handler) GetComparisonModelStats(c *gin.Context) {
+	stats, err := h.store.GetComparisonModelStats(c.Request.Context(), c.Query("repo"))
+	if err != nil {
+		h.logger.Error("Failed to get comparison model stats", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "failed to get comparison stats"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    stats,
+	})
+}
@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"code-sentinel/internal/service"
+	"code-sentinel/internal/store"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// maxAdvisoryImportSize 公告导入请求体上限，OSV 单生态的 all.zip 通常在百 MB 以内
+const maxAdvisoryImportSize = 512 << 20
+
+// ImportAdvisories 导入 OSV 格式的漏洞公告，请求体为 JSON（单个或数组）或 OSV 导出的 zip
+func (h *Handler) ImportAdvisories(c *gin.Context) {
+	data, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxAdvisoryImportSize))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "failed to read request body: " + err.Error()})
+		return
+	}
+
+	result, err := h.depSvc.ImportOSV(c.Request.Context(), data)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidAdvisory) {
+			c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": err.Error()})
+			return
+		}
+		h.logger.Error("Failed to import advisories", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "failed to import advisories"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    result,
+	})
+}
+
+// ListAdvisories 分页查询漏洞公告，支持按生态、包名和公告 ID/别名筛选
+func (h *Handler) ListAdvisories(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	filter := &store.AdvisoryFilter{
+		Ecosystem: c.Query("ecosystem"),
+		Package:   c.Query("package"),
+		Search:    c.Query("search"),
+	}
+
+	advisories, total, err := h.depSvc.ListAdvisories(c.Request.Context(), filter, page, pageSize)
+	if err != nil {
+		h.logger.Error("Failed to list advisories", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "failed to list advisories"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"items":     advisories,
+			"total":     total,
+			"page":      page,
+			"page_size": pageSize,
+		},
+	})
+}
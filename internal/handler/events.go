@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"code-sentinel/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// sseHeartbeatInterval SSE 心跳间隔，防止代理断开空闲连接
+const sseHeartbeatInterval = 15 * time.Second
+
+// StreamReviewEvents 通过 SSE 推送审查进度（阶段切换和 LLM 增量输出）
+func (h *Handler) StreamReviewEvents(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "invalid id"})
+		return
+	}
+
+	review, err := h.store.GetReview(c.Request.Context(), uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "review not found"})
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	events, unsubscribe := h.analyzerSvc.Progress().Subscribe(review.ID)
+	defer unsubscribe()
+
+	// 订阅后再次读取状态，避免审查在订阅前已经结束导致连接一直挂起
+	if latest, err := h.store.GetReview(c.Request.Context(), review.ID); err == nil {
+		review = latest
+	}
+	if isReviewFinished(review.Status) {
+		c.SSEvent(string(model.ReviewEventDone), model.ReviewEvent{
+			Type:     model.ReviewEventDone,
+			ReviewID: review.ID,
+			Status:   review.Status,
+			Message:  review.ErrorMsg,
+			Time:     time.Now(),
+		})
+		return
+	}
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return false
+			}
+			c.SSEvent(string(event.Type), event)
+			return event.Type != model.ReviewEventDone
+		case <-heartbeat.C:
+			_, err := io.WriteString(w, ": keepalive\n\n")
+			return err == nil
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+}
+
+// isReviewFinished 审查是否已结束
+func isReviewFinished(status model.ReviewStatus) bool {
+	switch status {
+	case model.ReviewStatusCompleted, model.ReviewStatusFailed, model.ReviewStatusSkipped, model.ReviewStatusDegraded:
+		return true
+	}
+	return false
+}
@@ -12,6 +12,9 @@ type Handler struct {
 	analyzerSvc *service.AnalyzerService
 	repoSvc     *service.RepoService
 	feedbackSvc *service.FeedbackService
+	ruleSvc     *service.RuleService
+	promptSvc   *service.PromptService
+	depSvc      *service.DependencyService
 	store       store.Store
 	config      *config.Config
 	logger      *zap.Logger
@@ -21,6 +24,9 @@ func NewHandler(
 	analyzerSvc *service.AnalyzerService,
 	repoSvc *service.RepoService,
 	feedbackSvc *service.FeedbackService,
+	ruleSvc *service.RuleService,
+	promptSvc *service.PromptService,
+	depSvc *service.DependencyService,
 	store store.Store,
 	cfg *config.Config,
 	logger *zap.Logger,
@@ -29,6 +35,9 @@ func NewHandler(
 		analyzerSvc: analyzerSvc,
 		repoSvc:     repoSvc,
 		feedbackSvc: feedbackSvc,
+		ruleSvc:     ruleSvc,
+		promptSvc:   promptSvc,
+		depSvc:      depSvc,
 		store:       store,
 		config:      cfg,
 		logger:      logger,
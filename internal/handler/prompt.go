@@ -0,0 +1,204 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"code-sentinel/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// ListPromptVersions 获取提示词版本列表，支持按模板名筛选
+func (h *Handler) ListPromptVersions(c *gin.Context) {
+	versions, err := h.promptSvc.ListVersions(c.Request.Context(), c.Query("name"))
+	if err != nil {
+		h.logger.Error("Failed to list prompt versions", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "failed to list prompt versions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    versions,
+	})
+}
+
+// GetPromptVersion 获取提示词版本详情
+func (h *Handler) GetPromptVersion(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "invalid id"})
+		return
+	}
+
+	version, err := h.promptSvc.GetVersion(c.Request.Context(), uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "prompt version not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    version,
+	})
+}
+
+// CreatePromptVersion 创建提示词版本，已有同名模板时版本号加一
+func (h *Handler) CreatePromptVersion(c *gin.Context) {
+	var req service.CreatePromptVersionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": err.Error()})
+		return
+	}
+
+	version, err := h.promptSvc.CreateVersion(c.Request.Context(), &req)
+	if err != nil {
+		h.respondExperimentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    version,
+	})
+}
+
+// ListExperiments 获取仓库的提示词实验
+func (h *Handler) ListExperiments(c *gin.Context) {
+	repo, ok := h.repoFromParam(c)
+	if !ok {
+		return
+	}
+
+	experiments, err := h.promptSvc.ListExperiments(c.Request.Context(), repo.FullName)
+	if err != nil {
+		h.logger.Error("Failed to list experiments", zap.String("repo", repo.FullName), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "failed to list experiments"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    experiments,
+	})
+}
+
+// CreateExperiment 为仓库创建并启动提示词实验
+func (h *Handler) CreateExperiment(c *gin.Context) {
+	repo, ok := h.repoFromParam(c)
+	if !ok {
+		return
+	}
+
+	var req service.CreateExperimentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": err.Error()})
+		return
+	}
+
+	experiment, err := h.promptSvc.CreateExperiment(c.Request.Context(), repo.FullName, &req)
+	if err != nil {
+		h.respondExperimentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    experiment,
+	})
+}
+
+// StopExperiment 停止提示词实验
+func (h *Handler) StopExperiment(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "invalid id"})
+		return
+	}
+
+	experiment, err := h.promptSvc.StopExperiment(c.Request.Context(), uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "experiment not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    experiment,
+	})
+}
+
+// GetExperimentReport 对比实验各变体的误报反馈率、平均问题数、Token 和耗时
+func (h *Handler) GetExperimentReport(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "invalid id"})
+		return
+	}
+
+	if _, err := h.store.GetExperiment(c.Request.Context(), uint(id)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "experiment not found"})
+		return
+	}
+
+	report, err := h.promptSvc.GetExperimentReport(c.Request.Context(), uint(id))
+	if err != nil {
+		h.logger.Error("Failed to get experiment report", zap.Uint64("experiment_id", id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "failed to get experiment report"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    report,
+	})
+}
+
+// respondExperimentError 按错误类型返回提示词版本或实验创建失败的响应
+func (h *Handler) respondExperimentError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidExperiment):
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": err.Error()})
+	case errors.Is(err, service.ErrExperimentRunning):
+		c.JSON(http.StatusConflict, gin.H{"code": 409, "message": err.Error()})
+	default:
+		h.logger.Error("Failed to save prompt experiment", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "failed to save"})
+	}
+}
+
+// RunPlayground 按候选配置试运行审查，不发布评论也不写审查记录
+func (h *Handler) RunPlayground(c *gin.Context) {
+	var req service.PlaygroundRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": err.Error()})
+		return
+	}
+
+	result, err := h.analyzerSvc.RunPlayground(c.Request.Context(), &req)
+	if errors.Is(err, service.ErrInvalidPlayground) {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": err.Error()})
+		return
+	}
+	if err != nil {
+		h.logger.Error("Playground run failed", zap.Uint("review_id", req.ReviewID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "playground run failed: " + err.Error(), "data": result})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    result,
+	})
+}
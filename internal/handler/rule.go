@@ -0,0 +1,171 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"code-sentinel/internal/model"
+	"code-sentinel/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// ListRules 获取仓库的审查规则
+func (h *Handler) ListRules(c *gin.Context) {
+	repo, ok := h.repoFromParam(c)
+	if !ok {
+		return
+	}
+
+	rules, err := h.ruleSvc.ListRules(c.Request.Context(), repo.FullName)
+	if err != nil {
+		h.logger.Error("Failed to list rules", zap.String("repo", repo.FullName), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "failed to list rules"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    rules,
+	})
+}
+
+// CreateRule 为仓库创建审查规则
+func (h *Handler) CreateRule(c *gin.Context) {
+	repo, ok := h.repoFromParam(c)
+	if !ok {
+		return
+	}
+
+	var req service.RuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": err.Error()})
+		return
+	}
+
+	rule, err := h.ruleSvc.CreateRule(c.Request.Context(), repo.FullName, &req)
+	if err != nil {
+		h.respondRuleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    rule,
+	})
+}
+
+// UpdateRule 更新审查规则，rule_id 不可修改
+func (h *Handler) UpdateRule(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "invalid id"})
+		return
+	}
+
+	var req service.RuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": err.Error()})
+		return
+	}
+
+	if _, err := h.ruleSvc.GetRule(c.Request.Context(), uint(id)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "rule not found"})
+		return
+	}
+
+	rule, err := h.ruleSvc.UpdateRule(c.Request.Context(), uint(id), &req)
+	if err != nil {
+		h.respondRuleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    rule,
+	})
+}
+
+// DeleteRule 删除审查规则
+func (h *Handler) DeleteRule(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "invalid id"})
+		return
+	}
+
+	if err := h.ruleSvc.DeleteRule(c.Request.Context(), uint(id)); err != nil {
+		h.logger.Error("Failed to delete rule", zap.Uint64("id", id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "failed to delete rule"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+	})
+}
+
+// GetRuleStats 获取仓库各审查规则的命中和误报统计
+func (h *Handler) GetRuleStats(c *gin.Context) {
+	repo, ok := h.repoFromParam(c)
+	if !ok {
+		return
+	}
+
+	stats, err := h.ruleSvc.GetRuleStats(c.Request.Context(), repo.FullName)
+	if err != nil {
+		h.logger.Error("Failed to get rule stats", zap.String("repo", repo.FullName), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "failed to get rule stats"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    stats,
+	})
+}
+
+// ListBuiltinRules 获取内置模式规则，仓库可通过 disabled_builtin_rules 配置关闭
+func (h *Handler) ListBuiltinRules(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    service.BuiltinPatternRules(),
+	})
+}
+
+// repoFromParam 解析路径中的仓库 ID，仓库不存在时写入错误响应
+func (h *Handler) repoFromParam(c *gin.Context) (*model.Repo, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "invalid id"})
+		return nil, false
+	}
+
+	repo, err := h.store.GetRepo(c.Request.Context(), uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "repo not found"})
+		return nil, false
+	}
+	return repo, true
+}
+
+// respondRuleError 按错误类型返回规则创建或更新失败的响应
+func (h *Handler) respondRuleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidRule):
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": err.Error()})
+	case errors.Is(err, service.ErrRuleExists):
+		c.JSON(http.StatusConflict, gin.H{"code": 409, "message": err.Error()})
+	default:
+		h.logger.Error("Failed to save rule", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "failed to save rule"})
+	}
+}
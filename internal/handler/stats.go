@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"net/http"
+
+	"code-sentinel/internal/store"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// GetCostSummary 获取成本汇总
+func (h *Handler) GetCostSummary(c *gin.Context) {
+	stats, ok := h.costStats(c, store.CostGroupNone)
+	if !ok {
+		return
+	}
+
+	summary := store.CostAggregate{}
+	if len(stats) > 0 {
+		summary = stats[0]
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"currency": h.config.Pricing.Currency,
+			"summary":  summary,
+		},
+	})
+}
+
+// GetCostByRepo 按仓库统计成本
+func (h *Handler) GetCostByRepo(c *gin.Context) {
+	h.respondCostStats(c, store.CostGroupRepo)
+}
+
+// GetCostByModel 按模型统计成本
+func (h *Handler) GetCostByModel(c *gin.Context) {
+	h.respondCostStats(c, store.CostGroupModel)
+}
+
+// GetCostByDay 按天统计成本
+func (h *Handler) GetCostByDay(c *gin.Context) {
+	h.respondCostStats(c, store.CostGroupDay)
+}
+
+func (h *Handler) respondCostStats(c *gin.Context, groupBy store.CostGroupBy) {
+	stats, ok := h.costStats(c, groupBy)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"currency": h.config.Pricing.Currency,
+			"items":    stats,
+		},
+	})
+}
+
+func (h *Handler) costStats(c *gin.Context, groupBy store.CostGroupBy) ([]store.CostAggregate, bool) {
+	filter := &store.CostFilter{
+		RepoFullName: c.Query("repo"),
+		Model:        c.Query("model"),
+		StartDate:    c.Query("start_date"),
+		EndDate:      c.Query("end_date"),
+	}
+
+	stats, err := h.store.GetCostStats(c.Request.Context(), filter, groupBy)
+	if err != nil {
+		h.logger.Error("Failed to get cost stats", zap.String("group_by", string(groupBy)), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "failed to get cost stats"})
+		return nil, false
+	}
+	return stats, true
+}
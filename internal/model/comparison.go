@@ -0,0 +1,72 @@
+package model
+
+import "time"
+
+// ReviewComparison 对比模式下单个模型对同一 PR 的审查结果
+type ReviewComparison struct {
+	ID               uint      `gorm:"primaryKey" json:"id"`
+	ReviewID         uint      `gorm:"index" json:"review_id"`
+	Provider         string    `gorm:"size:50" json:"provider"`
+	Model            string    `gorm:"size:100;index" json:"model"`
+	Primary          bool      `gorm:"column:is_primary" json:"primary"` // 是否为发布评论的主模型
+	Status           string    `gorm:"size:20" json:"status"`
+	Result           string    `gorm:"type:text" json:"result"` // ReviewResult JSON
+	IssueCount       int       `json:"issue_count"`
+	TokenUsed        int       `json:"token_used"`
+	PromptTokens     int       `json:"prompt_tokens"`
+	CompletionTokens int       `json:"completion_tokens"`
+	Cost             float64   `json:"cost"`
+	DurationMs       int64     `json:"duration_ms"`
+	ErrorMsg         string    `gorm:"type:text" json:"error_msg,omitempty"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// ComparisonReport 多模型对比报告
+type ComparisonReport struct {
+	ReviewID  uint                `json:"review_id"`
+	Models    []ComparisonModel   `json:"models"`
+	Findings  []ComparisonFinding `json:"findings"`  // 按位置对齐后的问题
+	Agreement []ModelAgreement    `json:"agreement"` // 两两模型之间的一致度
+}
+
+// ComparisonModel 单个模型的审查概况
+type ComparisonModel struct {
+	Model            string      `json:"model"`
+	Provider         string      `json:"provider"`
+	Primary          bool        `json:"primary"`
+	Status           string      `json:"status"`
+	Stats            ReviewStats `json:"stats"`
+	IssueCount       int         `json:"issue_count"`
+	UniqueCount      int         `json:"unique_count"` // 只有该模型报告的问题数
+	TokenUsed        int         `json:"token_used"`
+	PromptTokens     int         `json:"prompt_tokens"`
+	CompletionTokens int         `json:"completion_tokens"`
+	Cost             float64     `json:"cost"`
+	DurationMs       int64       `json:"duration_ms"`
+	ErrorMsg         string      `json:"error_msg,omitempty"`
+}
+
+// ComparisonFinding 多个模型在同一位置报告的问题
+type ComparisonFinding struct {
+	File      string          `json:"file"`
+	Line      int             `json:"line"`
+	Reports   []FindingReport `json:"reports"`
+	Agreement float64         `json:"agreement"` // 报告该问题的模型占成功模型的比例
+}
+
+// FindingReport 单个模型对某个问题的报告
+type FindingReport struct {
+	Model    string `json:"model"`
+	Severity string `json:"severity"`
+	Category string `json:"category"`
+	Line     int    `json:"line"`
+	Title    string `json:"title"`
+}
+
+// ModelAgreement 两个模型的问题重合度
+type ModelAgreement struct {
+	ModelA  string  `json:"model_a"`
+	ModelB  string  `json:"model_b"`
+	Shared  int     `json:"shared"`  // 两者都报告的问题数
+	Jaccard float64 `json:"jaccard"` // 交集 / 并集
+}
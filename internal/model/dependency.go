@@ -0,0 +1,46 @@
+package model
+
+import "time"
+
+// Advisory 导入的 OSV 漏洞公告，公告影响多个包时每个包一条记录
+type Advisory struct {
+	ID         uint            `gorm:"primaryKey" json:"id"`
+	AdvisoryID string          `gorm:"uniqueIndex:idx_advisory_pkg;size:100" json:"advisory_id"` // OSV ID，如 GHSA-xxxx、GO-2023-0001
+	Ecosystem  string          `gorm:"uniqueIndex:idx_advisory_pkg;index:idx_advisory_lookup;size:50" json:"ecosystem"`
+	Package    string          `gorm:"uniqueIndex:idx_advisory_pkg;index:idx_advisory_lookup;size:300" json:"package"`
+	Summary    string          `gorm:"type:text" json:"summary"`
+	Aliases    []string        `gorm:"serializer:json;type:text" json:"aliases"`  // 如 CVE 编号
+	Severity   string          `gorm:"size:20" json:"severity"`                   // CRITICAL/HIGH/MODERATE/LOW，来源未提供时为空
+	Ranges     []AdvisoryRange `gorm:"serializer:json;type:text" json:"ranges"`   // 受影响的版本区间
+	Versions   []string        `gorm:"serializer:json;type:text" json:"versions"` // 显式列出的受影响版本
+	Modified   string          `gorm:"size:40" json:"modified"`
+	CreatedAt  time.Time       `json:"created_at"`
+	UpdatedAt  time.Time       `json:"updated_at"`
+}
+
+// AdvisoryRange 受影响的版本区间
+type AdvisoryRange struct {
+	Type   string          `json:"type"` // SEMVER / ECOSYSTEM / GIT
+	Events []AdvisoryEvent `json:"events"`
+}
+
+// AdvisoryEvent 区间事件，每个事件只有一个字段有值
+type AdvisoryEvent struct {
+	Introduced   string `json:"introduced,omitempty"`
+	Fixed        string `json:"fixed,omitempty"`
+	LastAffected string `json:"last_affected,omitempty"`
+	Limit        string `json:"limit,omitempty"`
+}
+
+// DependencyChange PR 中依赖清单或锁文件的版本变更
+type DependencyChange struct {
+	Ecosystem  string   `json:"ecosystem"`
+	Name       string   `json:"name"`
+	File       string   `json:"file"`
+	Line       int      `json:"line,omitempty"`
+	OldVersion string   `json:"old_version,omitempty"`
+	NewVersion string   `json:"new_version,omitempty"`
+	Kind       string   `json:"kind"`                 // added / removed / upgraded / downgraded / changed
+	Direct     bool     `json:"direct"`               // 直接依赖
+	Advisories []string `json:"advisories,omitempty"` // 新版本命中的公告 ID
+}
@@ -0,0 +1,35 @@
+package model
+
+import "time"
+
+// ReviewStage 审查阶段
+type ReviewStage string
+
+const (
+	ReviewStageFetchingDiff ReviewStage = "fetching_diff"
+	ReviewStageFiltering    ReviewStage = "filtering"
+	ReviewStagePrompting    ReviewStage = "prompting"
+	ReviewStageGenerating   ReviewStage = "generating"
+	ReviewStageVerifying    ReviewStage = "verifying"
+	ReviewStagePosting      ReviewStage = "posting"
+)
+
+// ReviewEventType 审查进度事件类型
+type ReviewEventType string
+
+const (
+	ReviewEventStage ReviewEventType = "stage" // 阶段切换
+	ReviewEventToken ReviewEventType = "token" // LLM 增量输出
+	ReviewEventDone  ReviewEventType = "done"  // 审查结束（完成/失败/跳过）
+)
+
+// ReviewEvent 审查进度事件（通过 SSE 推送给前端）
+type ReviewEvent struct {
+	Type     ReviewEventType `json:"type"`
+	ReviewID uint            `json:"review_id"`
+	Stage    ReviewStage     `json:"stage,omitempty"`
+	Status   ReviewStatus    `json:"status,omitempty"`  // done 事件携带最终状态
+	Message  string          `json:"message,omitempty"` // 阶段说明或错误信息
+	Delta    string          `json:"delta,omitempty"`   // token 事件的增量内容
+	Time     time.Time       `json:"time"`
+}
@@ -38,6 +38,33 @@ type User struct {
 	Type  string `json:"type"`
 }
 
+// ContentEntry 仓库目录项
+type ContentEntry struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+	Type string `json:"type"` // file / dir / symlink / submodule
+	Size int    `json:"size"`
+}
+
+// CodeSearchResult 代码搜索结果
+type CodeSearchResult struct {
+	TotalCount int              `json:"total_count"`
+	Items      []CodeSearchItem `json:"items"`
+}
+
+// CodeSearchItem 代码搜索命中的文件
+type CodeSearchItem struct {
+	Name        string            `json:"name"`
+	Path        string            `json:"path"`
+	Repository  Repository        `json:"repository"`
+	TextMatches []CodeSearchMatch `json:"text_matches"`
+}
+
+// CodeSearchMatch 命中的代码片段
+type CodeSearchMatch struct {
+	Fragment string `json:"fragment"`
+}
+
 type Ref struct {
 	Ref  string     `json:"ref"`
 	SHA  string     `json:"sha"`
@@ -68,11 +95,29 @@ type IssueCommentEvent struct {
 type Issue struct {
 	Number      int          `json:"number"`
 	Title       string       `json:"title"`
+	Body        string       `json:"body"`
 	State       string       `json:"state"`
 	User        User         `json:"user"`
 	PullRequest *PullRequest `json:"pull_request,omitempty"` // 非空表示是 PR
 }
 
+// PRCommit PR 中的提交
+type PRCommit struct {
+	SHA     string        `json:"sha"`
+	Commit  CommitDetail  `json:"commit"`
+	Parents []CommitIdent `json:"parents"`
+}
+
+// CommitDetail 提交详情
+type CommitDetail struct {
+	Message string `json:"message"`
+}
+
+// CommitIdent 提交标识
+type CommitIdent struct {
+	SHA string `json:"sha"`
+}
+
 // Comment GitHub 评论
 type Comment struct {
 	ID        int64  `json:"id"`
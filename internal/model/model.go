@@ -1,6 +1,7 @@
 package model
 
 import (
+	"encoding/json"
 	"time"
 
 	"gorm.io/gorm"
@@ -32,9 +33,77 @@ type ReviewConfig struct {
 	MinSeverity  string   `json:"min_severity"`   // 最小报告级别: P0/P1/P2
 	Languages    []string `json:"languages"`      // 支持语言: go/java/python
 	IgnoreFiles  []string `json:"ignore_files"`   // 忽略文件: *.test.go
-	MaxDiffLines int      `json:"max_diff_lines"` // 最大 Diff 行数
+	MaxDiffLines int      `json:"max_diff_lines"` // 单次审查最大 Diff 行数，超出时分批审查
 	AutoReview   bool     `json:"auto_review"`    // 是否自动审查
 
+	// 使用指定的提示词版本，优先于 SystemPrompt；仓库有运行中的提示词实验时按实验分配
+	PromptVersionID uint `json:"prompt_version_id,omitempty"`
+
+	// 大 Diff 分批审查配置（可选，0 表示使用默认值）
+	BatchMaxTokens   int `json:"batch_max_tokens,omitempty"`  // 单批次 Diff 最大 Token 数
+	BatchConcurrency int `json:"batch_concurrency,omitempty"` // 批次并发数
+	MaxBatches       int `json:"max_batches,omitempty"`       // 最大批次数，超出部分的文件不审查，结果标记为降级
+
+	ContextWindow int `json:"context_window,omitempty"` // 模型上下文窗口，0 表示按模型查表
+
+	// 高严重度问题复核（可选）
+	VerifyEnabled bool   `json:"verify_enabled,omitempty"` // 对 P0/P1 问题进行二次确认
+	VerifyModel   string `json:"verify_model,omitempty"`   // 复核模型，为空时使用审查模型
+	VerifyAction  string `json:"verify_action,omitempty"`  // 被否定的问题：drop（丢弃，默认）/ downgrade（降为 P2）
+
+	// 复核使用服务端 llm.endpoints 中定义的端点（可选），为空时沿用审查使用的提供商和凭据
+	VerifyEndpoint string `json:"verify_endpoint,omitempty"`
+
+	// 多模型对比（可选）：列出的模型与主模型并行审查同一 PR，只存储结果不发布评论
+	// 每项可引用服务端定义的端点，只写模型名（字符串）时沿用审查使用的提供商和凭据
+	CompareModels []LLMEndpoint `json:"compare_models,omitempty"`
+
+	// 自洽性投票（可选）：同一审查采样 SampleCount 次，至少 SampleMinVotes 次报告的问题才保留
+	SampleCount       int     `json:"sample_count,omitempty"`       // 采样次数，不大于 1 表示不采样
+	SampleMinVotes    int     `json:"sample_min_votes,omitempty"`   // 最少票数，0 表示过半数
+	SampleTemperature float64 `json:"sample_temperature,omitempty"` // 采样温度，0 表示使用默认值
+
+	// 工具调用审查（可选）：模型可按需读取文件、列目录、搜索代码
+	// 每次运行读取的内容不同，结果不写入响应缓存；不能与自洽性投票（SampleCount > 1）同时启用
+	AgentEnabled       bool `json:"agent_enabled,omitempty"`
+	AgentMaxToolCalls  int  `json:"agent_max_tool_calls,omitempty"`  // 单次审查最多工具调用次数，0 表示默认 10
+	AgentMaxToolTokens int  `json:"agent_max_tool_tokens,omitempty"` // 单次审查工具输出的最大 Token 总数，0 表示默认 16000
+
+	// PR 上下文：随 Diff 发送 PR 标题和描述、提交信息、关联 Issue，长度上限为字符数，0 表示使用默认值
+	IncludeDescription   bool `json:"include_description"`
+	IncludeCommits       bool `json:"include_commits"`
+	IncludeLinkedIssues  bool `json:"include_linked_issues"`
+	DescriptionMaxChars  int  `json:"description_max_chars,omitempty"`   // 默认 2000
+	CommitsMaxChars      int  `json:"commits_max_chars,omitempty"`       // 默认 2000
+	LinkedIssuesMaxChars int  `json:"linked_issues_max_chars,omitempty"` // 默认 3000
+
+	// 允许获取关联 Issue 的其他仓库，如 owner/shared、owner/*；默认只获取本仓库的 Issue
+	LinkedIssueRepos []string `json:"linked_issue_repos,omitempty"`
+
+	// 仓库编码规范（可选）：从目标分支读取规范文件注入系统提示词，问题可引用规范章节
+	GuidelineFiles     []string `json:"guideline_files,omitempty"`      // 如 docs/STYLE.md、.github/REVIEW_GUIDELINES.md
+	GuidelineMaxTokens int      `json:"guideline_max_tokens,omitempty"` // 规范总 Token 上限，超出的文件由模型摘要，0 表示默认 3000
+
+	// 语言专项检查（可选）：键为语言，值替换内置检查清单，空字符串表示关闭该语言的专项检查
+	LanguagePrompts map[string]string `json:"language_prompts,omitempty"`
+
+	// 关闭的内置模式规则 ID，如 builtin-go-println
+	DisabledBuiltinRules []string `json:"disabled_builtin_rules,omitempty"`
+
+	// 敏感信息脱敏：默认启用内置检测器，可追加仓库自定义规则
+	DisableRedaction bool            `json:"disable_redaction,omitempty"` // 关闭脱敏（不建议）
+	RedactPatterns   []RedactPattern `json:"redact_patterns,omitempty"`   // 自定义脱敏规则
+
+	// 密钥扫描：默认对所有新增行启用，发现密钥时提交状态为失败（未开启自动审查时同样生效）
+	DisableSecretScan   bool   `json:"disable_secret_scan,omitempty"`
+	SecretAllowlistFile string `json:"secret_allowlist_file,omitempty"` // 目标分支上的 allowlist 文件，默认 .code-sentinel/secrets-allowlist
+
+	// 依赖变更分析：默认启用，对比导入的 OSV 漏洞库（不受忽略文件和语言过滤影响）
+	DisableDependencyCheck bool `json:"disable_dependency_check,omitempty"`
+
+	// 外部检查工具：服务端 linters.tools 中配置的工具名，为空不执行
+	Linters []string `json:"linters,omitempty"`
+
 	// 仓库级 LLM 配置（可选，覆盖全局配置）
 	LLMAPIKey  string `json:"llm_api_key,omitempty"`  // LLM API Key
 	LLMBaseURL string `json:"llm_base_url,omitempty"` // LLM API Base URL
@@ -43,6 +112,29 @@ type ReviewConfig struct {
 	GitHubToken string `json:"github_token,omitempty"` // GitHub Personal Access Token
 }
 
+// RedactPattern 自定义脱敏规则，Pattern 含捕获组时只替换第一个捕获组
+type RedactPattern struct {
+	Name    string `json:"name"`
+	Pattern string `json:"pattern"`
+}
+
+// LLMEndpoint 对比使用的模型：Endpoint 为服务端 llm.endpoints 中定义的端点名，为空时沿用审查使用的提供商和凭据
+type LLMEndpoint struct {
+	Endpoint string `json:"endpoint,omitempty"`
+	Model    string `json:"model,omitempty"` // 为空时使用端点配置的模型
+}
+
+// UnmarshalJSON 兼容只写模型名的字符串形式
+func (e *LLMEndpoint) UnmarshalJSON(data []byte) error {
+	var name string
+	if json.Unmarshal(data, &name) == nil {
+		*e = LLMEndpoint{Model: name}
+		return nil
+	}
+	type endpoint LLMEndpoint
+	return json.Unmarshal(data, (*endpoint)(e))
+}
+
 type Config struct {
 	ID          uint      `gorm:"primaryKey" json:"id"`
 	Key         string    `gorm:"uniqueIndex;size:100" json:"key"`
@@ -64,8 +156,27 @@ type Review struct {
 	Result       string       `gorm:"type:text" json:"result"`
 	TokenUsed    int          `json:"token_used"`
 	DurationMs   int64        `json:"duration_ms"`
-	ErrorMsg     string       `gorm:"type:text" json:"error_msg,omitempty"`
-	CreatedAt    time.Time    `json:"created_at"`
+
+	// 成本核算
+	Provider         string  `gorm:"size:50" json:"provider"`
+	Model            string  `gorm:"size:100;index" json:"model"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	Cost             float64 `json:"cost"` // 按价格表计算的费用，命中缓存的调用不计费
+
+	BaseSHA string `gorm:"size:40" json:"base_sha,omitempty"` // 审查时 PR 目标分支的提交，与 CommitSHA 一起还原当时审查的 Diff
+
+	Redactions  int    `json:"redactions"`                   // 发送给模型前脱敏的敏感信息数量
+	ReviewFocus string `gorm:"size:100" json:"review_focus"` // 实际应用的审查重点，逗号分隔
+	IssueCount  int    `json:"issue_count"`                  // 发布的问题数
+
+	// 提示词版本：0 表示内置默认提示词或仓库配置中的自定义提示词
+	PromptVersionID uint `gorm:"index" json:"prompt_version_id"`
+	ExperimentID    uint `gorm:"index" json:"experiment_id,omitempty"` // 参与的提示词实验
+
+	CacheHit  bool      `gorm:"default:false" json:"cache_hit"` // LLM 响应是否来自缓存
+	ErrorMsg  string    `gorm:"type:text" json:"error_msg,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
 type ReviewStatus string
@@ -76,6 +187,7 @@ const (
 	ReviewStatusCompleted ReviewStatus = "completed"
 	ReviewStatusFailed    ReviewStatus = "failed"
 	ReviewStatusSkipped   ReviewStatus = "skipped"
+	ReviewStatusDegraded  ReviewStatus = "degraded" // 模型输出修复后仍未通过校验
 )
 
 type ReviewResult struct {
@@ -85,6 +197,112 @@ type ReviewResult struct {
 	Score    int           `json:"score"`
 	Model    string        `json:"model"`
 	Duration int64         `json:"duration_ms"`
+
+	Omitted []OmittedChange `json:"omitted,omitempty"` // 因超出 Token 预算未审查的变更
+
+	AssumedContextWindow int `json:"assumed_context_window,omitempty"` // 模型上下文窗口未知时假定的窗口大小
+
+	Degraded         bool     `json:"degraded,omitempty"`          // 模型输出未通过校验，结果不可信
+	ValidationErrors []string `json:"validation_errors,omitempty"` // 最后一次校验的错误
+	RawOutput        string   `json:"raw_output,omitempty"`        // 降级时未通过校验的模型原始输出，不发布到评论
+
+	Guard *GuardReport `json:"guard,omitempty"` // 问题定位校验记录
+
+	Verifications []IssueVerification `json:"verifications,omitempty"` // P0/P1 问题复核结论
+
+	Samples  int `json:"samples,omitempty"`   // 参与投票的有效采样次数
+	MinVotes int `json:"min_votes,omitempty"` // 问题保留所需的最少票数
+
+	Redactions map[string]int `json:"redactions,omitempty"` // 各类敏感信息的脱敏次数
+
+	Focus         []string `json:"focus,omitempty"`          // 实际应用的审查重点
+	FocusFiltered int      `json:"focus_filtered,omitempty"` // 类别不在审查重点内被丢弃的问题数
+
+	Guidelines []GuidelineRef `json:"guidelines,omitempty"` // 注入提示词的仓库规范文件
+
+	Rules []string `json:"rules,omitempty"` // 变更路径匹配并注入提示词的审查规则 ID
+
+	LLMError string `json:"llm_error,omitempty"` // 模型调用失败的错误，此时结果只包含模式规则发现的问题
+
+	Dependencies []DependencyChange `json:"dependencies,omitempty"` // 依赖清单和锁文件的版本变更
+
+	Linters []LinterRun `json:"linters,omitempty"` // 外部检查工具的执行情况
+}
+
+// LinterRun 外部检查工具的执行情况
+type LinterRun struct {
+	Tool       string `json:"tool"`
+	Findings   int    `json:"findings"` // 工具报告的问题数
+	Kept       int    `json:"kept"`     // 位于变更行上、合并到结果中的问题数
+	DurationMs int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"` // 检出或执行失败的原因
+}
+
+// GuidelineRef 注入提示词的仓库规范文件
+type GuidelineRef struct {
+	File       string `json:"file"`
+	Ref        string `json:"ref"`        // 读取的版本（目标分支 SHA）
+	Summarized bool   `json:"summarized"` // 超出长度上限，注入的是模型摘要
+}
+
+// IssueVerification 单个问题的复核结论
+type IssueVerification struct {
+	File      string `json:"file"`
+	Line      int    `json:"line"`
+	Title     string `json:"title"`
+	Severity  string `json:"severity"`
+	Verdict   string `json:"verdict"`   // confirmed / refuted / uncertain
+	Reasoning string `json:"reasoning"` // 模型给出的理由
+	Model     string `json:"model"`
+	Action    string `json:"action"` // kept / dropped / downgraded
+	Error     string `json:"error,omitempty"`
+}
+
+// 复核结论
+const (
+	VerdictConfirmed = "confirmed"
+	VerdictRefuted   = "refuted"
+	VerdictUncertain = "uncertain"
+)
+
+// GuardReport 按实际发送的 Diff 校验问题位置的处理记录
+type GuardReport struct {
+	Checked   int           `json:"checked"`
+	Kept      int           `json:"kept"`
+	Corrected int           `json:"corrected"` // 文件路径或行号被修正
+	Demoted   int           `json:"demoted"`
+	Dropped   int           `json:"dropped"`
+	Actions   []GuardAction `json:"actions,omitempty"`
+}
+
+// GuardAction 单个问题的处理动作
+type GuardAction struct {
+	Action      string `json:"action"` // file_corrected / snapped / file_level / demoted / dropped
+	Title       string `json:"title"`
+	File        string `json:"file"`
+	Line        int    `json:"line"`
+	NewFile     string `json:"new_file,omitempty"`
+	NewLine     int    `json:"new_line,omitempty"`
+	Severity    string `json:"severity,omitempty"`
+	NewSeverity string `json:"new_severity,omitempty"`
+	Reason      string `json:"reason"`
+}
+
+// 问题定位校验动作
+const (
+	GuardActionFileCorrected = "file_corrected"
+	GuardActionSnapped       = "snapped"
+	GuardActionFileLevel     = "file_level"
+	GuardActionDemoted       = "demoted"
+	GuardActionDropped       = "dropped"
+)
+
+// OmittedChange 因超出 Token 预算未发送给模型的变更
+type OmittedChange struct {
+	File   string `json:"file"`
+	Hunk   string `json:"hunk,omitempty"` // 为空表示整个文件
+	Lines  int    `json:"lines"`
+	Reason string `json:"reason"`
 }
 
 type ReviewIssue struct {
@@ -95,9 +313,23 @@ type ReviewIssue struct {
 	Title       string `json:"title"` // 问题标题
 	Description string `json:"description"`
 	Suggestion  string `json:"suggestion,omitempty"`
-	CodeFix     string `json:"code_fix,omitempty"` // 修复代码
+	CodeFix     string `json:"code_fix,omitempty"`  // 修复代码
+	Guideline   string `json:"guideline,omitempty"` // 问题依据的仓库规范，如 docs/STYLE.md#错误处理
+	RuleID      string `json:"rule_id,omitempty"`   // 问题依据的仓库审查规则，如 SEC-001
+	Votes       int    `json:"votes,omitempty"`     // 多次采样中报告该问题的次数
+	Source      string `json:"source,omitempty"`    // 问题来源：llm / rule / secret / dependency / linter
+	Tool        string `json:"tool,omitempty"`      // 外部检查工具名，仅 source 为 linter 时有值
 }
 
+// 问题来源
+const (
+	IssueSourceLLM        = "llm"        // 模型审查
+	IssueSourceRule       = "rule"       // 模式规则匹配
+	IssueSourceSecret     = "secret"     // 密钥扫描
+	IssueSourceDependency = "dependency" // 依赖变更分析
+	IssueSourceLinter     = "linter"     // 外部检查工具
+)
+
 // ReviewStats 审查统计
 type ReviewStats struct {
 	P0Count int `json:"p0_count"`
@@ -105,21 +337,54 @@ type ReviewStats struct {
 	P2Count int `json:"p2_count"`
 }
 
+// LLMCache LLM 响应缓存（按提示词、模型和温度的哈希寻址）
+type LLMCache struct {
+	ID        uint   `gorm:"primaryKey" json:"id"`
+	CacheKey  string `gorm:"uniqueIndex;size:64" json:"cache_key"` // SHA-256
+	Model     string `gorm:"size:100" json:"model"`
+	Response  string `gorm:"type:text" json:"response"`
+	TokenUsed int    `json:"token_used"`
+	HitCount  int    `gorm:"default:0" json:"hit_count"`
+
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+
+	ExpiresAt time.Time `gorm:"index" json:"expires_at"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
+
 // Feedback 误报反馈记录
 type Feedback struct {
 	ID              uint      `gorm:"primaryKey" json:"id"`
 	ReviewID        uint      `gorm:"index" json:"review_id"` // 关联的审查记录
 	RepoFullName    string    `gorm:"index;size:200" json:"repo_full_name"`
 	PRNumber        int       `json:"pr_number"`
-	File            string    `gorm:"size:500" json:"file"`        // 文件路径
-	Line            int       `json:"line"`                        // 行号
-	IssueIndex      int       `json:"issue_index"`                 // 问题索引
-	Severity        string    `gorm:"size:10" json:"severity"`     // P0/P1/P2
-	Category        string    `gorm:"size:20" json:"category"`     // security/performance/logic/style
-	Title           string    `gorm:"size:255" json:"title"`       // 问题标题
-	AIContent       string    `gorm:"type:text" json:"ai_content"` // AI 原始判断
-	IsFalsePositive bool      `gorm:"index;default:true" json:"is_false_positive"`
-	Reason          string    `gorm:"type:text" json:"reason"`  // 用户提供的原因
-	Reporter        string    `gorm:"size:100" json:"reporter"` // 反馈人
+	File            string    `gorm:"size:500" json:"file"`                   // 文件路径
+	Line            int       `json:"line"`                                   // 行号
+	IssueIndex      int       `json:"issue_index"`                            // 问题索引
+	Severity        string    `gorm:"size:10" json:"severity"`                // P0/P1/P2
+	Category        string    `gorm:"size:20" json:"category"`                // security/performance/logic/style
+	Title           string    `gorm:"size:255" json:"title"`                  // 问题标题
+	AIContent       string    `gorm:"type:text" json:"ai_content"`            // AI 原始判断
+	IsFalsePositive bool      `gorm:"index" json:"is_false_positive"`         // 不设数据库默认值，否则 GORM 会把显式的 false 替换为默认值；API 缺省为 true 由 handler 处理
+	Reason          string    `gorm:"type:text" json:"reason"`                // 用户提供的原因
+	Reporter        string    `gorm:"size:100" json:"reporter"`               // 反馈人
+	RuleID          string    `gorm:"index;size:50" json:"rule_id,omitempty"` // 问题引用的审查规则
 	CreatedAt       time.Time `gorm:"index" json:"created_at"`
 }
+
+// ToolCallTrace 工具调用审查中每次工具调用的审计记录
+type ToolCallTrace struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	ReviewID   uint      `gorm:"index" json:"review_id"`
+	Model      string    `gorm:"size:100" json:"model"`
+	Round      int       `json:"round"` // 第几轮对话
+	CallID     string    `gorm:"size:100" json:"call_id"`
+	Tool       string    `gorm:"size:50" json:"tool"`
+	Arguments  string    `gorm:"type:text" json:"arguments"`
+	Output     string    `gorm:"type:text" json:"output"` // 返回给模型的内容（已截断）
+	Tokens     int       `json:"tokens"`
+	Error      string    `gorm:"type:text" json:"error,omitempty"`
+	DurationMs int64     `json:"duration_ms"`
+	CreatedAt  time.Time `json:"created_at"`
+}
@@ -0,0 +1,61 @@
+package model
+
+import "time"
+
+// PromptVersion 系统提示词的不可变版本，修改提示词时以同名创建新版本
+type PromptVersion struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Name      string    `gorm:"uniqueIndex:idx_prompt_version;size:100" json:"name"`
+	Version   int       `gorm:"uniqueIndex:idx_prompt_version" json:"version"` // 同名模板内从 1 递增
+	Content   string    `gorm:"type:text" json:"content"`                      // 支持 {review_focus}、{categories} 占位符
+	Note      string    `gorm:"size:500" json:"note"`                          // 本版本的修改说明
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ExperimentStatus 提示词实验状态
+type ExperimentStatus string
+
+const (
+	ExperimentStatusRunning ExperimentStatus = "running"
+	ExperimentStatusStopped ExperimentStatus = "stopped"
+)
+
+// PromptExperiment 仓库级提示词 A/B 实验，PR 按百分比稳定分配到各变体
+type PromptExperiment struct {
+	ID           uint                `gorm:"primaryKey" json:"id"`
+	RepoFullName string              `gorm:"index;size:200" json:"repo_full_name"`
+	Name         string              `gorm:"size:100" json:"name"`
+	Status       ExperimentStatus    `gorm:"size:20;index" json:"status"`
+	Variants     []ExperimentVariant `gorm:"serializer:json;type:text" json:"variants"`
+	CreatedAt    time.Time           `json:"created_at"`
+	UpdatedAt    time.Time           `json:"updated_at"`
+	StoppedAt    *time.Time          `json:"stopped_at,omitempty"`
+}
+
+// ExperimentVariant 实验变体，PromptVersionID 为 0 表示仓库当前使用的提示词（对照组）
+type ExperimentVariant struct {
+	PromptVersionID uint `json:"prompt_version_id"`
+	Percent         int  `json:"percent"` // 分配到该变体的 PR 百分比，所有变体之和为 100
+}
+
+// ExperimentReport 提示词实验各变体的效果对比
+type ExperimentReport struct {
+	Experiment PromptExperiment `json:"experiment"`
+	Variants   []VariantStats   `json:"variants"`
+}
+
+// VariantStats 单个实验变体的审查效果汇总
+type VariantStats struct {
+	PromptVersionID uint    `json:"prompt_version_id"`
+	PromptName      string  `json:"prompt_name,omitempty"`
+	PromptVersion   int     `json:"prompt_version,omitempty"`
+	Percent         int     `json:"percent"`
+	Reviews         int     `json:"reviews"`         // 已完成的审查数
+	Issues          int     `json:"issues"`          // 发布的问题总数
+	IssuesPerPR     float64 `json:"issues_per_pr"`   // 平均每次审查发布的问题数
+	FalsePositives  int     `json:"false_positives"` // 被反馈为误报的问题数
+	FeedbackRate    float64 `json:"feedback_rate"`   // 误报反馈数 / 问题总数
+	AvgTokens       float64 `json:"avg_tokens"`
+	AvgDurationMs   float64 `json:"avg_duration_ms"`
+	AvgCost         float64 `json:"avg_cost"`
+}
@@ -0,0 +1,35 @@
+package model
+
+import "time"
+
+// ReviewRule 仓库自定义的审查规则，RuleID 在仓库内唯一且创建后保持不变
+// Pattern 为空时是自然语言规则，注入提示词由模型判断；非空时是模式规则，直接用正则匹配新增行，不经过模型
+type ReviewRule struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	RepoFullName string    `gorm:"uniqueIndex:idx_repo_rule;size:200" json:"repo_full_name"`
+	RuleID       string    `gorm:"uniqueIndex:idx_repo_rule;size:50" json:"rule_id"` // 如 SEC-001，问题通过 rule_id 引用
+	Description  string    `gorm:"type:text" json:"description"`
+	Severity     string    `gorm:"size:10" json:"severity"`                // 违反规则时问题的严重程度 P0/P1/P2
+	Category     string    `gorm:"size:20" json:"category"`                // security/performance/logic/style
+	Paths        []string  `gorm:"serializer:json;type:text" json:"paths"` // 适用路径 glob，支持 **，为空表示所有文件
+	Examples     string    `gorm:"type:text" json:"examples"`              // 违反和符合规则的示例
+	Enabled      bool      `json:"enabled"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+
+	// 模式规则（可选）
+	Pattern   string   `gorm:"size:500" json:"pattern,omitempty"`                    // RE2 正则表达式，匹配新增行
+	Languages []string `gorm:"serializer:json;type:text" json:"languages,omitempty"` // 适用语言，为空表示所有语言
+}
+
+// RuleHit 发布的审查问题引用审查规则的记录
+type RuleHit struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	RepoFullName string    `gorm:"index:idx_rule_hit;size:200" json:"repo_full_name"`
+	RuleID       string    `gorm:"index:idx_rule_hit;size:50" json:"rule_id"`
+	ReviewID     uint      `gorm:"index" json:"review_id"`
+	File         string    `gorm:"size:500" json:"file"`
+	Line         int       `json:"line"`
+	Severity     string    `gorm:"size:10" json:"severity"`
+	CreatedAt    time.Time `json:"created_at"`
+}
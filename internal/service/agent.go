@@ -0,0 +1,350 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"code-sentinel/internal/model"
+	"code-sentinel/pkg/tokenizer"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultAgentMaxToolCalls  = 10
+	defaultAgentMaxToolTokens = 16000
+	// maxAgentRounds 工具调用对话的最大轮数，超出后要求模型直接给出结论
+	maxAgentRounds = 8
+	// maxToolOutputTokens 单次工具输出的最大 Token 数
+	maxToolOutputTokens = 2000
+	// maxToolFileLines 读取文件时单次返回的最大行数
+	maxToolFileLines = 300
+	// maxToolSearchResults 代码搜索返回的最大结果数
+	maxToolSearchResults = 10
+)
+
+// 工具名称
+const (
+	toolReadFile      = "read_file"
+	toolReadBaseFile  = "read_base_file"
+	toolListDirectory = "list_directory"
+	toolSearchCode    = "search_code"
+)
+
+// agentPromptSuffix 工具调用审查时追加到系统提示词的说明
+const agentPromptSuffix = `
+
+## 可用工具
+审查时如需了解 Diff 之外的上下文（调用方、接口定义、配置等），可以调用只读工具：
+- read_file：读取 PR 最新提交中的文件
+- read_base_file：读取目标分支（修改前）版本的文件
+- list_directory：列出目录内容
+- search_code：在仓库中搜索符号或关键字（仅索引默认分支）
+
+工具调用次数和返回内容有限额，请只在确实需要时调用。信息足够后，按上述格式直接输出最终的 JSON 审查结果。`
+
+// agentBudgetExhaustedPrompt 工具额度用完时追加的提示
+const agentBudgetExhaustedPrompt = "工具调用额度已用完，请根据已有信息直接输出最终的 JSON 审查结果。"
+
+// errToolBudgetExceeded 工具调用次数或输出 Token 已达上限
+var errToolBudgetExceeded = errors.New("tool call budget exceeded")
+
+var lineRangeParams = map[string]any{
+	"start_line": map[string]any{"type": "integer", "description": "起始行号（从 1 开始，可选）"},
+	"end_line":   map[string]any{"type": "integer", "description": "结束行号（可选）"},
+}
+
+// reviewTools 工具调用审查可用的只读工具
+var reviewTools = []model.Tool{
+	newTool(toolReadFile, "读取 PR 最新提交（head）中的文件内容，返回带行号的文本", map[string]any{
+		"path": map[string]any{"type": "string", "description": "相对仓库根目录的文件路径"},
+	}, lineRangeParams),
+	newTool(toolReadBaseFile, "读取目标分支（base）中修改前的文件内容，返回带行号的文本", map[string]any{
+		"path": map[string]any{"type": "string", "description": "相对仓库根目录的文件路径"},
+	}, lineRangeParams),
+	newTool(toolListDirectory, "列出 PR 最新提交中指定目录的文件和子目录", map[string]any{
+		"path": map[string]any{"type": "string", "description": "相对仓库根目录的目录路径，根目录传空字符串"},
+	}, nil),
+	newTool(toolSearchCode, "在仓库中搜索符号、函数名或关键字，返回命中的文件和代码片段", map[string]any{
+		"query": map[string]any{"type": "string", "description": "搜索关键字，不支持 repo:、path: 等限定符"},
+	}, nil),
+}
+
+// newTool 构建函数工具定义，required 为 props 中的全部参数
+func newTool(name, description string, props map[string]any, optional map[string]any) model.Tool {
+	properties := make(map[string]any)
+	var required []string
+	for k, v := range props {
+		properties[k] = v
+		required = append(required, k)
+	}
+	sort.Strings(required)
+	for k, v := range optional {
+		properties[k] = v
+	}
+	return model.Tool{
+		Type: "function",
+		Function: model.ToolFunction{
+			Name:        name,
+			Description: description,
+			Parameters: map[string]any{
+				"type":       "object",
+				"properties": properties,
+				"required":   required,
+			},
+		},
+	}
+}
+
+// toolArgs 工具调用参数
+type toolArgs struct {
+	Path      string `json:"path"`
+	Query     string `json:"query"`
+	StartLine int    `json:"start_line"`
+	EndLine   int    `json:"end_line"`
+}
+
+// generateWithTools 工具调用审查：模型可多轮调用只读工具获取上下文，最后输出 JSON 结果
+// 工具调用次数和输出 Token 按审查累计限额，所有调用记录存入 ToolCallTrace
+func (s *AnalyzerService) generateWithTools(ctx context.Context, run *reviewRun, systemPrompt, userPrompt string) (*model.ReviewResult, error) {
+	llmSvc := run.llmSvc
+	messages := newMessages(systemPrompt+agentPromptSuffix, userPrompt)
+
+	var output string
+	for round := 1; ; round++ {
+		tools := reviewTools
+		if round > maxAgentRounds || run.toolBudgetExhausted() {
+			tools = nil
+		}
+
+		msg, usage, err := llmSvc.ChatWithTools(ctx, messages, tools, reviewResponseFormat)
+		if err != nil {
+			return nil, err
+		}
+		run.recordLLMCall(usage, s.pricing.Cost(llmSvc.GetProvider(), llmSvc.GetModel(), usage), false)
+
+		if len(msg.ToolCalls) == 0 || tools == nil {
+			output = msg.Content
+			run.recordExchange(llmSvc.GetModel(), messages, output, usage)
+			break
+		}
+
+		messages = append(messages, msg)
+		for _, call := range msg.ToolCalls {
+			messages = append(messages, model.Message{
+				Role:       "tool",
+				ToolCallID: call.ID,
+				Content:    s.executeTool(ctx, run, round, call),
+			})
+		}
+
+		if run.toolBudgetExhausted() {
+			messages = append(messages, model.Message{Role: "user", Content: agentBudgetExhaustedPrompt})
+		}
+	}
+
+	result, _, _ := s.validateOrRepair(ctx, run, llmSvc, messages, output)
+	return result, nil
+}
+
+// executeTool 执行单个工具调用并记录审计日志，返回给模型的内容已按限额截断
+func (s *AnalyzerService) executeTool(ctx context.Context, run *reviewRun, round int, call model.ToolCall) string {
+	start := time.Now()
+	trace := &model.ToolCallTrace{
+		ReviewID:  run.review.ID,
+		Model:     run.llmSvc.GetModel(),
+		Round:     round,
+		CallID:    call.ID,
+		Tool:      call.Function.Name,
+		Arguments: call.Function.Arguments,
+	}
+
+	output, err := s.runTool(ctx, run, call)
+	if err != nil {
+		trace.Error = err.Error()
+		output = "ERROR: " + err.Error()
+	} else {
+		output = run.redact(output)
+		est := tokenizer.ForModel(run.llmSvc.GetModel())
+		limit := min(maxToolOutputTokens, run.remainingToolTokens())
+		output = truncateToTokens(output, limit, est)
+		trace.Tokens = est.Count(output)
+		run.addToolTokens(trace.Tokens)
+	}
+	trace.Output = output
+	trace.DurationMs = time.Since(start).Milliseconds()
+
+	s.publishStage(run, model.ReviewStageGenerating, fmt.Sprintf("tool %s(%s)", call.Function.Name, truncateRunes(call.Function.Arguments, 80)))
+	if run.dryRun {
+		return output
+	}
+	if err := s.store.CreateToolCallTrace(ctx, trace); err != nil {
+		s.logger.Warn("Failed to save tool call trace", zap.Uint("review_id", run.review.ID), zap.Error(err))
+	}
+
+	return output
+}
+
+// runTool 校验参数并通过 GitHubService 执行只读工具
+func (s *AnalyzerService) runTool(ctx context.Context, run *reviewRun, call model.ToolCall) (string, error) {
+	if !run.reserveToolCall() {
+		return "", errToolBudgetExceeded
+	}
+
+	var args toolArgs
+	if call.Function.Arguments != "" {
+		if err := json.Unmarshal([]byte(call.Function.Arguments), &args); err != nil {
+			return "", fmt.Errorf("invalid arguments: %w", err)
+		}
+	}
+
+	repo := run.review.RepoFullName
+	gh := run.githubSvc
+
+	switch call.Function.Name {
+	case toolReadFile, toolReadBaseFile:
+		p, err := cleanRepoPath(args.Path)
+		if err != nil || p == "" {
+			return "", fmt.Errorf("invalid path %q", args.Path)
+		}
+		ref := run.review.CommitSHA
+		if call.Function.Name == toolReadBaseFile {
+			ref = baseRef(run.event)
+			if ref == "" {
+				return "", errors.New("base version unavailable")
+			}
+		}
+		content, err := gh.GetFileContent(ctx, repo, p, ref)
+		if err != nil {
+			return "", err
+		}
+		return numberLines(content, args.StartLine, args.EndLine), nil
+
+	case toolListDirectory:
+		p, err := cleanRepoPath(args.Path)
+		if err != nil {
+			return "", fmt.Errorf("invalid path %q", args.Path)
+		}
+		entries, err := gh.ListDirectory(ctx, repo, p, run.review.CommitSHA)
+		if err != nil {
+			return "", err
+		}
+		var sb strings.Builder
+		for _, e := range entries {
+			if e.Type == "dir" {
+				fmt.Fprintf(&sb, "%s/\n", e.Path)
+			} else {
+				fmt.Fprintf(&sb, "%s (%d bytes)\n", e.Path, e.Size)
+			}
+		}
+		return sb.String(), nil
+
+	case toolSearchCode:
+		query := strings.TrimSpace(args.Query)
+		if query == "" {
+			return "", errors.New("query is required")
+		}
+		result, err := gh.SearchCode(ctx, repo, query, maxToolSearchResults)
+		if err != nil {
+			return "", err
+		}
+		var sb strings.Builder
+		fmt.Fprintf(&sb, "%d results (default branch)\n", result.TotalCount)
+		for _, item := range result.Items {
+			fmt.Fprintf(&sb, "\n## %s\n", item.Path)
+			for _, m := range item.TextMatches {
+				sb.WriteString(m.Fragment)
+				sb.WriteString("\n")
+			}
+		}
+		return sb.String(), nil
+
+	default:
+		return "", fmt.Errorf("unknown tool %q", call.Function.Name)
+	}
+}
+
+// cleanRepoPath 规范化仓库内路径，拒绝跳出仓库根目录或携带查询参数的路径
+func cleanRepoPath(p string) (string, error) {
+	p = strings.TrimSpace(p)
+	if strings.Contains(p, "..") {
+		return "", errors.New("path must not contain ..")
+	}
+	if strings.ContainsAny(p, "?#") {
+		return "", errors.New("path must not contain ? or #")
+	}
+	return strings.TrimPrefix(path.Clean("/"+p), "/"), nil
+}
+
+// baseRef 返回 PR 目标分支的版本，优先使用 SHA
+func baseRef(event *model.PullRequestEvent) string {
+	if event.PullRequest.Base.SHA != "" {
+		return event.PullRequest.Base.SHA
+	}
+	return event.PullRequest.Base.Ref
+}
+
+// numberLines 为文件内容加上行号，并截取指定行范围（最多 maxToolFileLines 行）
+func numberLines(content string, startLine, endLine int) string {
+	lines := strings.Split(content, "\n")
+	start := max(startLine, 1)
+	end := endLine
+	if end <= 0 || end > len(lines) {
+		end = len(lines)
+	}
+	if end-start+1 > maxToolFileLines {
+		end = start + maxToolFileLines - 1
+	}
+	if start > end {
+		return fmt.Sprintf("(file has %d lines)", len(lines))
+	}
+
+	var sb strings.Builder
+	for n := start; n <= end; n++ {
+		fmt.Fprintf(&sb, "%d: %s\n", n, lines[n-1])
+	}
+	if end < len(lines) {
+		fmt.Fprintf(&sb, "... (%d lines total, use start_line/end_line to read more)\n", len(lines))
+	}
+	return sb.String()
+}
+
+// truncateToTokens 按 Token 上限截断文本
+func truncateToTokens(text string, limit int, est tokenizer.Estimator) string {
+	if limit <= 0 {
+		return "(tool output budget exhausted)"
+	}
+	if est.Count(text) <= limit {
+		return text
+	}
+
+	lines := strings.Split(text, "\n")
+	var sb strings.Builder
+	used := 0
+	for _, line := range lines {
+		cost := est.Count(line + "\n")
+		if used+cost > limit {
+			break
+		}
+		sb.WriteString(line)
+		sb.WriteString("\n")
+		used += cost
+	}
+	sb.WriteString("... (truncated)\n")
+	return sb.String()
+}
+
+// truncateRunes 按字符数截断字符串
+func truncateRunes(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n]) + "..."
+}
@@ -3,15 +3,22 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
+	"sync"
 	"time"
 
+	"code-sentinel/internal/config"
 	"code-sentinel/internal/model"
 	"code-sentinel/internal/store"
 	"code-sentinel/pkg/diff"
 	"code-sentinel/pkg/prompt"
+	"code-sentinel/pkg/redact"
+	"code-sentinel/pkg/secrets"
 
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
 // 默认系统提示词（当仓库未配置时使用）
@@ -20,10 +27,7 @@ const defaultSystemPrompt = `你是资深代码审查专家，精通多种编程
 你的任务是审查代码变更，识别潜在问题，并提供详细的修复建议。
 
 ## 审查重点
-- 安全问题：SQL 注入、XSS、硬编码密钥、敏感信息泄露、不安全的加密
-- 性能问题：循环内查库、N+1 查询、不必要的重复计算、内存泄漏
-- 逻辑错误：空指针、边界条件、异常处理不当、死循环、竞态条件
-- 代码风格：命名规范、注释质量、代码可读性、过长函数
+{review_focus}
 
 ## 严重程度定义
 - P0（严重）：安全漏洞、会导致系统崩溃或数据泄露的问题
@@ -35,16 +39,19 @@ const defaultSystemPrompt = `你是资深代码审查专家，精通多种编程
 
 {
   "summary": "本次审查总体评价（1-2句话）",
+  "score": 代码质量评分（0-100 的整数，越高越好）,
   "issues": [
     {
       "severity": "P0|P1|P2",
-      "category": "security|performance|logic|style",
+      "category": "{categories}",
       "file": "文件路径",
       "line": 行号,
       "title": "问题标题（简短）",
       "description": "问题详细描述",
       "suggestion": "修复建议",
-      "code_fix": "修复后的代码片段（可选）"
+      "code_fix": "修复后的代码片段（可选）",
+      "guideline": "问题依据的仓库规范章节（可选）",
+      "rule_id": "问题依据的仓库审查规则编号（可选）"
     }
   ],
   "stats": {
@@ -56,18 +63,36 @@ const defaultSystemPrompt = `你是资深代码审查专家，精通多种编程
 
 ## 注意事项
 - 如果代码没有问题，issues 返回空数组，summary 写 "代码质量良好，未发现明显问题"
-- code_fix 字段仅在能提供具体修复代码时填写
+- code_fix 字段仅在能提供具体修复代码时填写，guideline 字段仅在问题违反下文给出的仓库规范时填写，rule_id 字段仅在问题违反下文给出的仓库审查规则时填写
 - 保持客观和专业，避免主观判断
 - 确保输出的是合法的 JSON，不要包含注释或额外文本`
 
+// redactionPromptNote Diff 或 PR 说明中存在脱敏占位符时追加到系统提示词的说明
+const redactionPromptNote = `
+
+## 脱敏说明
+代码和 PR 说明中形如 [REDACTED:类型#序号] 的内容是发送前被替换的敏感信息（密钥、令牌、私钥、连接串密码、邮箱、手机号等），相同序号表示同一个值。
+[REDACTED:secret] 是密钥扫描识别出的凭据，不带序号。
+- 占位符出现在新增代码中，说明该处硬编码了敏感信息，请照常作为 security 问题报告，并在描述中引用占位符
+- 不要猜测或还原占位符的原始值`
+
 type AnalyzerService struct {
 	githubSvc     *GitHubService
 	llmSvc        *LLMService
 	store         store.Store
 	logger        *zap.Logger
 	builder       *prompt.Builder
+	templates     *prompt.TemplateRegistry
+	guidelines    *guidelineCache
+	progress      *ProgressHub
+	cache         *LLMCache
+	pricing       *CostCalculator
+	linters       *LinterRunner
 	defaultLLMCfg LLMConfig
 	defaultGHCfg  GitHubConfig
+
+	comparisonSem chan struct{}  // 限制同时执行的对比审查数
+	comparisons   sync.WaitGroup // 进行中的对比审查
 }
 
 // LLMConfig 用于创建仓库级 LLM 客户端
@@ -78,6 +103,13 @@ type LLMConfig struct {
 	BaseURL   string
 	Timeout   int
 	MaxTokens int
+	Stream    bool
+
+	ContextWindow    int
+	Temperature      float64
+	StructuredOutput string
+
+	Endpoints []config.LLMEndpoint // 复核和对比可选用的端点
 }
 
 // GitHubConfig 用于创建仓库级 GitHub 客户端
@@ -86,19 +118,279 @@ type GitHubConfig struct {
 	BaseURL string
 }
 
-func NewAnalyzerService(githubSvc *GitHubService, llmSvc *LLMService, store store.Store, logger *zap.Logger, defaultLLMCfg LLMConfig, defaultGHCfg GitHubConfig) *AnalyzerService {
+func NewAnalyzerService(githubSvc *GitHubService, llmSvc *LLMService, store store.Store, cache *LLMCache, pricing *CostCalculator, linters *LinterRunner, logger *zap.Logger, defaultLLMCfg LLMConfig, defaultGHCfg GitHubConfig) *AnalyzerService {
 	return &AnalyzerService{
 		githubSvc:     githubSvc,
 		llmSvc:        llmSvc,
 		store:         store,
 		logger:        logger,
 		builder:       prompt.NewBuilder(),
+		templates:     prompt.NewTemplateRegistry(),
+		guidelines:    newGuidelineCache(),
+		progress:      NewProgressHub(),
+		cache:         cache,
+		pricing:       pricing,
+		linters:       linters,
 		defaultLLMCfg: defaultLLMCfg,
 		defaultGHCfg:  defaultGHCfg,
+		comparisonSem: make(chan struct{}, maxConcurrentComparisons),
+	}
+}
+
+// AnalyzeOptions 审查选项
+type AnalyzeOptions struct {
+	Manual      bool // 手动触发，忽略 AutoReview 开关
+	BypassCache bool // 跳过 LLM 响应缓存，强制重新调用模型
+}
+
+// reviewRun 单次审查的运行上下文
+type reviewRun struct {
+	event     *model.PullRequestEvent
+	review    *model.Review
+	config    *model.ReviewConfig
+	llmSvc    *LLMService
+	githubSvc *GitHubService
+	opts      AnalyzeOptions
+	secondary bool               // 对比模式下的附加模型，不推送进度也不流式输出
+	redactor  *redact.Redactor   // 发送给模型的内容先经过脱敏，为空表示未启用
+	builder   *prompt.Builder    // 附带本次 PR 上下文的提示词构建器
+	rules     []model.ReviewRule // 变更路径匹配并注入提示词的仓库审查规则
+	dryRun    bool               // 演练模式：不推送进度、不写审查相关记录，记录每次 LLM 调用的输入输出
+	secrets   []string           // 检测到的密钥原文，发送给模型的任何内容都会遮盖
+
+	mu        sync.Mutex
+	llmCalls  int         // LLM 调用次数（含缓存命中）
+	cacheHits int         // 命中缓存的次数
+	usage     model.Usage // 累计 Token 用量
+	cost      float64     // 累计费用（命中缓存的调用不计费）
+
+	toolCalls  int // 已执行的工具调用次数
+	toolTokens int // 工具输出累计 Token 数
+
+	transcript []LLMExchange // 演练模式下的 LLM 调用记录
+}
+
+// LLMExchange 一次 LLM 调用的完整输入和原始输出
+type LLMExchange struct {
+	Model    string          `json:"model"`
+	Messages []model.Message `json:"messages"`
+	Response string          `json:"response"`
+	Usage    model.Usage     `json:"usage"`
+}
+
+// recordExchange 演练模式下记录 LLM 调用的输入输出
+func (r *reviewRun) recordExchange(modelName string, messages []model.Message, response string, usage model.Usage) {
+	if !r.dryRun {
+		return
 	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.transcript = append(r.transcript, LLMExchange{
+		Model:    modelName,
+		Messages: append([]model.Message{}, messages...),
+		Response: response,
+		Usage:    usage,
+	})
+}
+
+// recordLLMCall 记录一次 LLM 调用的用量、费用及是否命中缓存（分批审查时并发调用）
+func (r *reviewRun) recordLLMCall(usage model.Usage, cost float64, cacheHit bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.llmCalls++
+	if cacheHit {
+		r.cacheHits++
+	}
+	r.usage.PromptTokens += usage.PromptTokens
+	r.usage.CompletionTokens += usage.CompletionTokens
+	r.usage.TotalTokens += usage.TotalTokens
+	r.cost += cost
+}
+
+// totalUsage 返回累计 Token 用量和费用
+func (r *reviewRun) totalUsage() (model.Usage, float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.usage, r.cost
+}
+
+// reserveToolCall 占用一次工具调用额度，额度用完时返回 false
+func (r *reviewRun) reserveToolCall() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.toolCalls >= r.maxToolCalls() || r.toolTokens >= r.maxToolTokens() {
+		return false
+	}
+	r.toolCalls++
+	return true
+}
+
+// addToolTokens 累计工具输出的 Token 数
+func (r *reviewRun) addToolTokens(tokens int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.toolTokens += tokens
+}
+
+// remainingToolTokens 剩余可用的工具输出 Token 数
+func (r *reviewRun) remainingToolTokens() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.maxToolTokens() - r.toolTokens
+}
+
+// toolBudgetExhausted 工具调用次数或输出 Token 是否已用完
+func (r *reviewRun) toolBudgetExhausted() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.toolCalls >= r.maxToolCalls() || r.toolTokens >= r.maxToolTokens()
+}
+
+func (r *reviewRun) maxToolCalls() int {
+	if r.config.AgentMaxToolCalls > 0 {
+		return r.config.AgentMaxToolCalls
+	}
+	return defaultAgentMaxToolCalls
+}
+
+func (r *reviewRun) maxToolTokens() int {
+	if r.config.AgentMaxToolTokens > 0 {
+		return r.config.AgentMaxToolTokens
+	}
+	return defaultAgentMaxToolTokens
+}
+
+// fork 为对比模式创建使用其他模型的运行上下文，用量单独累计
+func (r *reviewRun) fork(llmSvc *LLMService) *reviewRun {
+	return &reviewRun{
+		event:     r.event,
+		review:    r.review,
+		config:    r.config,
+		llmSvc:    llmSvc,
+		githubSvc: r.githubSvc,
+		opts:      r.opts,
+		secondary: true,
+		redactor:  r.redactor,
+		builder:   r.builder,
+		rules:     r.rules,
+		secrets:   r.secrets,
+	}
+}
+
+// redact 对发送给模型的文本脱敏，检测到的密钥在未启用脱敏时同样遮盖
+func (r *reviewRun) redact(text string) string {
+	text = secrets.MaskText(text, r.secrets, secretPlaceholder)
+	if r.redactor == nil {
+		return text
+	}
+	return r.redactor.Text(text)
+}
+
+// publishStage 发布阶段事件，对比模式的附加模型和演练模式不发布
+func (s *AnalyzerService) publishStage(run *reviewRun, stage model.ReviewStage, message string) {
+	if run.secondary || run.dryRun {
+		return
+	}
+	s.progress.PublishStage(run.review.ID, stage, message)
+}
+
+// applyUsage 将累计用量和费用写入审查记录
+func (r *reviewRun) applyUsage() (model.Usage, float64) {
+	usage, cost := r.totalUsage()
+	r.review.TokenUsed = usage.TotalTokens
+	r.review.PromptTokens = usage.PromptTokens
+	r.review.CompletionTokens = usage.CompletionTokens
+	r.review.Cost = cost
+	return usage, cost
+}
+
+// servedFromCache 本次审查的所有 LLM 调用是否都来自缓存
+func (r *reviewRun) servedFromCache() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.llmCalls > 0 && r.cacheHits == r.llmCalls
 }
 
 func (s *AnalyzerService) AnalyzePR(ctx context.Context, event *model.PullRequestEvent) error {
+	run, err := s.prepareReview(ctx, event, AnalyzeOptions{})
+	if err != nil {
+		return err
+	}
+	if run == nil {
+		// 未启用自动审查时仍执行密钥扫描，停用的仓库不扫描
+		repo, err := s.store.GetRepoByFullName(ctx, event.Repository.FullName)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if repo != nil && !repo.Enabled {
+			return nil
+		}
+		return s.secretGate(ctx, event, s.loadRepoConfig(ctx, event.Repository.FullName))
+	}
+	return s.executeReview(ctx, run)
+}
+
+// ErrRepoDisabled 仓库已停用，不能手动重新审查
+var ErrRepoDisabled = errors.New("repo is disabled")
+
+// RerunReview 手动重新审查指定记录对应的 PR
+// 审查在后台执行，立即返回新建的审查记录，可通过 SSE 订阅进度
+func (s *AnalyzerService) RerunReview(ctx context.Context, reviewID uint, opts AnalyzeOptions) (*model.Review, error) {
+	prev, err := s.store.GetReview(ctx, reviewID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 停用的仓库不加载配置，手动触发会以空配置（无忽略文件、严重程度和提示词设置）审查
+	repo, err := s.store.GetRepoByFullName(ctx, prev.RepoFullName)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if repo != nil && !repo.Enabled {
+		return nil, fmt.Errorf("%w: %s", ErrRepoDisabled, prev.RepoFullName)
+	}
+
+	event := &model.PullRequestEvent{
+		Action: "rerun",
+		Number: prev.PRNumber,
+		PullRequest: model.PullRequest{
+			Number: prev.PRNumber,
+			Title:  prev.PRTitle,
+			User:   model.User{Login: prev.PRAuthor},
+			Head:   model.Ref{SHA: prev.CommitSHA},
+		},
+		Repository: model.Repository{FullName: prev.RepoFullName},
+	}
+
+	opts.Manual = true
+	run, err := s.prepareReview(ctx, event, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// PR 可能已有新提交，尽量使用最新的标题和 head SHA
+	if pr, err := run.githubSvc.GetPullRequest(ctx, prev.RepoFullName, prev.PRNumber); err == nil {
+		run.event.PullRequest = *pr
+		run.review.PRTitle = pr.Title
+		run.review.CommitSHA = pr.Head.SHA
+		run.review.BaseSHA = pr.Base.SHA
+		s.store.UpdateReview(ctx, run.review)
+	}
+
+	go func() {
+		if err := s.executeReview(context.Background(), run); err != nil {
+			s.logger.Error("Failed to rerun review",
+				zap.Uint("review_id", reviewID),
+				zap.Error(err),
+			)
+		}
+	}()
+
+	return run.review, nil
+}
+
+// prepareReview 加载配置并创建审查记录，未启用自动审查时返回 nil
+func (s *AnalyzerService) prepareReview(ctx context.Context, event *model.PullRequestEvent, opts AnalyzeOptions) (*reviewRun, error) {
 	repoFullName := event.Repository.FullName
 	prNumber := event.Number
 
@@ -106,21 +398,18 @@ func (s *AnalyzerService) AnalyzePR(ctx context.Context, event *model.PullReques
 		zap.String("repo", repoFullName),
 		zap.Int("pr_number", prNumber),
 		zap.String("action", event.Action),
+		zap.Bool("bypass_cache", opts.BypassCache),
 	)
 
 	// 1. 加载仓库配置
 	config := s.loadRepoConfig(ctx, repoFullName)
 
-	// 2. 检查是否启用自动审查
-	if !config.AutoReview {
+	// 2. 检查是否启用自动审查（手动触发时忽略）
+	if !config.AutoReview && !opts.Manual {
 		s.logger.Info("Auto review disabled for repo", zap.String("repo", repoFullName))
-		return nil
+		return nil, nil
 	}
 
-	// 获取仓库级的 LLM 和 GitHub 服务（如果有自定义配置）
-	llmSvc := s.getLLMService(config)
-	githubSvc := s.getGitHubService(config)
-
 	// 3. 创建审查记录
 	review := &model.Review{
 		RepoFullName: repoFullName,
@@ -128,20 +417,43 @@ func (s *AnalyzerService) AnalyzePR(ctx context.Context, event *model.PullReques
 		PRTitle:      event.PullRequest.Title,
 		PRAuthor:     event.PullRequest.User.Login,
 		CommitSHA:    event.PullRequest.Head.SHA,
+		BaseSHA:      event.PullRequest.Base.SHA,
 		Status:       model.ReviewStatusPending,
 	}
 
 	if err := s.store.CreateReview(ctx, review); err != nil {
 		s.logger.Error("Failed to create review record", zap.Error(err))
-		return err
+		return nil, err
 	}
 
+	// 获取仓库级的 LLM 和 GitHub 服务（如果有自定义配置）
+	return &reviewRun{
+		event:     event,
+		review:    review,
+		config:    config,
+		llmSvc:    s.getLLMService(config),
+		githubSvc: s.getGitHubService(config),
+		opts:      opts,
+		builder:   s.builder,
+	}, nil
+}
+
+// executeReview 执行审查流水线：获取 Diff → 过滤 → 构建提示词 → 调用 LLM → 发布评论
+func (s *AnalyzerService) executeReview(ctx context.Context, run *reviewRun) error {
+	review := run.review
+	config := run.config
+	llmSvc := run.llmSvc
+	githubSvc := run.githubSvc
+	repoFullName := review.RepoFullName
+	prNumber := review.PRNumber
+
 	review.Status = model.ReviewStatusRunning
 	s.store.UpdateReview(ctx, review)
 
 	startTime := time.Now()
 
 	// 4. 获取 PR Diff
+	s.publishStage(run, model.ReviewStageFetchingDiff, "")
 	diffContent, err := githubSvc.GetPRDiff(ctx, repoFullName, prNumber)
 	if err != nil {
 		s.updateReviewFailed(ctx, review, err)
@@ -154,7 +466,15 @@ func (s *AnalyzerService) AnalyzePR(ctx context.Context, event *model.PullReques
 		return err
 	}
 
+	// 密钥扫描覆盖所有文件，结果直接设置提交状态，密钥不会发送给模型
+	secretIssues := s.scanSecrets(ctx, run, changes)
+	s.setSecretStatus(ctx, run, secretIssues)
+
+	// 依赖清单和锁文件默认被忽略，在过滤前分析版本变更
+	dependencies, depIssues := s.analyzeDependencies(ctx, run, changes)
+
 	// 5. 应用过滤规则
+	s.publishStage(run, model.ReviewStageFiltering, fmt.Sprintf("%d files in diff", len(changes)))
 	changes = s.applyFilters(changes, config)
 
 	if len(changes) == 0 {
@@ -162,79 +482,125 @@ func (s *AnalyzerService) AnalyzePR(ctx context.Context, event *model.PullReques
 			zap.String("repo", repoFullName),
 			zap.Int("pr_number", prNumber),
 		)
-		review.Status = model.ReviewStatusSkipped
-		review.Result = "No reviewable changes after filtering"
-		s.store.UpdateReview(ctx, review)
-		return nil
+		if len(secretIssues) == 0 && len(dependencies) == 0 {
+			review.Status = model.ReviewStatusSkipped
+			review.Result = "No reviewable changes after filtering"
+			s.store.UpdateReview(ctx, review)
+			s.progress.Finish(review.ID, review.Status, review.Result)
+			return nil
+		}
+		// 没有需要模型审查的代码时，密钥扫描和依赖分析的结果仍作为审查结果存储和发布
+		reviewResult := &model.ReviewResult{
+			Summary:      "过滤后没有需要模型审查的代码变更，以下为密钥扫描和依赖分析结果",
+			Issues:       mergePatternIssues(nil, concatIssues(secretIssues, depIssues)),
+			Dependencies: dependencies,
+		}
+		return s.publishResult(ctx, run, reviewResult, nil, model.Usage{}, 0, startTime, 0)
 	}
 
-	// 6. 检查 Diff 行数限制
+	// 外部检查工具在后台检出 head 提交执行，与模型审查并行
+	waitLinters := s.startLinters(ctx, run, changes)
+
+	// 模式规则直接匹配新增行，不依赖模型，在脱敏前执行
+	ruleIssues := s.scanPatterns(ctx, run, changes)
+
+	// 6. 脱敏：密钥和个人信息替换为占位符后再发送给模型
+	changes = s.redactChanges(run, maskSecrets(run, changes))
+
+	// 7. 构建系统提示词，附加变更涉及语言的专项检查；用户提示词附带 PR 说明、提交信息和关联 Issue
+	run.builder = s.builder.WithPRContext(s.buildPRContext(ctx, run))
 	totalLines := s.countDiffLines(changes)
-	if config.MaxDiffLines > 0 && totalLines > config.MaxDiffLines {
-		s.logger.Info("Diff too large, skipping",
+	templates := s.templates.WithOverrides(config.LanguagePrompts)
+	s.publishStage(run, model.ReviewStagePrompting, fmt.Sprintf("%d files, %d lines, language checks %v", len(changes), totalLines, templates.Languages(changes)))
+	systemPrompt, guidelineRefs := s.buildSystemPrompt(ctx, run, changes, templates)
+
+	// 8. 调用 LLM（对比模式下附加模型并行审查，结果只存储不发布）
+	s.startComparisons(ctx, run, systemPrompt, changes, totalLines)
+	llmStart := time.Now()
+	reviewResult, err := s.runReview(ctx, run, systemPrompt, changes, totalLines)
+	if len(config.CompareModels) > 0 {
+		s.saveComparison(ctx, run, reviewResult, err, time.Since(llmStart), true)
+	}
+	review.Provider = llmSvc.GetProvider()
+	review.Model = llmSvc.GetModel()
+	run.applyUsage()
+	linterRuns, linterIssues := waitLinters()
+	ruleIssues = concatIssues(secretIssues, depIssues, ruleIssues, linterIssues)
+	if err != nil {
+		if len(ruleIssues) == 0 {
+			s.updateReviewFailed(ctx, review, err)
+			return err
+		}
+		// 模型调用失败时仍发布密钥扫描、依赖分析、模式规则和外部检查工具发现的问题
+		s.logger.Warn("LLM review failed, publishing non-LLM findings only",
 			zap.String("repo", repoFullName),
 			zap.Int("pr_number", prNumber),
-			zap.Int("total_lines", totalLines),
-			zap.Int("max_lines", config.MaxDiffLines),
+			zap.Int("rule_issues", len(ruleIssues)),
+			zap.Error(err),
 		)
-		review.Status = model.ReviewStatusSkipped
-		review.Result = fmt.Sprintf("Diff too large: %d lines (max %d)", totalLines, config.MaxDiffLines)
-		s.store.UpdateReview(ctx, review)
-		return nil
+		reviewResult = patternOnlyResult(err)
 	}
 
-	// 7. 构建提示词
-	systemPrompt := config.SystemPrompt
-	if systemPrompt == "" {
-		systemPrompt = defaultSystemPrompt
-	}
-	userPrompt, err := s.builder.BuildUserPrompt(changes)
-	if err != nil {
-		s.updateReviewFailed(ctx, review, err)
-		return err
+	// 9. 复核高严重程度问题（可选），模型以外的来源发现的问题不需要复核
+	s.verifyIssues(ctx, run, reviewResult, changes)
+	reviewResult.Issues = mergePatternIssues(reviewResult.Issues, ruleIssues)
+	reviewResult.Dependencies = dependencies
+	reviewResult.Linters = linterRuns
+	usage, cost := run.applyUsage()
+	if run.redactor != nil {
+		reviewResult.Redactions = run.redactor.Counts()
+		review.Redactions = run.redactor.Total()
 	}
 
-	// 8. 调用 LLM
-	result, tokenUsed, err := llmSvc.Chat(ctx, systemPrompt, userPrompt)
-	if err != nil {
-		s.updateReviewFailed(ctx, review, err)
-		return err
-	}
+	reviewResult.Model = llmSvc.GetModel()
+	return s.publishResult(ctx, run, reviewResult, guidelineRefs, usage, cost, startTime, len(changes))
+}
 
-	duration := time.Since(startTime)
+// publishResult 过滤审查结果，发布 PR 评论并更新审查记录和仓库统计
+func (s *AnalyzerService) publishResult(ctx context.Context, run *reviewRun, reviewResult *model.ReviewResult, guidelineRefs []model.GuidelineRef, usage model.Usage, cost float64, startTime time.Time, fileCount int) error {
+	review := run.review
+	repoFullName := review.RepoFullName
+	prNumber := review.PRNumber
 
-	// 9. 解析 JSON 响应
-	reviewResult := s.parseReviewResult(result)
-	reviewResult.Model = llmSvc.GetModel()
+	duration := time.Since(startTime)
 	reviewResult.Duration = duration.Milliseconds()
 
-	// 10. 按最小严重程度过滤
-	reviewResult.Issues = s.filterBySeverity(reviewResult.Issues, config.MinSeverity)
+	// 10. 按审查重点和最小严重程度过滤
+	s.finishResult(run, reviewResult, guidelineRefs)
+	review.IssueCount = len(reviewResult.Issues)
 
 	// 11. 格式化评论
-	comment := s.formatCommentFromResult(reviewResult, tokenUsed, duration, len(changes))
-	if err := githubSvc.CreatePRComment(ctx, repoFullName, prNumber, comment); err != nil {
+	s.publishStage(run, model.ReviewStagePosting, "")
+	review.CacheHit = run.servedFromCache()
+	comment := s.formatCommentFromResult(reviewResult, usage, cost, duration, fileCount, review.CacheHit)
+	if err := run.githubSvc.CreatePRComment(ctx, repoFullName, prNumber, comment); err != nil {
 		s.updateReviewFailed(ctx, review, err)
 		return err
 	}
 
 	// 12. 更新审查记录
 	review.Status = model.ReviewStatusCompleted
-	review.TokenUsed = tokenUsed
+	if reviewResult.Degraded {
+		review.Status = model.ReviewStatusDegraded
+	}
 	review.DurationMs = duration.Milliseconds()
 
 	resultJSON, _ := json.Marshal(reviewResult)
 	review.Result = string(resultJSON)
 
 	s.store.UpdateReview(ctx, review)
+	s.progress.Finish(review.ID, review.Status, "")
 
-	// 13. 更新仓库统计
+	// 13. 更新仓库统计和规则命中
+	s.recordRuleHits(ctx, review, reviewResult.Issues)
 	s.updateRepoStats(ctx, repoFullName)
 
 	s.logger.Info("PR analysis completed",
 		zap.String("repo", repoFullName),
 		zap.Int("pr_number", prNumber),
-		zap.Int("token_used", tokenUsed),
+		zap.Int("token_used", usage.TotalTokens),
+		zap.Float64("cost", cost),
+		zap.Bool("cache_hit", review.CacheHit),
 		zap.Duration("duration", duration),
 		zap.Int("issues_count", len(reviewResult.Issues)),
 	)
@@ -242,6 +608,269 @@ func (s *AnalyzerService) AnalyzePR(ctx context.Context, event *model.PullReques
 	return nil
 }
 
+// buildSystemPrompt 构建系统提示词：基础模板按审查重点填充，附加语言专项检查、仓库规范、审查规则和脱敏说明
+func (s *AnalyzerService) buildSystemPrompt(ctx context.Context, run *reviewRun, changes []diff.FileChange, templates *prompt.TemplateRegistry) (string, []model.GuidelineRef) {
+	focus := prompt.NormalizeFocus(run.config.ReviewFocus)
+	run.review.ReviewFocus = strings.Join(focus, ",")
+
+	systemPrompt := s.builder.BuildSystemPrompt(s.basePrompt(ctx, run), focus)
+	systemPrompt += templates.Compose(changes)
+	guidelines, guidelineRefs := s.guidelineSection(ctx, run)
+	systemPrompt += guidelines
+	systemPrompt += s.ruleSection(ctx, run, changes)
+	// 在 PR 上下文构建之后判断，标题、描述、提交信息和 Issue 中的脱敏同样需要说明
+	if (run.redactor != nil && run.redactor.Total() > 0) || len(run.secrets) > 0 {
+		systemPrompt += redactionPromptNote
+	}
+	return systemPrompt, guidelineRefs
+}
+
+// finishResult 记录审查使用的重点、规范和规则，按审查重点和最小严重程度过滤问题并重新统计
+func (s *AnalyzerService) finishResult(run *reviewRun, result *model.ReviewResult, guidelineRefs []model.GuidelineRef) {
+	result.Focus = prompt.NormalizeFocus(run.config.ReviewFocus)
+	result.Guidelines = guidelineRefs
+	result.Rules = ruleIDs(run.rules)
+	result.Issues, result.FocusFiltered = filterByFocus(result.Issues, result.Focus)
+	result.Issues = s.filterBySeverity(result.Issues, run.config.MinSeverity)
+	result.Stats = computeStats(result.Issues)
+}
+
+// LanguageTemplates 返回语言专项检查模板，overrides 为仓库的覆盖配置
+func (s *AnalyzerService) LanguageTemplates(overrides map[string]string) []prompt.LanguageTemplate {
+	return s.templates.WithOverrides(overrides).Templates()
+}
+
+// redactChanges 创建本次审查的脱敏器并对变更脱敏，复核和工具调用读取的文件共用同一脱敏器
+func (s *AnalyzerService) redactChanges(run *reviewRun, changes []diff.FileChange) []diff.FileChange {
+	if run.config.DisableRedaction {
+		return changes
+	}
+
+	patterns := make([]redact.Pattern, 0, len(run.config.RedactPatterns))
+	for _, p := range run.config.RedactPatterns {
+		patterns = append(patterns, redact.Pattern{Name: p.Name, Pattern: p.Pattern})
+	}
+	redactor, err := redact.New(patterns)
+	if err != nil {
+		s.logger.Warn("Invalid custom redact patterns skipped",
+			zap.String("repo", run.review.RepoFullName),
+			zap.Error(err),
+		)
+	}
+
+	run.redactor = redactor
+	changes = redactor.Changes(changes)
+	run.review.Redactions = redactor.Total()
+	if run.review.Redactions > 0 {
+		s.logger.Info("Sensitive content redacted from diff",
+			zap.Uint("review_id", run.review.ID),
+			zap.String("redactions", redactor.Summary()),
+		)
+	}
+	return changes
+}
+
+// runReview 调用 LLM 审查变更，超出 Diff 行数限制或模型上下文预算时分批审查后合并
+func (s *AnalyzerService) runReview(ctx context.Context, run *reviewRun, systemPrompt string, changes []diff.FileChange, totalLines int) (*model.ReviewResult, error) {
+	config := run.config
+	budget := s.promptBudget(run.llmSvc)
+	var result *model.ReviewResult
+	var err error
+	if (config.MaxDiffLines > 0 && totalLines > config.MaxDiffLines) || !run.builder.Fits(systemPrompt, changes, budget) {
+		result, err = s.reviewInBatches(ctx, run, systemPrompt, changes, budget)
+	} else {
+		result, err = s.reviewSingle(ctx, run, systemPrompt, changes, budget)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if window, assumed := budget.Window(); assumed {
+		s.logger.Warn("Unknown context window for model, assuming a conservative default",
+			zap.String("model", budget.Model),
+			zap.Int("context_window", window),
+		)
+		result.AssumedContextWindow = window
+	}
+	applyRules(result.Issues, run.rules)
+	return result, nil
+}
+
+// reviewSingle 单次调用 LLM 审查全部变更
+func (s *AnalyzerService) reviewSingle(ctx context.Context, run *reviewRun, systemPrompt string, changes []diff.FileChange, budget prompt.Budget) (*model.ReviewResult, error) {
+	built, err := run.builder.BuildUserPromptWithBudget(systemPrompt, changes, budget)
+	if err != nil {
+		return nil, err
+	}
+
+	s.publishStage(run, model.ReviewStageGenerating, fmt.Sprintf("%s, ~%d prompt tokens", run.llmSvc.GetModel(), built.Tokens))
+	reviewResult, err := s.generateReview(ctx, run, systemPrompt, built.UserPrompt, true)
+	if err != nil {
+		return nil, err
+	}
+
+	reviewResult.Issues, reviewResult.Guard = guardIssues(reviewResult.Issues, built.Changes)
+	reviewResult.Omitted = toOmittedChanges(built.Omitted)
+	return reviewResult, nil
+}
+
+// promptBudget 根据模型上下文窗口和回答预留计算提示词预算
+func (s *AnalyzerService) promptBudget(llmSvc *LLMService) prompt.Budget {
+	return prompt.Budget{
+		Model:           llmSvc.GetModel(),
+		ContextWindow:   llmSvc.GetContextWindow(),
+		MaxOutputTokens: llmSvc.GetMaxTokens(),
+	}
+}
+
+// omissionReason 未审查原因的中文说明
+func omissionReason(reason string) string {
+	switch reason {
+	case "token budget exceeded":
+		return "超出模型上下文预算"
+	case "batch review failed":
+		return "所在批次审查失败"
+	case "max batches exceeded":
+		return "超出批次上限"
+	}
+	return reason
+}
+
+// toOmittedChanges 转换提示词构建时省略的变更
+func toOmittedChanges(omitted []prompt.Omission) []model.OmittedChange {
+	var changes []model.OmittedChange
+	for _, o := range omitted {
+		changes = append(changes, model.OmittedChange{
+			File:   o.File,
+			Hunk:   o.Hunk,
+			Lines:  o.Lines,
+			Reason: o.Reason,
+		})
+	}
+	return changes
+}
+
+// generateReview 调用 LLM 生成审查结果：启用工具调用时进入多轮工具对话，启用多次采样时按投票合并
+func (s *AnalyzerService) generateReview(ctx context.Context, run *reviewRun, systemPrompt, userPrompt string, stream bool) (*model.ReviewResult, error) {
+	if run.config.AgentEnabled {
+		if sampleCount(run.config) > 1 {
+			// 保存配置时会拒绝这种组合，这里处理之前保存的配置
+			s.logger.Warn("Self-consistency voting is not supported with agent review, sampling skipped",
+				zap.String("repo", run.review.RepoFullName),
+				zap.Int("sample_count", run.config.SampleCount),
+			)
+		}
+		return s.generateWithTools(ctx, run, systemPrompt, userPrompt)
+	}
+	if sampleCount(run.config) > 1 {
+		return s.sampleReview(ctx, run, systemPrompt, userPrompt)
+	}
+	return s.generate(ctx, run, run.llmSvc, systemPrompt, userPrompt, 0, stream)
+}
+
+// generate 调用 LLM 生成并校验审查结果，sample 为采样序号（0 表示不采样），用于区分缓存
+// 校验失败时把错误反馈给模型修复（最多 maxRepairAttempts 次），仍失败则返回降级结果
+// 只有通过校验的结果才写入响应缓存
+func (s *AnalyzerService) generate(ctx context.Context, run *reviewRun, llmSvc *LLMService, systemPrompt, userPrompt string, sample int, stream bool) (*model.ReviewResult, error) {
+	cachePrompt := userPrompt
+	if sample > 0 {
+		cachePrompt = fmt.Sprintf("%s\n#sample %d", userPrompt, sample)
+	}
+	key := CacheKey(systemPrompt, cachePrompt, llmSvc.GetModel(), llmSvc.GetTemperature())
+
+	if !run.opts.BypassCache {
+		if content, usage, ok := s.cache.Get(ctx, key); ok {
+			if result, problems := parseReviewOutput(content); len(problems) == 0 {
+				run.recordLLMCall(usage, 0, true)
+				s.publishStage(run, model.ReviewStageGenerating, "served from cache")
+				return result, nil
+			}
+		}
+	}
+
+	messages := newMessages(systemPrompt, userPrompt)
+	output, usage, err := s.chat(ctx, run, llmSvc, messages, reviewResponseFormat, stream)
+	if err != nil {
+		return nil, err
+	}
+
+	result, repairUsage, ok := s.validateOrRepair(ctx, run, llmSvc, messages, output)
+	if !ok {
+		return result, nil
+	}
+
+	usage.PromptTokens += repairUsage.PromptTokens
+	usage.CompletionTokens += repairUsage.CompletionTokens
+	usage.TotalTokens += repairUsage.TotalTokens
+	if canonical, err := json.Marshal(result); err == nil {
+		s.cache.Put(ctx, key, llmSvc.GetModel(), string(canonical), usage)
+	}
+	return result, nil
+}
+
+// validateOrRepair 校验模型输出，失败时在原对话后追加校验错误请求修复
+// 返回校验通过的结果或降级结果、修复调用的用量，以及是否通过校验
+func (s *AnalyzerService) validateOrRepair(ctx context.Context, run *reviewRun, llmSvc *LLMService, messages []model.Message, output string) (*model.ReviewResult, model.Usage, bool) {
+	var total model.Usage
+	result, problems := parseReviewOutput(output)
+	for attempt := 1; len(problems) > 0 && attempt <= maxRepairAttempts; attempt++ {
+		s.logger.Warn("LLM output failed validation, requesting repair",
+			zap.Uint("review_id", run.review.ID),
+			zap.Int("attempt", attempt),
+			zap.Strings("problems", problems),
+		)
+		s.publishStage(run, model.ReviewStageGenerating, fmt.Sprintf("repairing invalid output (attempt %d)", attempt))
+
+		messages = append(messages,
+			model.Message{Role: "assistant", Content: output},
+			model.Message{Role: "user", Content: repairPrompt(problems)},
+		)
+		repaired, usage, err := s.chat(ctx, run, llmSvc, messages, reviewResponseFormat, false)
+		if err != nil {
+			s.logger.Warn("LLM repair request failed", zap.Uint("review_id", run.review.ID), zap.Error(err))
+			break
+		}
+		total.PromptTokens += usage.PromptTokens
+		total.CompletionTokens += usage.CompletionTokens
+		total.TotalTokens += usage.TotalTokens
+
+		output = repaired
+		result, problems = parseReviewOutput(output)
+	}
+
+	if len(problems) > 0 {
+		s.logger.Warn("LLM output still invalid after repair, review degraded",
+			zap.Uint("review_id", run.review.ID),
+			zap.Strings("problems", problems),
+			zap.String("output", output),
+		)
+		return degradedResult(output, problems), total, false
+	}
+
+	return result, total, true
+}
+
+// chat 调用 LLM 并按 format 请求结构化输出；stream 为 true 且启用流式输出时将增量内容推送到进度订阅者
+// 用量和费用累计到 run 中
+func (s *AnalyzerService) chat(ctx context.Context, run *reviewRun, llmSvc *LLMService, messages []model.Message, format *model.ResponseFormat, stream bool) (string, model.Usage, error) {
+	var content string
+	var usage model.Usage
+	var err error
+	if stream && llmSvc.StreamEnabled() && !run.secondary && !run.dryRun {
+		content, usage, err = llmSvc.ChatMessagesStream(ctx, messages, format, func(delta string) {
+			s.progress.PublishToken(run.review.ID, delta)
+		})
+	} else {
+		content, usage, err = llmSvc.ChatMessages(ctx, messages, format)
+	}
+	if err != nil {
+		return "", model.Usage{}, err
+	}
+
+	run.recordLLMCall(usage, s.pricing.Cost(llmSvc.GetProvider(), llmSvc.GetModel(), usage), false)
+	run.recordExchange(llmSvc.GetModel(), messages, content, usage)
+	return content, usage, nil
+}
+
 // getLLMService 获取 LLM 服务（优先使用仓库级配置）
 func (s *AnalyzerService) getLLMService(config *model.ReviewConfig) *LLMService {
 	// 如果仓库有自定义 LLM 配置，创建新的 LLM 客户端
@@ -260,6 +889,9 @@ func (s *AnalyzerService) getLLMService(config *model.ReviewConfig) *LLMService
 		if config.MaxTokens > 0 {
 			cfg.MaxTokens = config.MaxTokens
 		}
+		if config.ContextWindow > 0 {
+			cfg.ContextWindow = config.ContextWindow
+		}
 
 		s.logger.Info("Using repo-level LLM config",
 			zap.String("provider", cfg.Provider),
@@ -327,6 +959,10 @@ func (s *AnalyzerService) getDefaultConfig() *model.ReviewConfig {
 		Languages:    []string{"go", "python", "javascript"},
 		MaxDiffLines: 10000,
 		AutoReview:   true,
+
+		IncludeDescription:  true,
+		IncludeCommits:      true,
+		IncludeLinkedIssues: true,
 	}
 }
 
@@ -335,6 +971,11 @@ func (s *AnalyzerService) applyFilters(changes []diff.FileChange, config *model.
 	var filtered []diff.FileChange
 
 	for _, change := range changes {
+		// 删除的文件、二进制文件和仅重命名的文件没有可审查的新增代码
+		if change.Binary || change.Status == diff.StatusDeleted || len(change.Hunks) == 0 {
+			continue
+		}
+
 		// 检查文件是否被忽略
 		if s.shouldIgnoreFile(change.Filename, config.IgnoreFiles) {
 			continue
@@ -380,33 +1021,6 @@ func (s *AnalyzerService) countDiffLines(changes []diff.FileChange) int {
 	return total
 }
 
-// parseReviewResult 解析 JSON 响应
-func (s *AnalyzerService) parseReviewResult(result string) *model.ReviewResult {
-	// 清理可能的 markdown 包裹
-	cleaned := result
-	if len(cleaned) > 7 && cleaned[:7] == "```json" {
-		cleaned = cleaned[7:]
-	}
-	if len(cleaned) > 3 && cleaned[:3] == "```" {
-		cleaned = cleaned[3:]
-	}
-	if len(cleaned) > 3 && cleaned[len(cleaned)-3:] == "```" {
-		cleaned = cleaned[:len(cleaned)-3]
-	}
-
-	var reviewResult model.ReviewResult
-	if err := json.Unmarshal([]byte(cleaned), &reviewResult); err != nil {
-		// JSON 解析失败，降级为纯文本
-		s.logger.Debug("Failed to parse JSON result, using raw text", zap.Error(err))
-		return &model.ReviewResult{
-			Summary: result,
-			Issues:  []model.ReviewIssue{},
-		}
-	}
-
-	return &reviewResult
-}
-
 // filterBySeverity 按最小严重程度过滤
 func (s *AnalyzerService) filterBySeverity(issues []model.ReviewIssue, minSeverity string) []model.ReviewIssue {
 	if minSeverity == "" || minSeverity == "P2" {
@@ -429,6 +1043,19 @@ func (s *AnalyzerService) filterBySeverity(issues []model.ReviewIssue, minSeveri
 	return filtered
 }
 
+// filterByFocus 丢弃类别不在审查重点内的问题，返回保留的问题和丢弃数量
+// 引用仓库审查规则的问题由维护者显式要求，密钥扫描、依赖分析和外部检查工具的问题始终报告，均不受审查重点限制
+func filterByFocus(issues []model.ReviewIssue, focus []string) ([]model.ReviewIssue, int) {
+	filtered := make([]model.ReviewIssue, 0, len(issues))
+	for _, issue := range issues {
+		if issue.RuleID != "" || issue.Source == model.IssueSourceSecret || issue.Source == model.IssueSourceDependency ||
+			issue.Source == model.IssueSourceLinter || containsString(focus, issue.Category) {
+			filtered = append(filtered, issue)
+		}
+	}
+	return filtered, len(issues) - len(filtered)
+}
+
 // updateRepoStats 更新仓库统计
 func (s *AnalyzerService) updateRepoStats(ctx context.Context, repoFullName string) {
 	repo, err := s.store.GetRepoByFullName(ctx, repoFullName)
@@ -444,9 +1071,15 @@ func (s *AnalyzerService) updateRepoStats(ctx context.Context, repoFullName stri
 }
 
 // formatCommentFromResult 从结构化结果格式化评论
-func (s *AnalyzerService) formatCommentFromResult(result *model.ReviewResult, tokenUsed int, duration time.Duration, fileCount int) string {
+func (s *AnalyzerService) formatCommentFromResult(result *model.ReviewResult, usage model.Usage, cost float64, duration time.Duration, fileCount int, cacheHit bool) string {
 	var issuesText string
-	if len(result.Issues) == 0 {
+	if result.LLMError != "" && len(result.Issues) == 0 {
+		issuesText = "**⚠️ 模型调用失败，未能完成审查。**"
+	} else if result.Degraded && len(result.Issues) == 0 && len(result.ValidationErrors) > 0 {
+		issuesText = "**⚠️ 模型输出未通过格式校验（修复后仍不合法），未能提取到结构化问题：**\n\n" + result.Summary
+	} else if result.Degraded && len(result.Issues) == 0 {
+		issuesText = "**⚠️ 审查结果不完整：**\n\n" + result.Summary
+	} else if len(result.Issues) == 0 {
 		issuesText = "✅ " + result.Summary
 	} else {
 		issuesText = fmt.Sprintf("**总结**：%s\n\n", result.Summary)
@@ -459,17 +1092,81 @@ func (s *AnalyzerService) formatCommentFromResult(result *model.ReviewResult, to
 			}
 			issuesText += fmt.Sprintf("### %s [%s] %s\n", icon, issue.Severity, issue.Title)
 			issuesText += fmt.Sprintf("**文件**：`%s:%d`\n", issue.File, issue.Line)
+			if result.Samples > 1 {
+				issuesText += fmt.Sprintf("**置信度**：%d/%d 次采样报告\n", issue.Votes, result.Samples)
+			}
 			issuesText += fmt.Sprintf("**问题**：%s\n", issue.Description)
+			if issue.Guideline != "" {
+				issuesText += fmt.Sprintf("**依据规范**：%s\n", issue.Guideline)
+			}
+			if issue.Source == model.IssueSourceLinter {
+				issuesText += fmt.Sprintf("**工具**：%s\n", issue.Tool)
+			}
+			if issue.Source == model.IssueSourceRule {
+				issuesText += fmt.Sprintf("**规则**：%s（模式匹配）\n", issue.RuleID)
+			} else if issue.RuleID != "" {
+				issuesText += fmt.Sprintf("**规则**：%s\n", issue.RuleID)
+			}
 			issuesText += fmt.Sprintf("**建议**：%s\n\n", issue.Suggestion)
 		}
 	}
 
+	issuesText += formatDependencySection(result.Dependencies)
+
+	if len(result.Focus) > 0 && len(result.Focus) < len(prompt.FocusAreas()) {
+		issuesText += fmt.Sprintf("\n\n> 🎯 审查重点：%s", strings.Join(result.Focus, "、"))
+		if result.FocusFiltered > 0 {
+			issuesText += fmt.Sprintf("（%d 个其他类别的问题已过滤）", result.FocusFiltered)
+		}
+		issuesText += "\n"
+	}
+
+	issuesText += formatLinterRuns(result.Linters)
+
+	if total := sumCounts(result.Redactions); total > 0 {
+		issuesText += fmt.Sprintf("\n\n> 🔒 脱敏：发送给模型前替换了 %d 处敏感信息，问题描述中以 `[REDACTED:类型#序号]` 指代\n", total)
+	}
+
+	if g := result.Guard; g != nil && (g.Dropped > 0 || g.Demoted > 0) {
+		issuesText += fmt.Sprintf("\n\n> 🛡️ 定位校验：%d 个问题不在本次变更范围内已丢弃，%d 个无法定位到变更行已降级\n", g.Dropped, g.Demoted)
+	}
+
+	if refuted := countVerdicts(result.Verifications, model.VerdictRefuted); refuted > 0 {
+		issuesText += fmt.Sprintf("\n\n> 🔍 二次复核：%d 个高严重程度问题中有 %d 个被否定，已移除或降为 P2\n", len(result.Verifications), refuted)
+	}
+
+	if result.LLMError != "" {
+		issuesText += "\n\n**⚠️ 模型调用失败，以上仅为模型以外的检查结果。**\n"
+	} else if result.Degraded && len(result.Issues) > 0 && len(result.ValidationErrors) > 0 {
+		issuesText += "\n\n**⚠️ 部分批次的模型输出未通过格式校验，审查结果不完整。**\n"
+	} else if result.Degraded && len(result.Issues) > 0 {
+		issuesText += "\n\n**⚠️ 部分变更未参与审查，审查结果不完整。**\n"
+	}
+
+	if len(result.Omitted) > 0 {
+		issuesText += "\n\n**⚠️ 以下变更未参与审查：**\n"
+		for _, o := range result.Omitted {
+			if o.Hunk == "" {
+				issuesText += fmt.Sprintf("- `%s`（整个文件，%d 行，%s）\n", o.File, o.Lines, omissionReason(o.Reason))
+			} else {
+				issuesText += fmt.Sprintf("- `%s` %s（%d 行，%s）\n", o.File, o.Hunk, o.Lines, omissionReason(o.Reason))
+			}
+		}
+	}
+
+	tokenText := fmt.Sprintf("%d（输入 %d / 输出 %d）", usage.TotalTokens, usage.PromptTokens, usage.CompletionTokens)
+	if cacheHit {
+		tokenText += "（命中缓存，未重复调用模型）"
+	} else if cost > 0 {
+		tokenText += fmt.Sprintf("，约 %s", s.pricing.Format(cost))
+	}
+
 	return fmt.Sprintf(`## 🤖 Code-Sentinel 代码审查报告
 
 **审查时间**：%s
 **审查模型**：%s
 **变更文件**：%d 个文件
-**Token 消耗**：%d
+**Token 消耗**：%s
 **耗时**：%.2f 秒
 
 ---
@@ -484,12 +1181,21 @@ func (s *AnalyzerService) formatCommentFromResult(result *model.ReviewResult, to
 		time.Now().Format("2006-01-02 15:04:05"),
 		result.Model,
 		fileCount,
-		tokenUsed,
+		tokenText,
 		duration.Seconds(),
 		issuesText,
 	)
 }
 
+// sumCounts 计数求和
+func sumCounts(counts map[string]int) int {
+	total := 0
+	for _, n := range counts {
+		total += n
+	}
+	return total
+}
+
 // matchGlob 简单的 glob 匹配
 func matchGlob(name, pattern string) bool {
 	// 简单实现：支持 * 通配符
@@ -511,6 +1217,7 @@ func (s *AnalyzerService) updateReviewFailed(ctx context.Context, review *model.
 	review.Status = model.ReviewStatusFailed
 	review.ErrorMsg = err.Error()
 	s.store.UpdateReview(ctx, review)
+	s.progress.Finish(review.ID, review.Status, review.ErrorMsg)
 	s.logger.Error("PR analysis failed",
 		zap.String("repo", review.RepoFullName),
 		zap.Int("pr_number", review.PRNumber),
@@ -548,3 +1255,8 @@ func (s *AnalyzerService) formatComment(result string, tokenUsed int, duration t
 func (s *AnalyzerService) GetStore() store.Store {
 	return s.store
 }
+
+// Progress 返回审查进度事件分发中心
+func (s *AnalyzerService) Progress() *ProgressHub {
+	return s.progress
+}
@@ -0,0 +1,294 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"code-sentinel/internal/model"
+	"code-sentinel/pkg/diff"
+	"code-sentinel/pkg/prompt"
+	"code-sentinel/pkg/tokenizer"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultBatchMaxTokens   = 12000
+	defaultBatchConcurrency = 3
+	defaultMaxBatches       = 10
+)
+
+// batchResult 单个批次的审查结果
+type batchResult struct {
+	index   int
+	changes []diff.FileChange
+	result  *model.ReviewResult
+	omitted []prompt.Omission
+	err     error
+}
+
+// reviewInBatches 将大 Diff 拆分为多个批次分别审查，再合并为一份结果
+// 批次数超出上限时只审查前面的批次，其余文件记为未审查，结果标记为降级
+func (s *AnalyzerService) reviewInBatches(ctx context.Context, run *reviewRun, systemPrompt string, changes []diff.FileChange, budget prompt.Budget) (*model.ReviewResult, error) {
+	config := run.config
+	reviewID := run.review.ID
+
+	maxTokens := config.BatchMaxTokens
+	if maxTokens <= 0 {
+		maxTokens = defaultBatchMaxTokens
+	}
+	// 批次大小不能超过模型上下文可容纳的 Diff 预算
+	if limit := run.builder.DiffTokenLimit(systemPrompt, budget); limit < maxTokens {
+		maxTokens = limit
+	}
+	if maxTokens <= 0 {
+		return nil, fmt.Errorf("system prompt exceeds token budget of model %s", budget.Model)
+	}
+	concurrency := config.BatchConcurrency
+	if concurrency <= 0 {
+		concurrency = defaultBatchConcurrency
+	}
+	maxBatches := config.MaxBatches
+	if maxBatches <= 0 {
+		maxBatches = defaultMaxBatches
+	}
+
+	batches := splitIntoBatches(changes, maxTokens, tokenizer.ForModel(budget.Model))
+	var overflow []diff.FileChange
+	if len(batches) > maxBatches {
+		for _, batch := range batches[maxBatches:] {
+			overflow = append(overflow, batch...)
+		}
+		s.logger.Warn("Diff exceeds max batches, reviewing first batches only",
+			zap.Uint("review_id", reviewID),
+			zap.Int("batches", len(batches)),
+			zap.Int("max_batches", maxBatches),
+			zap.Int("omitted_files", len(overflow)),
+		)
+		batches = batches[:maxBatches]
+	}
+
+	s.logger.Info("Reviewing diff in batches",
+		zap.Uint("review_id", reviewID),
+		zap.Int("files", len(changes)),
+		zap.Int("batches", len(batches)),
+		zap.Int("concurrency", concurrency),
+	)
+	s.publishStage(run, model.ReviewStageGenerating, fmt.Sprintf("%s, %d batches", run.llmSvc.GetModel(), len(batches)))
+
+	results := make([]batchResult, len(batches))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	finished := 0
+
+	for i, batch := range batches {
+		wg.Add(1)
+		go func(i int, batch []diff.FileChange) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			res := batchResult{index: i, changes: batch}
+			built, err := run.builder.BuildUserPromptWithBudget(systemPrompt, batch, budget)
+			if err == nil {
+				res.omitted = built.Omitted
+				// 多批次并发时增量输出会交错，不推送 token 事件
+				res.result, err = s.generateReview(ctx, run, systemPrompt, built.UserPrompt, false)
+				if err == nil {
+					res.result.Issues, res.result.Guard = guardIssues(res.result.Issues, built.Changes)
+				}
+			}
+			res.err = err
+			results[i] = res
+
+			mu.Lock()
+			finished++
+			s.publishStage(run, model.ReviewStageGenerating, fmt.Sprintf("batch %d/%d done", finished, len(batches)))
+			mu.Unlock()
+		}(i, batch)
+	}
+	wg.Wait()
+
+	merged, err := mergeBatchResults(results)
+	if err != nil {
+		return nil, err
+	}
+	if len(overflow) > 0 {
+		merged.Degraded = true
+		for _, c := range overflow {
+			merged.Omitted = append(merged.Omitted, model.OmittedChange{
+				File:   c.Filename,
+				Lines:  len(c.Additions) + len(c.Deletions),
+				Reason: "max batches exceeded",
+			})
+		}
+		merged.Summary += fmt.Sprintf("\n\n变更超出批次上限（%d 批），其余 %d 个文件未参与审查，审查结果不完整。", maxBatches, len(overflow))
+	}
+	return merged, nil
+}
+
+// splitIntoBatches 按目录聚合文件，再按 Token 预算装箱
+// 同目录文件尽量放在同一批，单个文件超出预算时独占一批
+func splitIntoBatches(changes []diff.FileChange, maxTokens int, est tokenizer.Estimator) [][]diff.FileChange {
+	groups := make(map[string][]diff.FileChange)
+	var dirs []string
+	for _, c := range changes {
+		dir := path.Dir(c.Filename)
+		if _, ok := groups[dir]; !ok {
+			dirs = append(dirs, dir)
+		}
+		groups[dir] = append(groups[dir], c)
+	}
+	// 排序后相邻目录（如 a/b 与 a/b/c）会落在相邻位置，更可能装入同一批
+	sort.Strings(dirs)
+
+	var batches [][]diff.FileChange
+	var current []diff.FileChange
+	currentTokens := 0
+
+	flush := func() {
+		if len(current) > 0 {
+			batches = append(batches, current)
+			current = nil
+			currentTokens = 0
+		}
+	}
+
+	for _, dir := range dirs {
+		files := groups[dir]
+		groupTokens := 0
+		for _, f := range files {
+			groupTokens += estimateChangeTokens(f, est)
+		}
+
+		// 整个目录放得下则整体装入，否则先另起一批
+		if currentTokens+groupTokens > maxTokens {
+			flush()
+		}
+
+		for _, f := range files {
+			tokens := estimateChangeTokens(f, est)
+			if currentTokens+tokens > maxTokens {
+				flush()
+			}
+			current = append(current, f)
+			currentTokens += tokens
+		}
+	}
+	flush()
+
+	return batches
+}
+
+// estimateChangeTokens 估算单个文件变更在提示词中占用的 Token 数
+func estimateChangeTokens(change diff.FileChange, est tokenizer.Estimator) int {
+	return est.Count(diff.FormatChangesForPrompt([]diff.FileChange{change}))
+}
+
+// mergeBatchResults 合并各批次结果：汇总摘要、去重问题并重新计算统计
+func mergeBatchResults(results []batchResult) (*model.ReviewResult, error) {
+	merged := &model.ReviewResult{Issues: []model.ReviewIssue{}}
+	seen := make(map[string]bool)
+
+	var summaries []string
+	var failed []string
+	var lastErr error
+
+	for _, res := range results {
+		if res.err != nil {
+			// 失败批次的文件未审查，结果标记为降级，与超出批次数的文件一致
+			merged.Degraded = true
+			for _, c := range res.changes {
+				merged.Omitted = append(merged.Omitted, model.OmittedChange{
+					File:   c.Filename,
+					Lines:  len(c.Additions) + len(c.Deletions),
+					Reason: "batch review failed",
+				})
+			}
+			lastErr = res.err
+			failed = append(failed, fmt.Sprintf("批次 %d（%s）：%v", res.index+1, strings.Join(batchFilenames(res.changes), ", "), res.err))
+			continue
+		}
+
+		merged.Omitted = append(merged.Omitted, toOmittedChanges(res.omitted)...)
+		if res.result.Degraded {
+			// 降级批次的原始输出不可信，不并入摘要
+			merged.Degraded = true
+			for _, p := range res.result.ValidationErrors {
+				merged.ValidationErrors = append(merged.ValidationErrors, fmt.Sprintf("batch %d: %s", res.index+1, p))
+			}
+			if res.result.RawOutput != "" {
+				merged.RawOutput += fmt.Sprintf("--- batch %d ---\n%s\n", res.index+1, res.result.RawOutput)
+			}
+			reason := "模型输出未通过格式校验"
+			if len(res.result.ValidationErrors) == 0 {
+				reason = res.result.Summary
+			}
+			summaries = append(summaries, fmt.Sprintf("- 批次 %d（%d 个文件）：%s，结果缺失", res.index+1, len(res.changes), reason))
+			continue
+		}
+		summaries = append(summaries, fmt.Sprintf("- 批次 %d（%d 个文件）：%s", res.index+1, len(res.changes), res.result.Summary))
+		merged.Guard = mergeGuardReports(merged.Guard, res.result.Guard)
+		merged.Samples = max(merged.Samples, res.result.Samples)
+		merged.MinVotes = max(merged.MinVotes, res.result.MinVotes)
+		for _, issue := range res.result.Issues {
+			key := issueKey(issue)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			merged.Issues = append(merged.Issues, issue)
+		}
+		if res.result.Score > 0 && (merged.Score == 0 || res.result.Score < merged.Score) {
+			merged.Score = res.result.Score
+		}
+	}
+
+	if len(summaries) == 0 {
+		return nil, fmt.Errorf("all %d batches failed: %w", len(results), lastErr)
+	}
+
+	merged.Summary = fmt.Sprintf("本次变更较大，分 %d 批审查：\n%s", len(results), strings.Join(summaries, "\n"))
+	if len(failed) > 0 {
+		merged.Summary += "\n\n以下批次审查失败，结果不完整：\n- " + strings.Join(failed, "\n- ")
+	}
+	merged.Stats = computeStats(merged.Issues)
+
+	return merged, nil
+}
+
+// issueKey 问题去重键：同一文件同一行的同名问题视为重复
+func issueKey(issue model.ReviewIssue) string {
+	title := strings.ToLower(strings.Join(strings.Fields(issue.Title), " "))
+	return fmt.Sprintf("%s:%d:%s", issue.File, issue.Line, title)
+}
+
+// computeStats 根据问题列表计算各级别数量
+func computeStats(issues []model.ReviewIssue) model.ReviewStats {
+	var stats model.ReviewStats
+	for _, issue := range issues {
+		switch issue.Severity {
+		case "P0":
+			stats.P0Count++
+		case "P1":
+			stats.P1Count++
+		case "P2":
+			stats.P2Count++
+		}
+	}
+	return stats
+}
+
+// batchFilenames 获取批次内的文件名列表
+func batchFilenames(batch []diff.FileChange) []string {
+	names := make([]string, 0, len(batch))
+	for _, c := range batch {
+		names = append(names, c.Filename)
+	}
+	return names
+}
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"time"
+
+	"code-sentinel/internal/config"
+	"code-sentinel/internal/model"
+	"code-sentinel/internal/store"
+
+	"go.uber.org/zap"
+)
+
+// LLMCache 按内容寻址的 LLM 响应缓存
+// 相同的系统提示词、用户提示词、模型和温度直接复用上次的响应，避免重复计费
+type LLMCache struct {
+	store      store.Store
+	enabled    bool
+	ttl        time.Duration
+	maxEntries int
+	logger     *zap.Logger
+}
+
+// NewLLMCache 创建 LLMCache 实例
+func NewLLMCache(store store.Store, cfg config.CacheConfig, logger *zap.Logger) *LLMCache {
+	ttl := time.Duration(cfg.TTLHours) * time.Hour
+	if ttl <= 0 {
+		ttl = 7 * 24 * time.Hour
+	}
+
+	maxEntries := cfg.MaxEntries
+	if maxEntries <= 0 {
+		maxEntries = 1000
+	}
+
+	return &LLMCache{
+		store:      store,
+		enabled:    cfg.Enabled,
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		logger:     logger,
+	}
+}
+
+// CacheKey 计算缓存键：系统提示词、用户提示词、模型和温度的 SHA-256
+func CacheKey(systemPrompt, userPrompt, modelName string, temperature float64) string {
+	h := sha256.New()
+	for _, part := range []string{
+		systemPrompt,
+		userPrompt,
+		modelName,
+		strconv.FormatFloat(temperature, 'f', -1, 64),
+	} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Get 读取未过期的缓存响应及原始调用的 Token 用量
+func (c *LLMCache) Get(ctx context.Context, key string) (string, model.Usage, bool) {
+	if c == nil || !c.enabled {
+		return "", model.Usage{}, false
+	}
+
+	entry, err := c.store.GetLLMCache(ctx, key)
+	if err != nil {
+		return "", model.Usage{}, false
+	}
+
+	if err := c.store.TouchLLMCache(ctx, entry.ID); err != nil {
+		c.logger.Debug("Failed to update cache hit count", zap.Error(err))
+	}
+
+	c.logger.Info("LLM cache hit",
+		zap.String("key", key[:12]),
+		zap.String("model", entry.Model),
+		zap.Int("hit_count", entry.HitCount+1),
+	)
+
+	return entry.Response, model.Usage{
+		PromptTokens:     entry.PromptTokens,
+		CompletionTokens: entry.CompletionTokens,
+		TotalTokens:      entry.TokenUsed,
+	}, true
+}
+
+// Put 写入缓存，超出容量时淘汰最早的条目
+func (c *LLMCache) Put(ctx context.Context, key, modelName, response string, usage model.Usage) {
+	if c == nil || !c.enabled {
+		return
+	}
+
+	entry := &model.LLMCache{
+		CacheKey:  key,
+		Model:     modelName,
+		Response:  response,
+		TokenUsed: usage.TotalTokens,
+		ExpiresAt: time.Now().Add(c.ttl),
+
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+	}
+
+	if err := c.store.SaveLLMCache(ctx, entry, c.maxEntries); err != nil {
+		c.logger.Warn("Failed to save LLM cache", zap.Error(err))
+	}
+}
@@ -0,0 +1,307 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"code-sentinel/internal/model"
+	"code-sentinel/pkg/diff"
+	"code-sentinel/pkg/prompt"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// compareLineTolerance 对齐不同模型的问题时允许的行号偏差
+	compareLineTolerance = 3
+	// maxConcurrentComparisons 全局同时执行的对比审查数，超出的排队等待
+	maxConcurrentComparisons = 4
+	// comparisonTimeout 单个对比审查从排队到完成的最长时间
+	comparisonTimeout = 10 * time.Minute
+)
+
+// WaitComparisons 等待进行中的对比审查结束，ctx 结束时返回其错误；用于服务关闭
+func (s *AnalyzerService) WaitComparisons(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.comparisons.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// ErrNoComparison 审查未启用对比模式
+var ErrNoComparison = errors.New("no comparison for review")
+
+// endpointLLM 复核或对比使用的 LLM 服务：未指定端点时沿用审查的提供商和凭据只换模型，
+// 指定端点时只能使用服务端 llm.endpoints 中定义的端点
+func (s *AnalyzerService) endpointLLM(run *reviewRun, ep model.LLMEndpoint) (*LLMService, error) {
+	if ep.Endpoint == "" {
+		return run.llmSvc.WithModel(ep.Model), nil
+	}
+	for _, e := range s.defaultLLMCfg.Endpoints {
+		if e.Name == ep.Endpoint {
+			return run.llmSvc.WithEndpoint(e, ep.Model), nil
+		}
+	}
+	return nil, fmt.Errorf("unknown llm endpoint %q", ep.Endpoint)
+}
+
+// startComparisons 对比模式下使用其他模型（可来自其他提供商）并行审查同一份变更，结果只存储不发布
+// 对比报告按模型名对齐问题，模型名重复的端点只运行第一个
+// 对比审查在后台执行，全局并发数受 maxConcurrentComparisons 限制，每个最长 comparisonTimeout
+func (s *AnalyzerService) startComparisons(ctx context.Context, run *reviewRun, systemPrompt string, changes []diff.FileChange, totalLines int) {
+	seen := map[string]bool{run.llmSvc.GetModel(): true}
+	for _, ep := range run.config.CompareModels {
+		llmSvc, err := s.endpointLLM(run, ep)
+		if err != nil {
+			s.logger.Warn("Invalid comparison model endpoint, skipped",
+				zap.Uint("review_id", run.review.ID),
+				zap.String("endpoint", ep.Endpoint),
+				zap.String("model", ep.Model),
+				zap.Error(err),
+			)
+			continue
+		}
+		if llmSvc.GetModel() == "" || seen[llmSvc.GetModel()] {
+			continue
+		}
+		seen[llmSvc.GetModel()] = true
+
+		compRun := run.fork(llmSvc)
+		s.comparisons.Add(1)
+		go func() {
+			defer s.comparisons.Done()
+			// 与触发审查的请求解耦：请求结束后对比仍会完成，但总时长有上限
+			saveCtx := context.WithoutCancel(ctx)
+			runCtx, cancel := context.WithTimeout(saveCtx, comparisonTimeout)
+			defer cancel()
+
+			start := time.Now()
+			select {
+			case s.comparisonSem <- struct{}{}:
+				defer func() { <-s.comparisonSem }()
+			case <-runCtx.Done():
+				s.saveComparison(saveCtx, compRun, nil, fmt.Errorf("waiting for comparison slot: %w", runCtx.Err()), time.Since(start), false)
+				return
+			}
+			result, err := s.runReview(runCtx, compRun, systemPrompt, changes, totalLines)
+			s.saveComparison(saveCtx, compRun, result, err, time.Since(start), false)
+		}()
+	}
+}
+
+// saveComparison 存储单个模型的审查结果，问题按仓库的审查重点和最小严重程度过滤，与发布的评论口径一致
+func (s *AnalyzerService) saveComparison(ctx context.Context, run *reviewRun, result *model.ReviewResult, err error, duration time.Duration, primary bool) {
+	usage, cost := run.totalUsage()
+	comparison := &model.ReviewComparison{
+		ReviewID:         run.review.ID,
+		Provider:         run.llmSvc.GetProvider(),
+		Model:            run.llmSvc.GetModel(),
+		Primary:          primary,
+		Status:           string(model.ReviewStatusCompleted),
+		TokenUsed:        usage.TotalTokens,
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		Cost:             cost,
+		DurationMs:       duration.Milliseconds(),
+	}
+
+	switch {
+	case err != nil:
+		comparison.Status = string(model.ReviewStatusFailed)
+		comparison.ErrorMsg = err.Error()
+	default:
+		filtered := *result
+		filtered.Model = comparison.Model
+		filtered.Duration = comparison.DurationMs
+		filtered.Focus = prompt.NormalizeFocus(run.config.ReviewFocus)
+		filtered.Issues, filtered.FocusFiltered = filterByFocus(result.Issues, filtered.Focus)
+		filtered.Issues = s.filterBySeverity(filtered.Issues, run.config.MinSeverity)
+		filtered.Stats = computeStats(filtered.Issues)
+		if filtered.Degraded {
+			comparison.Status = string(model.ReviewStatusDegraded)
+		}
+		comparison.IssueCount = len(filtered.Issues)
+		resultJSON, _ := json.Marshal(filtered)
+		comparison.Result = string(resultJSON)
+	}
+
+	if err := s.store.CreateReviewComparison(ctx, comparison); err != nil {
+		s.logger.Error("Failed to save review comparison",
+			zap.Uint("review_id", run.review.ID),
+			zap.String("model", comparison.Model),
+			zap.Error(err),
+		)
+		return
+	}
+
+	s.logger.Info("Review comparison saved",
+		zap.Uint("review_id", run.review.ID),
+		zap.String("model", comparison.Model),
+		zap.Bool("primary", primary),
+		zap.String("status", comparison.Status),
+		zap.Int("issues", comparison.IssueCount),
+		zap.Int64("duration_ms", comparison.DurationMs),
+	)
+}
+
+// GetComparisonReport 生成多模型对比报告：按位置对齐各模型的问题并计算一致度
+func (s *AnalyzerService) GetComparisonReport(ctx context.Context, reviewID uint) (*model.ComparisonReport, error) {
+	comparisons, err := s.store.ListReviewComparisons(ctx, reviewID)
+	if err != nil {
+		return nil, err
+	}
+	if len(comparisons) == 0 {
+		return nil, ErrNoComparison
+	}
+
+	return buildComparisonReport(reviewID, comparisons), nil
+}
+
+// buildComparisonReport 对齐各模型的问题：同一文件中行号相差不超过 compareLineTolerance 视为同一问题，
+// 文件级问题（行号为 0）按类别对齐
+func buildComparisonReport(reviewID uint, comparisons []model.ReviewComparison) *model.ComparisonReport {
+	report := &model.ComparisonReport{
+		ReviewID:  reviewID,
+		Findings:  []model.ComparisonFinding{},
+		Agreement: []model.ModelAgreement{},
+	}
+
+	var succeeded []string
+	for _, c := range comparisons {
+		entry := model.ComparisonModel{
+			Model:            c.Model,
+			Provider:         c.Provider,
+			Primary:          c.Primary,
+			Status:           c.Status,
+			IssueCount:       c.IssueCount,
+			TokenUsed:        c.TokenUsed,
+			PromptTokens:     c.PromptTokens,
+			CompletionTokens: c.CompletionTokens,
+			Cost:             c.Cost,
+			DurationMs:       c.DurationMs,
+			ErrorMsg:         c.ErrorMsg,
+		}
+
+		var result model.ReviewResult
+		if c.Result != "" && json.Unmarshal([]byte(c.Result), &result) == nil {
+			entry.Stats = result.Stats
+			if c.Status == string(model.ReviewStatusCompleted) {
+				succeeded = append(succeeded, c.Model)
+				for _, issue := range result.Issues {
+					report.Findings = alignFinding(report.Findings, c.Model, issue)
+				}
+			}
+		}
+		report.Models = append(report.Models, entry)
+	}
+
+	sort.SliceStable(report.Findings, func(i, j int) bool {
+		a, b := report.Findings[i], report.Findings[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Line < b.Line
+	})
+
+	unique := make(map[string]int)
+	for i := range report.Findings {
+		f := &report.Findings[i]
+		if len(succeeded) > 0 {
+			f.Agreement = float64(len(f.Reports)) / float64(len(succeeded))
+		}
+		if len(f.Reports) == 1 {
+			unique[f.Reports[0].Model]++
+		}
+	}
+	for i := range report.Models {
+		report.Models[i].UniqueCount = unique[report.Models[i].Model]
+	}
+
+	for i := 0; i < len(succeeded); i++ {
+		for j := i + 1; j < len(succeeded); j++ {
+			report.Agreement = append(report.Agreement, pairAgreement(report.Findings, succeeded[i], succeeded[j]))
+		}
+	}
+
+	return report
+}
+
+// alignFinding 将问题并入已有的对齐项，同一模型在同一位置只计一次
+func alignFinding(findings []model.ComparisonFinding, modelName string, issue model.ReviewIssue) []model.ComparisonFinding {
+	entry := model.FindingReport{
+		Model:    modelName,
+		Severity: issue.Severity,
+		Category: issue.Category,
+		Line:     issue.Line,
+		Title:    issue.Title,
+	}
+
+	for i := range findings {
+		f := &findings[i]
+		if f.File != issue.File || findingHasModel(f, modelName) {
+			continue
+		}
+		if issue.Line == 0 || f.Line == 0 {
+			if issue.Line == 0 && f.Line == 0 && f.Reports[0].Category == issue.Category {
+				f.Reports = append(f.Reports, entry)
+				return findings
+			}
+			continue
+		}
+		d := issue.Line - f.Line
+		if d < 0 {
+			d = -d
+		}
+		if d <= compareLineTolerance {
+			f.Reports = append(f.Reports, entry)
+			return findings
+		}
+	}
+
+	return append(findings, model.ComparisonFinding{
+		File:    issue.File,
+		Line:    issue.Line,
+		Reports: []model.FindingReport{entry},
+	})
+}
+
+// findingHasModel 对齐项中是否已包含该模型的报告
+func findingHasModel(f *model.ComparisonFinding, modelName string) bool {
+	for _, r := range f.Reports {
+		if r.Model == modelName {
+			return true
+		}
+	}
+	return false
+}
+
+// pairAgreement 计算两个模型报告问题的重合度，两者都未报告问题时视为完全一致
+func pairAgreement(findings []model.ComparisonFinding, a, b string) model.ModelAgreement {
+	shared, union := 0, 0
+	for i := range findings {
+		hasA, hasB := findingHasModel(&findings[i], a), findingHasModel(&findings[i], b)
+		if hasA && hasB {
+			shared++
+		}
+		if hasA || hasB {
+			union++
+		}
+	}
+
+	jaccard := 1.0
+	if union > 0 {
+		jaccard = float64(shared) / float64(union)
+	}
+	return model.ModelAgreement{ModelA: a, ModelB: b, Shared: shared, Jaccard: jaccard}
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"code-sentinel/internal/config"
+	"code-sentinel/internal/model"
+)
+
+// CostCalculator 按模型价格表计算 LLM 调用费用
+type CostCalculator struct {
+	currency string
+	prices   []config.ModelPrice
+}
+
+// NewCostCalculator 创建 CostCalculator 实例
+func NewCostCalculator(cfg config.PricingConfig) *CostCalculator {
+	currency := cfg.Currency
+	if currency == "" {
+		currency = "USD"
+	}
+	return &CostCalculator{
+		currency: currency,
+		prices:   cfg.Models,
+	}
+}
+
+// Cost 计算一次调用的费用，价格表中没有对应模型时返回 0
+func (c *CostCalculator) Cost(provider, modelName string, usage model.Usage) float64 {
+	price, ok := c.lookup(provider, modelName)
+	if !ok {
+		return 0
+	}
+	return float64(usage.PromptTokens)/1000*price.PromptPrice +
+		float64(usage.CompletionTokens)/1000*price.CompletionPrice
+}
+
+// Currency 返回计价货币
+func (c *CostCalculator) Currency() string {
+	if c == nil {
+		return "USD"
+	}
+	return c.currency
+}
+
+// Format 格式化费用
+func (c *CostCalculator) Format(cost float64) string {
+	return fmt.Sprintf("%.4f %s", cost, c.Currency())
+}
+
+// lookup 查找模型价格：优先匹配提供商和模型名，其次按模型名最长前缀匹配
+// 价格表中 provider 为空表示适用于任意提供商
+func (c *CostCalculator) lookup(provider, modelName string) (config.ModelPrice, bool) {
+	if c == nil {
+		return config.ModelPrice{}, false
+	}
+
+	var best config.ModelPrice
+	bestLen := -1
+	for _, p := range c.prices {
+		if p.Provider != "" && !strings.EqualFold(p.Provider, provider) {
+			continue
+		}
+		if strings.EqualFold(p.Model, modelName) {
+			return p, true
+		}
+		if strings.HasPrefix(strings.ToLower(modelName), strings.ToLower(p.Model)) && len(p.Model) > bestLen {
+			best = p
+			bestLen = len(p.Model)
+		}
+	}
+
+	return best, bestLen >= 0
+}
@@ -0,0 +1,393 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"code-sentinel/internal/model"
+	"code-sentinel/internal/store"
+	"code-sentinel/pkg/deps"
+	"code-sentinel/pkg/diff"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// maxDependencyChanges 审查结果中最多记录的依赖变更数，锁文件重新生成时可能有上千条
+	maxDependencyChanges = 200
+	// maxTransitiveListed 新增间接依赖问题中列出的包名数
+	maxTransitiveListed = 10
+	// maxCommentDependencies 评论中最多列出的直接依赖变更数
+	maxCommentDependencies = 30
+)
+
+// ErrInvalidAdvisory 公告数据无法解析
+var ErrInvalidAdvisory = errors.New("invalid advisory data")
+
+// dependencyKindLabels 依赖变更类型的展示名称
+var dependencyKindLabels = map[string]string{
+	deps.KindAdded:      "新增",
+	deps.KindRemoved:    "移除",
+	deps.KindUpgraded:   "升级",
+	deps.KindDowngraded: "降级",
+	deps.KindChanged:    "变更",
+}
+
+// DependencyService 漏洞公告库管理服务
+type DependencyService struct {
+	store  store.Store
+	logger *zap.Logger
+}
+
+// NewDependencyService 创建 DependencyService 实例
+func NewDependencyService(store store.Store, logger *zap.Logger) *DependencyService {
+	return &DependencyService{
+		store:  store,
+		logger: logger,
+	}
+}
+
+// AdvisoryImportResult 公告导入结果
+type AdvisoryImportResult struct {
+	Entries   int `json:"entries"`   // 解析出的公告数
+	Imported  int `json:"imported"`  // 写入的公告-包记录数
+	Withdrawn int `json:"withdrawn"` // 已撤回并删除的公告数
+}
+
+// ImportOSV 导入 OSV 格式的公告：单个 JSON、JSON 数组，或 OSV 按生态导出的 all.zip
+// 同一公告重复导入时覆盖，已撤回的公告从库中删除
+func (s *DependencyService) ImportOSV(ctx context.Context, data []byte) (*AdvisoryImportResult, error) {
+	entries, err := deps.ParseOSV(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAdvisory, err)
+	}
+
+	result := &AdvisoryImportResult{Entries: len(entries)}
+	var advisories []model.Advisory
+	var withdrawn []string
+	for _, e := range entries {
+		if e.ID == "" {
+			continue
+		}
+		if e.Withdrawn != "" {
+			withdrawn = append(withdrawn, e.ID)
+			continue
+		}
+		advisories = append(advisories, advisoriesFromOSV(e)...)
+	}
+
+	if err := s.store.DeleteAdvisories(ctx, withdrawn); err != nil {
+		return nil, err
+	}
+	if err := s.store.UpsertAdvisories(ctx, advisories); err != nil {
+		return nil, err
+	}
+	result.Imported = len(advisories)
+	result.Withdrawn = len(withdrawn)
+
+	s.logger.Info("OSV advisories imported",
+		zap.Int("entries", result.Entries),
+		zap.Int("imported", result.Imported),
+		zap.Int("withdrawn", result.Withdrawn),
+	)
+	return result, nil
+}
+
+// ListAdvisories 分页查询公告
+func (s *DependencyService) ListAdvisories(ctx context.Context, filter *store.AdvisoryFilter, page, pageSize int) ([]model.Advisory, int64, error) {
+	if filter != nil && filter.Package != "" {
+		filter.Package = deps.NormalizeName(filter.Ecosystem, filter.Package)
+	}
+	return s.store.ListAdvisories(ctx, filter, page, pageSize)
+}
+
+// advisoriesFromOSV 将 OSV 公告按受影响的包拆分为记录，同一包出现多次时合并区间
+func advisoriesFromOSV(e deps.OSVEntry) []model.Advisory {
+	byPkg := make(map[string]*model.Advisory)
+	var order []string
+	for _, a := range e.Affected {
+		ecosystem := a.Package.Ecosystem
+		// 带版本后缀的生态（如 Debian:11）不参与清单匹配
+		if ecosystem == "" || a.Package.Name == "" || strings.Contains(ecosystem, ":") {
+			continue
+		}
+		name := deps.NormalizeName(ecosystem, a.Package.Name)
+		key := ecosystem + "\x00" + name
+		adv, ok := byPkg[key]
+		if !ok {
+			adv = &model.Advisory{
+				AdvisoryID: e.ID,
+				Ecosystem:  ecosystem,
+				Package:    name,
+				Summary:    e.Summary,
+				Aliases:    e.Aliases,
+				Severity:   e.Severity(),
+				Modified:   e.Modified,
+			}
+			byPkg[key] = adv
+			order = append(order, key)
+		}
+		for _, r := range a.Ranges {
+			if r.Type == "GIT" {
+				continue
+			}
+			ar := model.AdvisoryRange{Type: r.Type}
+			for _, ev := range r.Events {
+				ar.Events = append(ar.Events, model.AdvisoryEvent(ev))
+			}
+			adv.Ranges = append(adv.Ranges, ar)
+		}
+		adv.Versions = append(adv.Versions, a.Versions...)
+	}
+
+	advisories := make([]model.Advisory, 0, len(order))
+	for _, key := range order {
+		advisories = append(advisories, *byPkg[key])
+	}
+	return advisories
+}
+
+// osvRanges 将存储的区间转换回 OSV 区间用于版本匹配
+func osvRanges(ranges []model.AdvisoryRange) []deps.OSVRange {
+	out := make([]deps.OSVRange, 0, len(ranges))
+	for _, r := range ranges {
+		or := deps.OSVRange{Type: r.Type}
+		for _, ev := range r.Events {
+			or.Events = append(or.Events, deps.OSVEvent(ev))
+		}
+		out = append(out, or)
+	}
+	return out
+}
+
+// analyzeDependencies 解析依赖清单和锁文件的变更（不受忽略文件和语言过滤影响），与漏洞库比对
+// 命中公告、主版本升级、降级、未固定版本和新增间接依赖作为问题返回
+func (s *AnalyzerService) analyzeDependencies(ctx context.Context, run *reviewRun, files []diff.FileChange) ([]model.DependencyChange, []model.ReviewIssue) {
+	if run.config.DisableDependencyCheck {
+		return nil, nil
+	}
+	changes := deps.Parse(files)
+	if len(changes) == 0 {
+		return nil, nil
+	}
+
+	dependencies := make([]model.DependencyChange, 0, len(changes))
+	var issues []model.ReviewIssue
+	transitive := make(map[string][]deps.Change)
+	var transitiveFiles []string
+	for _, c := range changes {
+		dep := model.DependencyChange{
+			Ecosystem:  c.Ecosystem,
+			Name:       c.Name,
+			File:       c.File,
+			Line:       c.Line,
+			OldVersion: c.OldVersion,
+			NewVersion: c.NewVersion,
+			Kind:       c.Kind,
+			Direct:     c.Direct,
+		}
+
+		if c.Kind != deps.KindRemoved {
+			if issue, ids := s.vulnerabilityIssue(ctx, c); issue != nil {
+				dep.Advisories = ids
+				issues = append(issues, *issue)
+			}
+		}
+
+		switch {
+		case !c.Direct:
+			if c.Kind == deps.KindAdded {
+				if _, ok := transitive[c.File]; !ok {
+					transitiveFiles = append(transitiveFiles, c.File)
+				}
+				transitive[c.File] = append(transitive[c.File], c)
+			}
+		case c.Kind == deps.KindUpgraded && deps.Major(deps.Concrete(c.OldVersion)) != deps.Major(deps.Concrete(c.NewVersion)):
+			issues = append(issues, dependencyIssue(c, "P2", "logic",
+				fmt.Sprintf("%s 主版本升级", c.Name),
+				fmt.Sprintf("`%s` 从 `%s` 升级到 `%s`，跨主版本升级通常包含不兼容变更。", c.Name, c.OldVersion, c.NewVersion),
+				"查阅该依赖的变更日志和迁移指南，确认调用方已适配不兼容的 API 和行为变化",
+			))
+		case c.Kind == deps.KindDowngraded:
+			issues = append(issues, dependencyIssue(c, "P2", "logic",
+				fmt.Sprintf("%s 版本降级", c.Name),
+				fmt.Sprintf("`%s` 从 `%s` 降级到 `%s`，可能重新引入已修复的缺陷或漏洞。", c.Name, c.OldVersion, c.NewVersion),
+				"确认降级是有意为之，并在 PR 说明中记录原因",
+			))
+		}
+
+		if c.Direct && c.Kind != deps.KindRemoved && deps.IsUnpinned(c.Ecosystem, c.NewVersion) &&
+			(c.OldVersion == "" || !deps.IsUnpinned(c.Ecosystem, c.OldVersion)) {
+			spec := c.NewVersion
+			if spec == "" {
+				spec = "未指定"
+			}
+			issues = append(issues, dependencyIssue(c, "P2", "security",
+				fmt.Sprintf("%s 版本未固定", c.Name),
+				fmt.Sprintf("`%s` 的版本约束 `%s` 没有上界，之后的安装可能拉取未经审查的新版本。", c.Name, spec),
+				"固定到具体版本或限定兼容范围（如 ==1.2.3、^1.2.3），由依赖更新工具统一升级",
+			))
+		}
+
+		if len(dependencies) < maxDependencyChanges {
+			dependencies = append(dependencies, dep)
+		}
+	}
+
+	for _, file := range transitiveFiles {
+		issues = append(issues, transitiveIssue(transitive[file]))
+	}
+
+	s.logger.Debug("Dependency changes analyzed",
+		zap.String("repo", run.review.RepoFullName),
+		zap.Int("changes", len(changes)),
+		zap.Int("issues", len(issues)),
+	)
+	return dependencies, issues
+}
+
+// vulnerabilityIssue 查找影响新版本的公告，命中时返回安全问题和公告 ID
+// 版本约束取允许的最低版本比对，如 ^1.2.3 按 1.2.3 检查
+func (s *AnalyzerService) vulnerabilityIssue(ctx context.Context, c deps.Change) (*model.ReviewIssue, []string) {
+	version := deps.Concrete(c.NewVersion)
+	if version == "" {
+		return nil, nil
+	}
+	advisories, err := s.store.FindAdvisories(ctx, c.Ecosystem, deps.NormalizeName(c.Ecosystem, c.Name))
+	if err != nil {
+		s.logger.Warn("Failed to query advisories",
+			zap.String("ecosystem", c.Ecosystem),
+			zap.String("package", c.Name),
+			zap.Error(err),
+		)
+		return nil, nil
+	}
+
+	var ids, lines []string
+	severity := "P1"
+	fixed := ""
+	for _, a := range advisories {
+		ranges := osvRanges(a.Ranges)
+		if !deps.Affected(version, ranges, a.Versions) {
+			continue
+		}
+		ids = append(ids, a.AdvisoryID)
+		if a.Severity == "CRITICAL" || a.Severity == "HIGH" {
+			severity = "P0"
+		}
+		if f := deps.FixedVersion(version, ranges); f != "" && (fixed == "" || deps.Compare(f, fixed) > 0) {
+			fixed = f
+		}
+		line := a.AdvisoryID
+		if len(a.Aliases) > 0 {
+			line += fmt.Sprintf("（%s）", strings.Join(a.Aliases, ", "))
+		}
+		if a.Severity != "" {
+			line += " " + a.Severity
+		}
+		if a.Summary != "" {
+			line += "：" + a.Summary
+		}
+		lines = append(lines, line)
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	target := fmt.Sprintf("`%s@%s`", c.Name, c.NewVersion)
+	if strings.TrimLeft(c.NewVersion, "=v") != version {
+		target = fmt.Sprintf("`%s@%s`（按最低版本 %s 检查）", c.Name, c.NewVersion, version)
+	}
+	suggestion := "升级到不受影响的版本；暂无修复版本时评估替代方案或缓解措施"
+	if fixed != "" {
+		suggestion = fmt.Sprintf("升级到 %s 或更高版本", fixed)
+	}
+	issue := dependencyIssue(c, severity, "security",
+		fmt.Sprintf("%s 存在已知漏洞", c.Name),
+		fmt.Sprintf("%s 命中 %d 条漏洞公告：%s", target, len(ids), strings.Join(lines, "；")),
+		suggestion,
+	)
+	return &issue, ids
+}
+
+// transitiveIssue 同一文件新增的间接依赖汇总为一个问题
+func transitiveIssue(changes []deps.Change) model.ReviewIssue {
+	names := make([]string, 0, len(changes))
+	for _, c := range changes {
+		names = append(names, c.Name)
+	}
+	sort.Strings(names)
+	listed := names
+	if len(listed) > maxTransitiveListed {
+		listed = listed[:maxTransitiveListed]
+	}
+	text := "`" + strings.Join(listed, "`、`") + "`"
+	if len(names) > len(listed) {
+		text += fmt.Sprintf(" 等 %d 个", len(names))
+	}
+
+	return dependencyIssue(changes[0], "P2", "security",
+		fmt.Sprintf("新增 %d 个间接依赖", len(names)),
+		fmt.Sprintf("本次变更引入了新的间接依赖：%s。间接依赖同样会进入构建产物，扩大供应链攻击面。", text),
+		"确认新增的间接依赖来自预期的直接依赖变更，必要时检查其维护状态和许可证",
+	)
+}
+
+// dependencyIssue 构造依赖变更问题
+func dependencyIssue(c deps.Change, severity, category, title, description, suggestion string) model.ReviewIssue {
+	return model.ReviewIssue{
+		Severity:    severity,
+		Category:    category,
+		File:        c.File,
+		Line:        c.Line,
+		Title:       title,
+		Description: description,
+		Suggestion:  suggestion,
+		Source:      model.IssueSourceDependency,
+	}
+}
+
+// formatDependencySection 评论中的依赖变更清单：直接依赖逐条列出，间接依赖只统计数量
+func formatDependencySection(dependencies []model.DependencyChange) string {
+	if len(dependencies) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("\n\n### 📦 依赖变更\n\n")
+	direct, indirect := 0, 0
+	for _, d := range dependencies {
+		if !d.Direct {
+			indirect++
+			continue
+		}
+		if direct++; direct > maxCommentDependencies {
+			continue
+		}
+		if direct == 1 {
+			sb.WriteString("| 依赖 | 变更 | 版本 | 文件 |\n|------|------|------|------|\n")
+		}
+		version := d.NewVersion
+		switch {
+		case d.Kind == deps.KindRemoved:
+			version = d.OldVersion
+		case d.OldVersion != "":
+			version = fmt.Sprintf("%s → %s", d.OldVersion, d.NewVersion)
+		}
+		name := d.Name
+		if len(d.Advisories) > 0 {
+			name += " ⚠️"
+		}
+		fmt.Fprintf(&sb, "| `%s` | %s | %s | `%s` |\n", name, dependencyKindLabels[d.Kind], version, d.File)
+	}
+	if direct > maxCommentDependencies {
+		fmt.Fprintf(&sb, "\n另有 %d 个直接依赖变更未列出。\n", direct-maxCommentDependencies)
+	}
+	if indirect > 0 {
+		fmt.Fprintf(&sb, "\n间接依赖变更 %d 个。\n", indirect)
+	}
+	return sb.String()
+}
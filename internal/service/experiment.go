@@ -0,0 +1,256 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"time"
+
+	"code-sentinel/internal/model"
+	"code-sentinel/internal/store"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// maxExperimentVariants 单个实验最多的变体数
+const maxExperimentVariants = 5
+
+// ErrInvalidExperiment 提示词版本或实验内容校验失败
+var ErrInvalidExperiment = errors.New("invalid experiment")
+
+// ErrExperimentRunning 仓库已有运行中的实验
+var ErrExperimentRunning = errors.New("repo already has a running experiment")
+
+// PromptService 提示词版本和 A/B 实验管理服务
+type PromptService struct {
+	store  store.Store
+	logger *zap.Logger
+}
+
+// NewPromptService 创建 PromptService 实例
+func NewPromptService(store store.Store, logger *zap.Logger) *PromptService {
+	return &PromptService{
+		store:  store,
+		logger: logger,
+	}
+}
+
+// CreatePromptVersionRequest 创建提示词版本请求，同名模板自动递增版本号
+type CreatePromptVersionRequest struct {
+	Name    string `json:"name" binding:"required"`
+	Content string `json:"content" binding:"required"`
+	Note    string `json:"note"`
+}
+
+// CreateExperimentRequest 创建提示词实验请求
+type CreateExperimentRequest struct {
+	Name     string                    `json:"name" binding:"required"`
+	Variants []model.ExperimentVariant `json:"variants" binding:"required"`
+}
+
+// CreateVersion 创建提示词版本，已创建的版本不可修改
+func (s *PromptService) CreateVersion(ctx context.Context, req *CreatePromptVersionRequest) (*model.PromptVersion, error) {
+	version := &model.PromptVersion{
+		Name:    strings.TrimSpace(req.Name),
+		Content: strings.TrimSpace(req.Content),
+		Note:    strings.TrimSpace(req.Note),
+	}
+	if version.Name == "" || version.Content == "" {
+		return nil, fmt.Errorf("%w: name and content are required", ErrInvalidExperiment)
+	}
+
+	if err := s.store.CreatePromptVersion(ctx, version); err != nil {
+		s.logger.Error("Failed to create prompt version", zap.String("name", version.Name), zap.Error(err))
+		return nil, err
+	}
+
+	s.logger.Info("Prompt version created", zap.String("name", version.Name), zap.Int("version", version.Version))
+	return version, nil
+}
+
+// GetVersion 获取提示词版本
+func (s *PromptService) GetVersion(ctx context.Context, id uint) (*model.PromptVersion, error) {
+	return s.store.GetPromptVersion(ctx, id)
+}
+
+// ListVersions 获取提示词版本列表，name 为空时返回全部模板
+func (s *PromptService) ListVersions(ctx context.Context, name string) ([]model.PromptVersion, error) {
+	return s.store.ListPromptVersions(ctx, name)
+}
+
+// ListExperiments 获取仓库的提示词实验
+func (s *PromptService) ListExperiments(ctx context.Context, repoFullName string) ([]model.PromptExperiment, error) {
+	return s.store.ListExperiments(ctx, repoFullName)
+}
+
+// CreateExperiment 为仓库创建并启动实验，同一仓库同时只能运行一个实验
+func (s *PromptService) CreateExperiment(ctx context.Context, repoFullName string, req *CreateExperimentRequest) (*model.PromptExperiment, error) {
+	if err := s.validateVariants(ctx, req.Variants); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.store.GetRunningExperiment(ctx, repoFullName); err == nil {
+		return nil, ErrExperimentRunning
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	experiment := &model.PromptExperiment{
+		RepoFullName: repoFullName,
+		Name:         strings.TrimSpace(req.Name),
+		Status:       model.ExperimentStatusRunning,
+		Variants:     req.Variants,
+	}
+	if err := s.store.CreateExperiment(ctx, experiment); err != nil {
+		s.logger.Error("Failed to create experiment", zap.String("repo", repoFullName), zap.Error(err))
+		return nil, err
+	}
+
+	s.logger.Info("Prompt experiment started",
+		zap.String("repo", repoFullName),
+		zap.Uint("experiment_id", experiment.ID),
+		zap.Int("variants", len(experiment.Variants)),
+	)
+	return experiment, nil
+}
+
+// StopExperiment 停止实验，之后的审查恢复使用仓库配置的提示词
+func (s *PromptService) StopExperiment(ctx context.Context, id uint) (*model.PromptExperiment, error) {
+	experiment, err := s.store.GetExperiment(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if experiment.Status == model.ExperimentStatusStopped {
+		return experiment, nil
+	}
+
+	now := time.Now()
+	experiment.Status = model.ExperimentStatusStopped
+	experiment.StoppedAt = &now
+	if err := s.store.UpdateExperiment(ctx, experiment); err != nil {
+		return nil, err
+	}
+	return experiment, nil
+}
+
+// GetExperimentReport 按变体对比误报反馈率、平均问题数、Token 和耗时，没有审查的变体也会列出
+func (s *PromptService) GetExperimentReport(ctx context.Context, id uint) (*model.ExperimentReport, error) {
+	experiment, err := s.store.GetExperiment(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	stats, err := s.store.GetExperimentStats(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &model.ExperimentReport{Experiment: *experiment}
+	for _, v := range experiment.Variants {
+		vs := model.VariantStats{PromptVersionID: v.PromptVersionID}
+		for _, st := range stats {
+			if st.PromptVersionID == v.PromptVersionID {
+				vs = st
+				break
+			}
+		}
+		vs.Percent = v.Percent
+		if v.PromptVersionID != 0 {
+			if version, err := s.store.GetPromptVersion(ctx, v.PromptVersionID); err == nil {
+				vs.PromptName = version.Name
+				vs.PromptVersion = version.Version
+			}
+		}
+		if vs.Reviews > 0 {
+			vs.IssuesPerPR = float64(vs.Issues) / float64(vs.Reviews)
+		}
+		if vs.Issues > 0 {
+			vs.FeedbackRate = float64(vs.FalsePositives) / float64(vs.Issues)
+		}
+		report.Variants = append(report.Variants, vs)
+	}
+	return report, nil
+}
+
+// validateVariants 校验变体数量、百分比之和及提示词版本是否存在
+func (s *PromptService) validateVariants(ctx context.Context, variants []model.ExperimentVariant) error {
+	if len(variants) < 2 || len(variants) > maxExperimentVariants {
+		return fmt.Errorf("%w: an experiment needs 2-%d variants", ErrInvalidExperiment, maxExperimentVariants)
+	}
+
+	total := 0
+	seen := make(map[uint]bool)
+	for _, v := range variants {
+		if v.Percent <= 0 {
+			return fmt.Errorf("%w: variant percent must be positive", ErrInvalidExperiment)
+		}
+		if seen[v.PromptVersionID] {
+			return fmt.Errorf("%w: duplicate prompt version %d", ErrInvalidExperiment, v.PromptVersionID)
+		}
+		seen[v.PromptVersionID] = true
+		total += v.Percent
+
+		if v.PromptVersionID == 0 {
+			continue
+		}
+		if _, err := s.store.GetPromptVersion(ctx, v.PromptVersionID); err != nil {
+			return fmt.Errorf("%w: prompt version %d not found", ErrInvalidExperiment, v.PromptVersionID)
+		}
+	}
+	if total != 100 {
+		return fmt.Errorf("%w: variant percents must sum to 100, got %d", ErrInvalidExperiment, total)
+	}
+	return nil
+}
+
+// assignVariant 按仓库和 PR 号的哈希将 PR 稳定分配到实验变体，同一 PR 重新审查时使用相同变体
+func assignVariant(experiment *model.PromptExperiment, repoFullName string, prNumber int) model.ExperimentVariant {
+	h := fnv.New32a()
+	fmt.Fprintf(h, "%d:%s#%d", experiment.ID, repoFullName, prNumber)
+	bucket := int(h.Sum32() % 100)
+
+	for _, v := range experiment.Variants {
+		if bucket < v.Percent {
+			return v
+		}
+		bucket -= v.Percent
+	}
+	return experiment.Variants[len(experiment.Variants)-1]
+}
+
+// basePrompt 选择本次审查的系统提示词模板并记录版本：运行中的实验 > 仓库指定的版本 > 自定义提示词 > 内置默认
+// 提示词版本获取失败时退回仓库配置的提示词，不影响审查
+func (s *AnalyzerService) basePrompt(ctx context.Context, run *reviewRun) string {
+	review := run.review
+	fallback := run.config.SystemPrompt
+	if fallback == "" {
+		fallback = defaultSystemPrompt
+	}
+
+	versionID := run.config.PromptVersionID
+	// 演练使用候选配置中的提示词，不参与实验
+	experiment, err := s.store.GetRunningExperiment(ctx, review.RepoFullName)
+	if err == nil && len(experiment.Variants) > 0 && !run.dryRun {
+		versionID = assignVariant(experiment, review.RepoFullName, review.PRNumber).PromptVersionID
+		review.ExperimentID = experiment.ID
+	}
+	if versionID == 0 {
+		return fallback
+	}
+
+	version, err := s.store.GetPromptVersion(ctx, versionID)
+	if err != nil {
+		s.logger.Warn("Failed to load prompt version, using repo prompt",
+			zap.String("repo", review.RepoFullName),
+			zap.Uint("prompt_version_id", versionID),
+			zap.Error(err),
+		)
+		// 实验中的审查记录为对照组会污染统计，退出实验
+		review.ExperimentID = 0
+		return fallback
+	}
+	review.PromptVersionID = version.ID
+	return version.Content
+}
@@ -104,6 +104,7 @@ func (s *FeedbackService) HandleFalseCommand(ctx context.Context, event *model.I
 				IsFalsePositive: true,
 				Reason:          reason,
 				Reporter:        event.Comment.User.Login,
+				RuleID:          issue.RuleID,
 			}
 
 			if err := s.store.CreateFeedback(ctx, feedback); err != nil {
@@ -3,6 +3,8 @@ package service
 import (
 	"context"
 	"fmt"
+	"net/url"
+	"strings"
 	"time"
 
 	"code-sentinel/internal/config"
@@ -65,6 +67,64 @@ func (s *GitHubService) GetPRDiff(ctx context.Context, repoFullName string, prNu
 	return resp.String(), nil
 }
 
+// GetCompareDiff 获取 base...head 的 Diff，以两者的合并基础为起点，与 PR 的 Diff 口径一致
+func (s *GitHubService) GetCompareDiff(ctx context.Context, repoFullName, base, head string) (string, error) {
+	resp, err := s.client.R().
+		SetContext(ctx).
+		SetHeader("Accept", "application/vnd.github.v3.diff").
+		Get(fmt.Sprintf("/repos/%s/compare/%s...%s", repoFullName, url.PathEscape(base), url.PathEscape(head)))
+
+	if err != nil {
+		return "", fmt.Errorf("failed to get compare diff: %w", err)
+	}
+
+	if resp.StatusCode() != 200 {
+		return "", fmt.Errorf("GitHub API error: %d %s", resp.StatusCode(), resp.String())
+	}
+
+	return resp.String(), nil
+}
+
+// GetReviewDiff 获取审查记录当时审查的 Diff：PR 之后有新提交时，PR 当前的 Diff 与当时的内容不同
+// 早期的审查记录没有保存目标分支提交，使用 PR 当前的目标分支
+func (s *GitHubService) GetReviewDiff(ctx context.Context, review *model.Review) (string, error) {
+	if review.CommitSHA == "" {
+		return s.GetPRDiff(ctx, review.RepoFullName, review.PRNumber)
+	}
+
+	base := review.BaseSHA
+	if base == "" {
+		pr, err := s.GetPullRequest(ctx, review.RepoFullName, review.PRNumber)
+		if err != nil {
+			return "", err
+		}
+		if pr.Head.SHA == review.CommitSHA {
+			return s.GetPRDiff(ctx, review.RepoFullName, review.PRNumber)
+		}
+		base = pr.Base.SHA
+	}
+	return s.GetCompareDiff(ctx, review.RepoFullName, base, review.CommitSHA)
+}
+
+// GetPullRequest 获取 PR 详情
+func (s *GitHubService) GetPullRequest(ctx context.Context, repoFullName string, prNumber int) (*model.PullRequest, error) {
+	var pr model.PullRequest
+	resp, err := s.client.R().
+		SetContext(ctx).
+		SetResult(&pr).
+		Get(fmt.Sprintf("/repos/%s/pulls/%d", repoFullName, prNumber))
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get PR: %w", err)
+	}
+
+	if resp.StatusCode() != 200 {
+		return nil, fmt.Errorf("GitHub API error: %d %s", resp.StatusCode(), resp.String())
+	}
+
+	return &pr, nil
+}
+
 func (s *GitHubService) GetPRFiles(ctx context.Context, repoFullName string, prNumber int) ([]model.PRFile, error) {
 	s.logger.Info("Fetching PR files",
 		zap.String("repo", repoFullName),
@@ -88,6 +148,127 @@ func (s *GitHubService) GetPRFiles(ctx context.Context, repoFullName string, prN
 	return files, nil
 }
 
+// GetPRCommits 获取 PR 的提交列表（按提交顺序，只取第一页 100 个）
+func (s *GitHubService) GetPRCommits(ctx context.Context, repoFullName string, prNumber int) ([]model.PRCommit, error) {
+	var commits []model.PRCommit
+	resp, err := s.client.R().
+		SetContext(ctx).
+		SetQueryParam("per_page", "100").
+		SetResult(&commits).
+		Get(fmt.Sprintf("/repos/%s/pulls/%d/commits", repoFullName, prNumber))
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get PR commits: %w", err)
+	}
+
+	if resp.StatusCode() != 200 {
+		return nil, fmt.Errorf("GitHub API error: %d %s", resp.StatusCode(), resp.String())
+	}
+
+	return commits, nil
+}
+
+// GetIssue 获取 Issue 详情
+func (s *GitHubService) GetIssue(ctx context.Context, repoFullName string, number int) (*model.Issue, error) {
+	var issue model.Issue
+	resp, err := s.client.R().
+		SetContext(ctx).
+		SetResult(&issue).
+		Get(fmt.Sprintf("/repos/%s/issues/%d", repoFullName, number))
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get issue: %w", err)
+	}
+
+	if resp.StatusCode() != 200 {
+		return nil, fmt.Errorf("GitHub API error: %d %s", resp.StatusCode(), resp.String())
+	}
+
+	return &issue, nil
+}
+
+// GetFileContent 获取指定版本的文件内容
+func (s *GitHubService) GetFileContent(ctx context.Context, repoFullName, path, ref string) (string, error) {
+	resp, err := s.client.R().
+		SetContext(ctx).
+		SetHeader("Accept", "application/vnd.github.v3.raw").
+		SetQueryParam("ref", ref).
+		Get(fmt.Sprintf("/repos/%s/contents/%s", repoFullName, escapeContentPath(path)))
+
+	if err != nil {
+		return "", fmt.Errorf("failed to get file content: %w", err)
+	}
+
+	if resp.StatusCode() != 200 {
+		return "", fmt.Errorf("GitHub API error: %d %s", resp.StatusCode(), resp.String())
+	}
+
+	return resp.String(), nil
+}
+
+// escapeContentPath 逐段转义仓库内路径，避免 ?、#、% 等字符改写请求的查询参数
+func escapeContentPath(p string) string {
+	segments := strings.Split(p, "/")
+	for i, seg := range segments {
+		segments[i] = url.PathEscape(seg)
+	}
+	return strings.Join(segments, "/")
+}
+
+// ListDirectory 列出指定版本的目录内容
+func (s *GitHubService) ListDirectory(ctx context.Context, repoFullName, path, ref string) ([]model.ContentEntry, error) {
+	var entries []model.ContentEntry
+	resp, err := s.client.R().
+		SetContext(ctx).
+		SetQueryParam("ref", ref).
+		SetResult(&entries).
+		Get(fmt.Sprintf("/repos/%s/contents/%s", repoFullName, escapeContentPath(path)))
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to list directory: %w", err)
+	}
+
+	if resp.StatusCode() != 200 {
+		return nil, fmt.Errorf("GitHub API error: %d %s", resp.StatusCode(), resp.String())
+	}
+
+	return entries, nil
+}
+
+// SearchCode 在仓库中搜索代码（GitHub 只索引默认分支）
+// 搜索词由模型给出，整体作为带引号的短语发送，其中的 repo:、org: 等不会被当作限定符
+// （GitHub 对重复的 repo: 取并集，会搜到 Token 可读的其他仓库）；结果中不属于该仓库的条目同样丢弃
+func (s *GitHubService) SearchCode(ctx context.Context, repoFullName, query string, limit int) (*model.CodeSearchResult, error) {
+	phrase := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(query)
+
+	var result model.CodeSearchResult
+	resp, err := s.client.R().
+		SetContext(ctx).
+		SetHeader("Accept", "application/vnd.github.text-match+json").
+		SetQueryParam("q", fmt.Sprintf(`"%s" repo:%s`, phrase, repoFullName)).
+		SetQueryParam("per_page", fmt.Sprintf("%d", limit)).
+		SetResult(&result).
+		Get("/search/code")
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to search code: %w", err)
+	}
+
+	if resp.StatusCode() != 200 {
+		return nil, fmt.Errorf("GitHub API error: %d %s", resp.StatusCode(), resp.String())
+	}
+
+	items := result.Items[:0]
+	for _, item := range result.Items {
+		if strings.EqualFold(item.Repository.FullName, repoFullName) {
+			items = append(items, item)
+		}
+	}
+	result.Items = items
+
+	return &result, nil
+}
+
 func (s *GitHubService) CreatePRComment(ctx context.Context, repoFullName string, prNumber int, body string) error {
 	s.logger.Info("Creating PR comment",
 		zap.String("repo", repoFullName),
@@ -115,6 +296,74 @@ func (s *GitHubService) CreatePRComment(ctx context.Context, repoFullName string
 	return nil
 }
 
+// ListPRComments 获取 PR 的全部评论，按创建时间排序
+func (s *GitHubService) ListPRComments(ctx context.Context, repoFullName string, prNumber int) ([]model.Comment, error) {
+	const perPage = 100
+	var comments []model.Comment
+	for page := 1; ; page++ {
+		var batch []model.Comment
+		resp, err := s.client.R().
+			SetContext(ctx).
+			SetQueryParam("per_page", fmt.Sprint(perPage)).
+			SetQueryParam("page", fmt.Sprint(page)).
+			SetResult(&batch).
+			Get(fmt.Sprintf("/repos/%s/issues/%d/comments", repoFullName, prNumber))
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to list comments: %w", err)
+		}
+
+		if resp.StatusCode() != 200 {
+			return nil, fmt.Errorf("GitHub API error: %d %s", resp.StatusCode(), resp.String())
+		}
+
+		comments = append(comments, batch...)
+		if len(batch) < perPage {
+			return comments, nil
+		}
+	}
+}
+
+// UpdatePRComment 修改已发布的评论
+func (s *GitHubService) UpdatePRComment(ctx context.Context, repoFullName string, commentID int64, body string) error {
+	resp, err := s.client.R().
+		SetContext(ctx).
+		SetBody(map[string]string{"body": body}).
+		Patch(fmt.Sprintf("/repos/%s/issues/comments/%d", repoFullName, commentID))
+
+	if err != nil {
+		return fmt.Errorf("failed to update comment: %w", err)
+	}
+
+	if resp.StatusCode() != 200 {
+		return fmt.Errorf("GitHub API error: %d %s", resp.StatusCode(), resp.String())
+	}
+
+	return nil
+}
+
+// CreateCommitStatus 设置提交状态，state 为 pending/success/failure/error，可在分支保护中作为必需检查
+func (s *GitHubService) CreateCommitStatus(ctx context.Context, repoFullName, sha, state, description, statusContext string) error {
+	resp, err := s.client.R().
+		SetContext(ctx).
+		SetBody(map[string]string{
+			"state":       state,
+			"description": description,
+			"context":     statusContext,
+		}).
+		Post(fmt.Sprintf("/repos/%s/statuses/%s", repoFullName, sha))
+
+	if err != nil {
+		return fmt.Errorf("failed to create commit status: %w", err)
+	}
+
+	if resp.StatusCode() != 201 {
+		return fmt.Errorf("GitHub API error: %d %s", resp.StatusCode(), resp.String())
+	}
+
+	return nil
+}
+
 func (s *GitHubService) GetWebhookSecret() string {
 	return s.config.WebhookSecret
 }
@@ -0,0 +1,200 @@
+package service
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"code-sentinel/internal/model"
+	"code-sentinel/pkg/diff"
+)
+
+// guardSnapDistance 行号偏差在该范围内时吸附到最近的变更行
+const guardSnapDistance = 3
+
+// guardIssues 按实际发送给模型的变更校验问题位置
+// 文件路径可唯一匹配时修正路径；行号偏差不大时吸附到最近的新增行；
+// 无法定位的问题降级一档，已是 P2 的直接丢弃；不在 Diff 中的文件直接丢弃
+func guardIssues(issues []model.ReviewIssue, changes []diff.FileChange) ([]model.ReviewIssue, *model.GuardReport) {
+	report := &model.GuardReport{Checked: len(issues)}
+	byFile := make(map[string]*diff.FileChange, len(changes))
+	for i := range changes {
+		byFile[changes[i].Filename] = &changes[i]
+	}
+
+	kept := make([]model.ReviewIssue, 0, len(issues))
+	for _, issue := range issues {
+		corrected := false
+
+		change, ok := byFile[issue.File]
+		if !ok {
+			change = matchChangedFile(issue.File, changes)
+			if change == nil {
+				report.Dropped++
+				report.Actions = append(report.Actions, model.GuardAction{
+					Action:   model.GuardActionDropped,
+					Title:    issue.Title,
+					File:     issue.File,
+					Line:     issue.Line,
+					Severity: issue.Severity,
+					Reason:   "file not in reviewed diff",
+				})
+				continue
+			}
+			report.Actions = append(report.Actions, model.GuardAction{
+				Action:  model.GuardActionFileCorrected,
+				Title:   issue.Title,
+				File:    issue.File,
+				Line:    issue.Line,
+				NewFile: change.Filename,
+				Reason:  "path matched a changed file by suffix",
+			})
+			issue.File = change.Filename
+			corrected = true
+		}
+
+		// 行号为 0 表示文件级问题，无需定位
+		if issue.Line == 0 {
+			if corrected {
+				report.Corrected++
+			} else {
+				report.Kept++
+			}
+			kept = append(kept, issue)
+			continue
+		}
+
+		if len(change.Additions) == 0 {
+			// 只有删除的文件没有可定位的新增行，按文件级问题处理
+			report.Actions = append(report.Actions, model.GuardAction{
+				Action: model.GuardActionFileLevel,
+				Title:  issue.Title,
+				File:   issue.File,
+				Line:   issue.Line,
+				Reason: "file has no added lines",
+			})
+			issue.Line = 0
+			report.Corrected++
+			kept = append(kept, issue)
+			continue
+		}
+
+		nearest, distance := nearestAddedLine(issue.Line, change.Additions)
+		switch {
+		case distance == 0:
+			if corrected {
+				report.Corrected++
+			} else {
+				report.Kept++
+			}
+			kept = append(kept, issue)
+
+		case distance <= guardSnapDistance:
+			report.Actions = append(report.Actions, model.GuardAction{
+				Action:  model.GuardActionSnapped,
+				Title:   issue.Title,
+				File:    issue.File,
+				Line:    issue.Line,
+				NewLine: nearest,
+				Reason:  fmt.Sprintf("line not changed, snapped to closest added line (%d lines away)", distance),
+			})
+			issue.Line = nearest
+			report.Corrected++
+			kept = append(kept, issue)
+
+		default:
+			demoted, ok := demoteSeverity(issue.Severity)
+			if !ok {
+				report.Dropped++
+				report.Actions = append(report.Actions, model.GuardAction{
+					Action:   model.GuardActionDropped,
+					Title:    issue.Title,
+					File:     issue.File,
+					Line:     issue.Line,
+					Severity: issue.Severity,
+					Reason:   fmt.Sprintf("line outside changed lines (closest added line %d)", nearest),
+				})
+				continue
+			}
+			report.Demoted++
+			report.Actions = append(report.Actions, model.GuardAction{
+				Action:      model.GuardActionDemoted,
+				Title:       issue.Title,
+				File:        issue.File,
+				Line:        issue.Line,
+				Severity:    issue.Severity,
+				NewSeverity: demoted,
+				Reason:      fmt.Sprintf("line outside changed lines (closest added line %d)", nearest),
+			})
+			issue.Severity = demoted
+			kept = append(kept, issue)
+		}
+	}
+
+	return kept, report
+}
+
+// matchChangedFile 按路径后缀匹配变更文件（模型常省略目录或带 a/、b/ 前缀），仅在唯一匹配时返回
+func matchChangedFile(file string, changes []diff.FileChange) *diff.FileChange {
+	file = strings.TrimPrefix(path.Clean(strings.TrimSpace(file)), "./")
+	file = strings.TrimPrefix(strings.TrimPrefix(file, "a/"), "b/")
+	if file == "" || file == "." {
+		return nil
+	}
+
+	var match *diff.FileChange
+	for i := range changes {
+		name := changes[i].Filename
+		if name == file || strings.HasSuffix(name, "/"+file) || strings.HasSuffix(file, "/"+name) {
+			if match != nil {
+				return nil
+			}
+			match = &changes[i]
+		}
+	}
+	return match
+}
+
+// nearestAddedLine 返回距离最近的新增行号及距离
+func nearestAddedLine(line int, additions []diff.Line) (int, int) {
+	nearest, distance := 0, -1
+	for _, a := range additions {
+		d := a.Number - line
+		if d < 0 {
+			d = -d
+		}
+		if distance < 0 || d < distance {
+			nearest, distance = a.Number, d
+		}
+	}
+	return nearest, distance
+}
+
+// demoteSeverity 严重程度降一档，P2 无法再降时返回 false
+func demoteSeverity(severity string) (string, bool) {
+	switch severity {
+	case "P0":
+		return "P1", true
+	case "P1":
+		return "P2", true
+	default:
+		return "", false
+	}
+}
+
+// mergeGuardReports 合并多个批次的校验记录
+func mergeGuardReports(dst *model.GuardReport, src *model.GuardReport) *model.GuardReport {
+	if src == nil {
+		return dst
+	}
+	if dst == nil {
+		dst = &model.GuardReport{}
+	}
+	dst.Checked += src.Checked
+	dst.Kept += src.Kept
+	dst.Corrected += src.Corrected
+	dst.Demoted += src.Demoted
+	dst.Dropped += src.Dropped
+	dst.Actions = append(dst.Actions, src.Actions...)
+	return dst
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"testing"
+
+	"code-sentinel/internal/model"
+	"code-sentinel/pkg/diff"
+)
+
+// guardChanges 校验用的变更：file.go 新增 10-12 和 20 行，removed.go 只有删除
+var guardChanges = []diff.FileChange{
+	{
+		Filename:  "internal/store/file.go",
+		Additions: []diff.Line{{Number: 10}, {Number: 11}, {Number: 12}, {Number: 20}},
+	},
+	{
+		Filename:  "internal/store/removed.go",
+		Deletions: []diff.Line{{Number: 5}},
+	},
+}
+
+func TestGuardIssues(t *testing.T) {
+	tests := []struct {
+		name   string
+		issue  model.ReviewIssue
+		want   *model.ReviewIssue // 为 nil 表示应被丢弃
+		action string             // 为空表示不记录处理动作
+	}{
+		{
+			name:  "exact line",
+			issue: model.ReviewIssue{File: "internal/store/file.go", Line: 11, Severity: "P1"},
+			want:  &model.ReviewIssue{File: "internal/store/file.go", Line: 11, Severity: "P1"},
+		},
+		{
+			name:   "within snap distance",
+			issue:  model.ReviewIssue{File: "internal/store/file.go", Line: 15, Severity: "P1"},
+			want:   &model.ReviewIssue{File: "internal/store/file.go", Line: 12, Severity: "P1"},
+			action: model.GuardActionSnapped,
+		},
+		{
+			name:   "far away demoted",
+			issue:  model.ReviewIssue{File: "internal/store/file.go", Line: 40, Severity: "P0"},
+			want:   &model.ReviewIssue{File: "internal/store/file.go", Line: 40, Severity: "P1"},
+			action: model.GuardActionDemoted,
+		},
+		{
+			name:   "far away P2 dropped",
+			issue:  model.ReviewIssue{File: "internal/store/file.go", Line: 40, Severity: "P2"},
+			action: model.GuardActionDropped,
+		},
+		{
+			name:   "suffix path",
+			issue:  model.ReviewIssue{File: "b/store/file.go", Line: 20, Severity: "P1"},
+			want:   &model.ReviewIssue{File: "internal/store/file.go", Line: 20, Severity: "P1"},
+			action: model.GuardActionFileCorrected,
+		},
+		{
+			name:  "file level",
+			issue: model.ReviewIssue{File: "internal/store/file.go", Line: 0, Severity: "P1"},
+			want:  &model.ReviewIssue{File: "internal/store/file.go", Line: 0, Severity: "P1"},
+		},
+		{
+			name:   "file without added lines",
+			issue:  model.ReviewIssue{File: "internal/store/removed.go", Line: 5, Severity: "P1"},
+			want:   &model.ReviewIssue{File: "internal/store/removed.go", Line: 0, Severity: "P1"},
+			action: model.GuardActionFileLevel,
+		},
+		{
+			name:   "file not in diff",
+			issue:  model.ReviewIssue{File: "cmd/main.go", Line: 3, Severity: "P0"},
+			action: model.GuardActionDropped,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kept, report := guardIssues([]model.ReviewIssue{tt.issue}, guardChanges)
+			if tt.want == nil {
+				if len(kept) != 0 || report.Dropped != 1 {
+					t.Fatalf("kept %+v, dropped %d, want the issue dropped", kept, report.Dropped)
+				}
+			} else {
+				if len(kept) != 1 {
+					t.Fatalf("got %d issues, want 1", len(kept))
+				}
+				got := kept[0]
+				if got.File != tt.want.File || got.Line != tt.want.Line || got.Severity != tt.want.Severity {
+					t.Errorf("got %s:%d %s, want %s:%d %s", got.File, got.Line, got.Severity, tt.want.File, tt.want.Line, tt.want.Severity)
+				}
+			}
+
+			if tt.action == "" {
+				if len(report.Actions) != 0 || report.Kept != 1 {
+					t.Errorf("actions = %+v, kept %d, want the issue kept as is", report.Actions, report.Kept)
+				}
+				return
+			}
+			if len(report.Actions) != 1 || report.Actions[0].Action != tt.action {
+				t.Errorf("actions = %+v, want one %s", report.Actions, tt.action)
+			}
+		})
+	}
+}
+
+func TestGuardIssuesAmbiguousSuffix(t *testing.T) {
+	// 后缀匹配到多个文件时无法确定，按不在 Diff 中处理
+	changes := []diff.FileChange{
+		{Filename: "api/handler.go", Additions: []diff.Line{{Number: 1}}},
+		{Filename: "web/handler.go", Additions: []diff.Line{{Number: 1}}},
+	}
+	kept, report := guardIssues([]model.ReviewIssue{{File: "handler.go", Line: 1, Severity: "P1"}}, changes)
+	if len(kept) != 0 || report.Dropped != 1 || report.Checked != 1 {
+		t.Errorf("kept %+v, report %+v, want the issue dropped", kept, report)
+	}
+}
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"code-sentinel/internal/model"
+	"code-sentinel/pkg/tokenizer"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultGuidelineMaxTokens = 3000
+	// maxGuidelineFiles 单个仓库最多注入的规范文件数
+	maxGuidelineFiles = 5
+	// maxGuidelineCacheEntries 规范缓存的最大条目数，超出后清空重建
+	maxGuidelineCacheEntries = 500
+)
+
+// guidelineSummaryPrompt 规范文件过长时的摘要提示词
+const guidelineSummaryPrompt = `你是技术文档编辑。下面是一个代码仓库的编码规范文件，请将其压缩为适合代码审查时参考的摘要。
+
+要求：
+- 保留原文的章节标题（使用原文的 Markdown 标题文字），后续审查意见会按章节标题引用规范
+- 每个章节只保留可检查的具体规则，去掉背景介绍、示例代码和流程说明
+- 使用与原文相同的语言，不要添加原文没有的规则
+- 直接输出 Markdown，不要包含任何额外说明`
+
+// guidelinePromptHeader 注入系统提示词的规范说明
+const guidelinePromptHeader = `
+
+## 仓库编码规范
+以下规范来自本仓库的规范文件（目标分支版本）。发现违反规范的代码时请报告问题，并在 guideline 字段中注明依据的文件和章节，格式为 "文件路径#章节标题"，如 "docs/STYLE.md#错误处理"；问题与规范无关时 guideline 留空。
+`
+
+// guidelineDoc 处理后的规范文件
+type guidelineDoc struct {
+	content    string
+	summarized bool
+	missing    bool // 文件不存在或获取失败
+}
+
+// guidelineCache 按仓库、目标分支 SHA 和文件路径缓存处理后的规范文件
+type guidelineCache struct {
+	mu      sync.Mutex
+	entries map[string]guidelineDoc
+}
+
+func newGuidelineCache() *guidelineCache {
+	return &guidelineCache{entries: make(map[string]guidelineDoc)}
+}
+
+func (c *guidelineCache) get(key string) (guidelineDoc, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	doc, ok := c.entries[key]
+	return doc, ok
+}
+
+func (c *guidelineCache) put(key string, doc guidelineDoc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= maxGuidelineCacheEntries {
+		c.entries = make(map[string]guidelineDoc)
+	}
+	c.entries[key] = doc
+}
+
+// guidelineSection 获取配置的规范文件并生成系统提示词片段，超出 Token 上限的文件由模型摘要
+// 使用的规范文件记录在返回的 GuidelineRef 中
+func (s *AnalyzerService) guidelineSection(ctx context.Context, run *reviewRun) (string, []model.GuidelineRef) {
+	files := run.config.GuidelineFiles
+	if len(files) == 0 {
+		return "", nil
+	}
+	if len(files) > maxGuidelineFiles {
+		files = files[:maxGuidelineFiles]
+	}
+
+	ref := baseRef(run.event)
+	maxTokens := run.config.GuidelineMaxTokens
+	if maxTokens <= 0 {
+		maxTokens = defaultGuidelineMaxTokens
+	}
+	perFile := maxTokens / len(files)
+
+	var sb strings.Builder
+	var refs []model.GuidelineRef
+	for _, file := range files {
+		path, err := cleanRepoPath(file)
+		if err != nil || path == "" {
+			continue
+		}
+
+		doc := s.loadGuideline(ctx, run, path, ref, perFile)
+		if doc.missing {
+			continue
+		}
+		refs = append(refs, model.GuidelineRef{File: path, Ref: ref, Summarized: doc.summarized})
+
+		fmt.Fprintf(&sb, "\n### 规范文件：%s\n%s\n", path, run.redact(doc.content))
+	}
+
+	if len(refs) == 0 {
+		return "", nil
+	}
+	return guidelinePromptHeader + sb.String(), refs
+}
+
+// loadGuideline 从目标分支获取规范文件，超出 maxTokens 时摘要，结果按 SHA 缓存
+func (s *AnalyzerService) loadGuideline(ctx context.Context, run *reviewRun, path, ref string, maxTokens int) guidelineDoc {
+	key := fmt.Sprintf("%s@%s:%s:%d", run.review.RepoFullName, ref, path, maxTokens)
+	if doc, ok := s.guidelines.get(key); ok {
+		return doc
+	}
+
+	content, err := run.githubSvc.GetFileContent(ctx, run.review.RepoFullName, path, ref)
+	if err != nil {
+		s.logger.Warn("Failed to fetch guideline file",
+			zap.String("repo", run.review.RepoFullName),
+			zap.String("file", path),
+			zap.String("ref", ref),
+			zap.Error(err),
+		)
+		// 获取失败不缓存，下次审查重试
+		return guidelineDoc{missing: true}
+	}
+
+	doc := guidelineDoc{content: strings.TrimSpace(content)}
+	est := tokenizer.ForModel(run.llmSvc.GetModel())
+	if est.Count(doc.content) > maxTokens {
+		s.publishStage(run, model.ReviewStagePrompting, fmt.Sprintf("summarizing guideline %s", path))
+		summary, _, err := s.chat(ctx, run, run.llmSvc, newMessages(guidelineSummaryPrompt, run.redact(doc.content)), nil, false)
+		if err != nil {
+			// 摘要失败时本次截断使用，不缓存，下次审查重试
+			s.logger.Warn("Failed to summarize guideline file, truncating",
+				zap.String("repo", run.review.RepoFullName),
+				zap.String("file", path),
+				zap.Error(err),
+			)
+			doc.content = truncateToTokens(doc.content, maxTokens, est)
+			return doc
+		}
+		doc.content = truncateToTokens(strings.TrimSpace(summary), maxTokens, est)
+		doc.summarized = true
+	}
+
+	s.guidelines.put(key, doc)
+	return doc
+}
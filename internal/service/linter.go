@@ -0,0 +1,461 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"code-sentinel/internal/config"
+	"code-sentinel/internal/model"
+	"code-sentinel/pkg/diff"
+	"code-sentinel/pkg/lint"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// maxLinterIssuesPerTool 单个工具最多合并到结果中的问题数
+	maxLinterIssuesPerTool = 30
+	// linterStderrLimit 工具失败时错误信息中保留的 stderr 字节数
+	linterStderrLimit = 2048
+	// linterWaitDelay 超时终止进程后等待输出管道关闭的时间
+	linterWaitDelay = 5 * time.Second
+)
+
+// commitSHARegex 完整的提交 SHA（SHA-1 或 SHA-256）
+var commitSHARegex = regexp.MustCompile(`^(?:[0-9a-f]{40}|[0-9a-f]{64})$`)
+
+// linterSecurityRuleRegex 按规则编号识别安全类问题：gosec（G101）、ruff/bandit（S101）
+var linterSecurityRuleRegex = regexp.MustCompile(`^(?:gosec|G\d{3}|S\d{3}|B\d{3})$`)
+
+// errLinterNoSandbox 未配置沙箱时不执行工具：工具会加载 PR 中的配置和插件，相当于执行 PR 中的代码
+var errLinterNoSandbox = errors.New("未配置 linters.sandbox，不执行外部检查工具")
+
+// LinterRunner 在临时工作区检出 PR 的 head 提交并执行外部检查工具
+// 工具只继承 PATH 和配置中列出的环境变量，服务端的令牌和密钥不会传给工具
+// 工具会执行仓库中的配置（如 eslint.config.js），只在配置了 Sandbox 时执行
+type LinterRunner struct {
+	config config.LintersConfig
+	tools  map[string]config.LinterTool
+	logger *zap.Logger
+}
+
+// NewLinterRunner 创建 LinterRunner 实例，缺少名称、命令或格式不受支持的工具被跳过
+func NewLinterRunner(cfg config.LintersConfig, logger *zap.Logger) *LinterRunner {
+	tools := make(map[string]config.LinterTool, len(cfg.Tools))
+	for _, t := range cfg.Tools {
+		if t.Name == "" || len(t.Command) == 0 || !containsString(lint.Formats(), t.Format) {
+			logger.Warn("Invalid linter tool skipped", zap.String("name", t.Name), zap.String("format", t.Format))
+			continue
+		}
+		tools[t.Name] = t
+	}
+	if len(tools) > 0 && len(cfg.Sandbox) == 0 {
+		logger.Warn("Linters are configured without linters.sandbox and will not run")
+	}
+	return &LinterRunner{
+		config: cfg,
+		tools:  tools,
+		logger: logger,
+	}
+}
+
+// toolsFor 仓库启用且变更涉及其语言的工具，按仓库配置的顺序
+func (r *LinterRunner) toolsFor(names []string, changes []diff.FileChange) []config.LinterTool {
+	if r == nil {
+		return nil
+	}
+	var tools []config.LinterTool
+	for _, name := range names {
+		t, ok := r.tools[name]
+		if !ok {
+			continue
+		}
+		if len(t.Languages) == 0 {
+			tools = append(tools, t)
+			continue
+		}
+		for _, c := range changes {
+			if containsString(t.Languages, c.Language) {
+				tools = append(tools, t)
+				break
+			}
+		}
+	}
+	return tools
+}
+
+// sandboxed 是否配置了沙箱；同一仓库分支的推送者同样可能不可信，未配置沙箱时不区分 head 来源，一律不执行
+func (r *LinterRunner) sandboxed() bool {
+	return len(r.config.Sandbox) > 0
+}
+
+// command 工具的完整命令：以沙箱命令为前缀并替换其中的工作区路径
+func (r *LinterRunner) command(w *linterWorkspace, tool config.LinterTool) []string {
+	paths := strings.NewReplacer("{workspace}", w.dir, "{repo}", w.repo, "{home}", w.home)
+	args := make([]string, 0, len(r.config.Sandbox)+len(tool.Command))
+	for _, a := range r.config.Sandbox {
+		args = append(args, paths.Replace(a))
+	}
+	return append(args, tool.Command...)
+}
+
+// linterWorkspace 检出代码的临时工作区，repo 为仓库根目录，home 为工具和 git 使用的 HOME
+type linterWorkspace struct {
+	dir  string
+	repo string
+	home string
+}
+
+// Close 删除工作区
+func (w *linterWorkspace) Close() {
+	os.RemoveAll(w.dir)
+}
+
+// checkout 在新建的临时工作区中拉取指定提交
+// 优先按 SHA 浅拉取；远端不允许按 SHA 拉取时退回拉取 PR 引用，并确认其仍指向该提交
+func (r *LinterRunner) checkout(ctx context.Context, remote, token string, prNumber int, sha string) (*linterWorkspace, error) {
+	if !commitSHARegex.MatchString(sha) {
+		return nil, fmt.Errorf("invalid commit sha %q", sha)
+	}
+	dir, err := os.MkdirTemp(r.config.WorkDir, "code-sentinel-lint-")
+	if err != nil {
+		return nil, err
+	}
+	// 工具输出的绝对路径基于真实路径（如 macOS 的 /private/var），与工作区路径保持一致
+	if real, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = real
+	}
+	w := &linterWorkspace{dir: dir, repo: filepath.Join(dir, "repo"), home: filepath.Join(dir, "home")}
+	if err := os.MkdirAll(w.home, 0o700); err != nil {
+		w.Close()
+		return nil, err
+	}
+
+	timeout := time.Duration(r.config.FetchTimeout) * time.Second
+	if timeout <= 0 {
+		timeout = 2 * time.Minute
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	env := []string{
+		"PATH=" + os.Getenv("PATH"),
+		"HOME=" + w.home,
+		"GIT_TERMINAL_PROMPT=0",
+		"GIT_CONFIG_NOSYSTEM=1",
+	}
+	if token != "" {
+		auth := base64.StdEncoding.EncodeToString([]byte("x-access-token:" + token))
+		// 通过环境变量传递认证头，不出现在命令行参数中
+		env = append(env,
+			"GIT_CONFIG_COUNT=1",
+			"GIT_CONFIG_KEY_0=http.extraHeader",
+			"GIT_CONFIG_VALUE_0=Authorization: Basic "+auth,
+		)
+	}
+	git := func(args ...string) (string, error) {
+		name := args[0]
+		if name == "-C" {
+			name = args[2]
+		}
+		cmd := exec.CommandContext(ctx, "git", args...)
+		cmd.Dir = w.dir
+		cmd.Env = env
+		cmd.WaitDelay = linterWaitDelay
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if err != nil {
+			if ctx.Err() != nil {
+				return "", fmt.Errorf("git %s: timed out after %s", name, timeout)
+			}
+			return "", fmt.Errorf("git %s: %v: %s", name, err, truncateRunes(strings.TrimSpace(stderr.String()), 300))
+		}
+		return strings.TrimSpace(string(out)), nil
+	}
+
+	steps := func() error {
+		if _, err := git("init", "-q", w.repo); err != nil {
+			return err
+		}
+		if _, err := git("-C", w.repo, "fetch", "-q", "--depth=1", "--no-tags", "--", remote, sha); err != nil {
+			if prNumber == 0 {
+				return err
+			}
+			if _, prErr := git("-C", w.repo, "fetch", "-q", "--depth=1", "--no-tags", "--", remote, fmt.Sprintf("refs/pull/%d/head", prNumber)); prErr != nil {
+				return fmt.Errorf("%v; %v", err, prErr)
+			}
+			head, err := git("-C", w.repo, "rev-parse", "FETCH_HEAD")
+			if err != nil {
+				return err
+			}
+			if head != sha {
+				return fmt.Errorf("pull request head moved to %s", head)
+			}
+		}
+		_, err := git("-C", w.repo, "checkout", "-q", "--detach", "FETCH_HEAD")
+		return err
+	}
+	if err := steps(); err != nil {
+		w.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// run 在仓库根目录执行工具并解析输出；工具发现问题时通常以非零状态退出，能解析输出即视为成功
+func (r *LinterRunner) run(ctx context.Context, w *linterWorkspace, tool config.LinterTool) ([]lint.Finding, error) {
+	timeout := time.Duration(tool.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = time.Duration(r.config.Timeout) * time.Second
+	}
+	if timeout <= 0 {
+		timeout = 5 * time.Minute
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	maxOutput := r.config.MaxOutput
+	if maxOutput <= 0 {
+		maxOutput = 10 << 20
+	}
+	stdout := &limitedBuffer{limit: maxOutput}
+	stderr := &limitedBuffer{limit: linterStderrLimit}
+
+	args := r.command(w, tool)
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Dir = w.repo
+	cmd.Env = r.toolEnv(w)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	cmd.WaitDelay = linterWaitDelay
+	runErr := cmd.Run()
+
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("timed out after %s", timeout)
+	}
+	var exitErr *exec.ExitError
+	if runErr != nil && !errors.As(runErr, &exitErr) {
+		return nil, runErr
+	}
+	if stdout.truncated {
+		return nil, fmt.Errorf("output exceeds %d bytes", maxOutput)
+	}
+
+	findings, err := lint.Parse(tool.Format, stdout.Bytes(), w.repo)
+	if err != nil {
+		if runErr != nil {
+			return nil, fmt.Errorf("%v: %s", runErr, strings.TrimSpace(stderr.String()))
+		}
+		return nil, err
+	}
+	return findings, nil
+}
+
+// toolEnv 工具的环境变量：PATH、工作区内的 HOME 和临时目录，以及配置中列出的变量
+func (r *LinterRunner) toolEnv(w *linterWorkspace) []string {
+	env := []string{
+		"PATH=" + os.Getenv("PATH"),
+		"HOME=" + w.home,
+		"TMPDIR=" + w.home,
+		"CI=true",
+		"NO_COLOR=1",
+	}
+	for _, name := range r.config.Env {
+		if v, ok := os.LookupEnv(name); ok {
+			env = append(env, name+"="+v)
+		}
+	}
+	return env
+}
+
+// remoteURL 仓库的 Git 地址，以及是否可以附带 GitHub 令牌
+// 只有远端主机与 GitHub API 所在站点一致时才发送令牌，避免泄露给其他远端
+func (r *LinterRunner) remoteURL(repo, githubBaseURL string) (string, bool) {
+	template := r.config.CloneURL
+	if template == "" {
+		template = "https://github.com/{repo}.git"
+	}
+	remote := strings.ReplaceAll(template, "{repo}", repo)
+
+	u, err := url.Parse(remote)
+	if err != nil || u.Scheme != "https" {
+		return remote, false
+	}
+	host := "github.com"
+	if api, err := url.Parse(githubBaseURL); err == nil && api.Host != "" {
+		host = strings.TrimPrefix(api.Host, "api.")
+	}
+	return remote, strings.EqualFold(u.Host, host)
+}
+
+// limitedBuffer 超过上限后丢弃写入的缓冲区
+type limitedBuffer struct {
+	bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.Len(); room < len(p) {
+		b.truncated = true
+		if room > 0 {
+			b.Buffer.Write(p[:room])
+		}
+		return len(p), nil
+	}
+	return b.Buffer.Write(p)
+}
+
+// startLinters 在后台检出 head 提交并执行仓库启用的工具，与模型审查并行；返回等待结果的函数
+func (s *AnalyzerService) startLinters(ctx context.Context, run *reviewRun, changes []diff.FileChange) func() ([]model.LinterRun, []model.ReviewIssue) {
+	tools := s.linters.toolsFor(run.config.Linters, changes)
+	sha := run.event.PullRequest.Head.SHA
+	if len(tools) == 0 || sha == "" || run.review.RepoFullName == "" {
+		return func() ([]model.LinterRun, []model.ReviewIssue) { return nil, nil }
+	}
+	if !s.linters.sandboxed() {
+		runs := make([]model.LinterRun, 0, len(tools))
+		for _, t := range tools {
+			runs = append(runs, model.LinterRun{Tool: t.Name, Error: errLinterNoSandbox.Error()})
+		}
+		s.logger.Warn("Linters skipped: linters.sandbox is not configured",
+			zap.String("repo", run.review.RepoFullName),
+			zap.Int("pr_number", run.review.PRNumber),
+		)
+		return func() ([]model.LinterRun, []model.ReviewIssue) { return runs, nil }
+	}
+
+	var runs []model.LinterRun
+	var issues []model.ReviewIssue
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		runs, issues = s.runLinters(ctx, run, tools, changes)
+	}()
+	return func() ([]model.LinterRun, []model.ReviewIssue) {
+		<-done
+		return runs, issues
+	}
+}
+
+// runLinters 依次执行工具，只保留位于新增行上的问题
+func (s *AnalyzerService) runLinters(ctx context.Context, run *reviewRun, tools []config.LinterTool, changes []diff.FileChange) ([]model.LinterRun, []model.ReviewIssue) {
+	repo := run.review.RepoFullName
+	remote, withToken := s.linters.remoteURL(repo, run.githubSvc.config.BaseURL)
+	token := ""
+	if withToken {
+		token = run.githubSvc.config.Token
+	}
+
+	runs := make([]model.LinterRun, 0, len(tools))
+	start := time.Now()
+	w, err := s.linters.checkout(ctx, remote, token, run.review.PRNumber, run.event.PullRequest.Head.SHA)
+	if err != nil {
+		s.logger.Warn("Failed to check out pull request for linters",
+			zap.String("repo", repo),
+			zap.Int("pr_number", run.review.PRNumber),
+			zap.Error(err),
+		)
+		for _, t := range tools {
+			runs = append(runs, model.LinterRun{Tool: t.Name, DurationMs: time.Since(start).Milliseconds(), Error: "checkout failed: " + err.Error()})
+		}
+		return runs, nil
+	}
+	defer w.Close()
+
+	var issues []model.ReviewIssue
+	for _, t := range tools {
+		toolStart := time.Now()
+		findings, err := s.linters.run(ctx, w, t)
+		lr := model.LinterRun{Tool: t.Name, Findings: len(findings), DurationMs: time.Since(toolStart).Milliseconds()}
+		if err != nil {
+			lr.Error = err.Error()
+			s.logger.Warn("Linter failed",
+				zap.String("repo", repo),
+				zap.String("tool", t.Name),
+				zap.Error(err),
+			)
+		}
+
+		kept := lint.OnAddedLines(findings, changes)
+		lr.Kept = len(kept)
+		for i, f := range kept {
+			if i >= maxLinterIssuesPerTool {
+				break
+			}
+			issues = append(issues, linterIssue(t.Name, f))
+		}
+		runs = append(runs, lr)
+	}
+	return runs, issues
+}
+
+// linterIssue 将工具的问题转换为审查问题：error 级别为 P1，其余为 P2
+func linterIssue(tool string, f lint.Finding) model.ReviewIssue {
+	severity := "P2"
+	if f.Level == lint.LevelError {
+		severity = "P1"
+	}
+	category := "style"
+	if linterSecurityRuleRegex.MatchString(f.Rule) {
+		category = "security"
+	}
+	title := truncateRunes(firstLine(f.Message), 80)
+	if f.Rule != "" {
+		title = fmt.Sprintf("[%s] %s", f.Rule, title)
+	}
+	name := tool
+	if f.Tool != "" && !strings.EqualFold(f.Tool, tool) {
+		name = fmt.Sprintf("%s（%s）", tool, f.Tool)
+	}
+	return model.ReviewIssue{
+		Severity:    severity,
+		Category:    category,
+		File:        f.File,
+		Line:        f.Line,
+		Title:       title,
+		Description: f.Message,
+		Source:      model.IssueSourceLinter,
+		Tool:        name,
+	}
+}
+
+// formatLinterRuns 评论中的外部检查工具执行摘要
+func formatLinterRuns(runs []model.LinterRun) string {
+	if len(runs) == 0 {
+		return ""
+	}
+	// 检出失败时所有工具的错误相同，合并为一条
+	shared := runs[0].Error
+	names := make([]string, 0, len(runs))
+	for _, r := range runs {
+		if r.Error != shared {
+			shared = ""
+		}
+		names = append(names, r.Tool)
+	}
+	if shared != "" {
+		return fmt.Sprintf("\n\n> 🧰 外部检查：%s 执行失败（%s）\n", strings.Join(names, "、"), truncateRunes(firstLine(shared), 120))
+	}
+
+	parts := make([]string, 0, len(runs))
+	for _, r := range runs {
+		if r.Error != "" {
+			parts = append(parts, fmt.Sprintf("%s 执行失败（%s）", r.Tool, truncateRunes(firstLine(r.Error), 80)))
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%s 报告 %d 个问题，%d 个位于变更行", r.Tool, r.Findings, r.Kept))
+	}
+	return fmt.Sprintf("\n\n> 🧰 外部检查：%s\n", strings.Join(parts, "；"))
+}
@@ -1,21 +1,57 @@
 package service
 
 import (
+	"bufio"
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"net/http"
+	"strings"
 	"time"
 
 	"code-sentinel/internal/config"
 	"code-sentinel/internal/model"
+	"code-sentinel/pkg/prompt"
+	"code-sentinel/pkg/tokenizer"
 
 	"github.com/go-resty/resty/v2"
 	"go.uber.org/zap"
 )
 
 type LLMService struct {
-	client *resty.Client
-	config config.LLMConfig
-	logger *zap.Logger
+	client       *resty.Client
+	streamClient *resty.Client // 流式请求专用：没有整体超时和重试，由首包和空闲超时约束
+	config       config.LLMConfig
+	logger       *zap.Logger
+}
+
+// InitTokenizer 配置词表来源并预先加载默认模型的词表，无法精确计数或上下文窗口未知时记录警告
+// 启用下载时在后台加载，不阻塞启动；加载完成前按启发式估算
+func InitTokenizer(cfg config.LLMConfig, logger *zap.Logger) {
+	tokenizer.SetVocabDir(cfg.TokenizerDir)
+	if cfg.TokenizerDownload {
+		tokenizer.EnableVocabDownload()
+	}
+	preload := func() {
+		if err := tokenizer.Preload(cfg.Model); err != nil {
+			logger.Warn("Tokenizer vocabulary unavailable, token counts fall back to estimation",
+				zap.String("model", cfg.Model),
+				zap.Error(err),
+			)
+		}
+	}
+	if cfg.TokenizerDownload {
+		go preload()
+	} else {
+		preload()
+	}
+	if _, ok := tokenizer.ContextWindow(cfg.Model); !ok && cfg.ContextWindow <= 0 {
+		logger.Warn("Unknown context window for model, assuming a conservative default until llm.context_window is set",
+			zap.String("model", cfg.Model),
+			zap.Int("context_window", prompt.DefaultContextWindow),
+		)
+	}
 }
 
 func NewLLMService(cfg config.LLMConfig, logger *zap.Logger) *LLMService {
@@ -29,6 +65,19 @@ func NewLLMService(cfg config.LLMConfig, logger *zap.Logger) *LLMService {
 		timeout = 60
 	}
 
+	client, streamClient := newLLMClients(baseURL, cfg.APIKey, timeout)
+
+	return &LLMService{
+		client:       client,
+		streamClient: streamClient,
+		config:       cfg,
+		logger:       logger,
+	}
+}
+
+// newLLMClients 创建普通请求和流式请求的 HTTP 客户端
+// 普通请求整体超时并重试；流式请求的生成时间可能远超超时，只限制等待响应头的时间，读取由空闲超时约束，且不重试
+func newLLMClients(baseURL, apiKey string, timeout int) (*resty.Client, *resty.Client) {
 	client := resty.New().
 		SetBaseURL(baseURL).
 		SetHeader("Content-Type", "application/json").
@@ -36,65 +85,361 @@ func NewLLMService(cfg config.LLMConfig, logger *zap.Logger) *LLMService {
 		SetRetryCount(2).
 		SetRetryWaitTime(2 * time.Second)
 
-	if cfg.APIKey != "" {
-		client.SetHeader("Authorization", "Bearer "+cfg.APIKey)
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = time.Duration(timeout) * time.Second
+	streamClient := resty.New().
+		SetTransport(transport).
+		SetBaseURL(baseURL).
+		SetHeader("Content-Type", "application/json")
+
+	if apiKey != "" {
+		client.SetHeader("Authorization", "Bearer "+apiKey)
+		streamClient.SetHeader("Authorization", "Bearer "+apiKey)
 	}
+	return client, streamClient
+}
 
-	return &LLMService{
-		client: client,
-		config: cfg,
-		logger: logger,
+func (s *LLMService) Chat(ctx context.Context, systemPrompt, userPrompt string) (string, model.Usage, error) {
+	return s.ChatMessages(ctx, newMessages(systemPrompt, userPrompt), nil)
+}
+
+// ChatStream 以流式方式调用 LLM，每收到一段增量内容就回调 onDelta
+// 返回值与 Chat 一致：完整内容和 Token 用量
+func (s *LLMService) ChatStream(ctx context.Context, systemPrompt, userPrompt string, onDelta func(string)) (string, model.Usage, error) {
+	return s.ChatMessagesStream(ctx, newMessages(systemPrompt, userPrompt), nil, onDelta)
+}
+
+// ChatMessages 以完整消息列表调用 LLM，format 不为空时按提供商能力请求结构化输出
+func (s *LLMService) ChatMessages(ctx context.Context, messages []model.Message, format *model.ResponseFormat) (string, model.Usage, error) {
+	msg, usage, err := s.ChatWithTools(ctx, messages, nil, format)
+	if err != nil {
+		return "", model.Usage{}, err
 	}
+	return msg.Content, usage, nil
 }
 
-func (s *LLMService) Chat(ctx context.Context, systemPrompt, userPrompt string) (string, int, error) {
+// ChatWithTools 携带工具定义调用 LLM，返回完整的 assistant 消息（可能包含工具调用）
+func (s *LLMService) ChatWithTools(ctx context.Context, messages []model.Message, tools []model.Tool, format *model.ResponseFormat) (model.Message, model.Usage, error) {
 	s.logger.Info("Calling LLM API",
 		zap.String("model", s.config.Model),
 		zap.Int("max_tokens", s.config.MaxTokens),
+		zap.Int("tools", len(tools)),
 	)
 
-	req := model.ChatRequest{
-		Model: s.config.Model,
-		Messages: []model.Message{
-			{Role: "system", Content: systemPrompt},
-			{Role: "user", Content: userPrompt},
-		},
-		MaxTokens:   s.config.MaxTokens,
-		Temperature: 0.3,
+	req := s.newChatRequest(messages, format)
+	req.Tools = tools
+
+	resp, httpResp, err := s.postChat(ctx, req)
+	if err != nil {
+		return model.Message{}, model.Usage{}, err
+	}
+
+	if httpResp.StatusCode() == 400 && req.ResponseFormat != nil {
+		// 模型不支持该结构化输出格式时去掉 response_format 重试，由调用方校验输出
+		s.logger.Warn("LLM rejected response_format, retrying without it",
+			zap.String("model", s.config.Model),
+			zap.String("response_format", req.ResponseFormat.Type),
+			zap.String("error", httpResp.String()),
+		)
+		req.ResponseFormat = nil
+		resp, httpResp, err = s.postChat(ctx, req)
+		if err != nil {
+			return model.Message{}, model.Usage{}, err
+		}
+	}
+
+	if httpResp.StatusCode() != 200 {
+		return model.Message{}, model.Usage{}, fmt.Errorf("LLM API error: %d %s", httpResp.StatusCode(), httpResp.String())
+	}
+
+	if len(resp.Choices) == 0 {
+		return model.Message{}, model.Usage{}, fmt.Errorf("LLM returned empty response")
 	}
 
+	s.logger.Info("LLM API response received",
+		zap.Int("prompt_tokens", resp.Usage.PromptTokens),
+		zap.Int("completion_tokens", resp.Usage.CompletionTokens),
+		zap.Int("total_tokens", resp.Usage.TotalTokens),
+		zap.Int("tool_calls", len(resp.Choices[0].Message.ToolCalls)),
+	)
+
+	return resp.Choices[0].Message, resp.Usage, nil
+}
+
+// postChat 发送非流式对话请求
+func (s *LLMService) postChat(ctx context.Context, req model.ChatRequest) (*model.ChatResponse, *resty.Response, error) {
 	var resp model.ChatResponse
 	httpResp, err := s.client.R().
 		SetContext(ctx).
 		SetBody(req).
 		SetResult(&resp).
 		Post("/chat/completions")
+	if err != nil {
+		return nil, nil, fmt.Errorf("LLM API request failed: %w", err)
+	}
+	return &resp, httpResp, nil
+}
+
+// ChatMessagesStream 以流式方式调用 LLM，参数含义与 ChatMessages 一致
+func (s *LLMService) ChatMessagesStream(ctx context.Context, messages []model.Message, format *model.ResponseFormat, onDelta func(string)) (string, model.Usage, error) {
+	s.logger.Info("Calling LLM API (stream)",
+		zap.String("model", s.config.Model),
+		zap.Int("max_tokens", s.config.MaxTokens),
+	)
+
+	req := s.newChatRequest(messages, format)
+	req.Stream = true
+	req.StreamOptions = &model.StreamOptions{IncludeUsage: true}
 
+	// 超过空闲超时没有收到新数据时取消请求，生成时间本身不受限制
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	idleTimeout := s.streamIdleTimeout()
+	idle := time.AfterFunc(idleTimeout, cancel)
+	defer idle.Stop()
+
+	httpResp, err := s.postStream(streamCtx, req)
 	if err != nil {
-		return "", 0, fmt.Errorf("LLM API request failed: %w", err)
+		return "", model.Usage{}, err
+	}
+
+	if httpResp.StatusCode() == 400 && req.ResponseFormat != nil {
+		errBody, _ := io.ReadAll(httpResp.RawBody())
+		httpResp.RawBody().Close()
+		s.logger.Warn("LLM rejected response_format, retrying without it",
+			zap.String("model", s.config.Model),
+			zap.String("response_format", req.ResponseFormat.Type),
+			zap.String("error", string(errBody)),
+		)
+		req.ResponseFormat = nil
+		idle.Reset(idleTimeout)
+		httpResp, err = s.postStream(streamCtx, req)
+		if err != nil {
+			return "", model.Usage{}, err
+		}
 	}
 
+	body := httpResp.RawBody()
+	defer body.Close()
+
 	if httpResp.StatusCode() != 200 {
-		return "", 0, fmt.Errorf("LLM API error: %d %s", httpResp.StatusCode(), httpResp.String())
+		errBody, _ := io.ReadAll(body)
+		return "", model.Usage{}, fmt.Errorf("LLM API error: %d %s", httpResp.StatusCode(), string(errBody))
 	}
 
-	if len(resp.Choices) == 0 {
-		return "", 0, fmt.Errorf("LLM returned empty response")
+	var content strings.Builder
+	var usage model.Usage
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		idle.Reset(idleTimeout)
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk model.ChatStreamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			s.logger.Debug("Failed to parse stream chunk", zap.Error(err))
+			continue
+		}
+
+		if chunk.Usage != nil {
+			usage = *chunk.Usage
+		}
+
+		for _, choice := range chunk.Choices {
+			if choice.Delta.Content == "" {
+				continue
+			}
+			content.WriteString(choice.Delta.Content)
+			if onDelta != nil {
+				onDelta(choice.Delta.Content)
+			}
+		}
 	}
 
-	s.logger.Info("LLM API response received",
-		zap.Int("prompt_tokens", resp.Usage.PromptTokens),
-		zap.Int("completion_tokens", resp.Usage.CompletionTokens),
-		zap.Int("total_tokens", resp.Usage.TotalTokens),
+	if err := scanner.Err(); err != nil {
+		if ctx.Err() == nil && streamCtx.Err() != nil {
+			return "", model.Usage{}, fmt.Errorf("LLM stream idle for more than %s", idleTimeout)
+		}
+		return "", model.Usage{}, fmt.Errorf("LLM stream read failed: %w", err)
+	}
+
+	if content.Len() == 0 {
+		return "", model.Usage{}, fmt.Errorf("LLM returned empty response")
+	}
+
+	s.logger.Info("LLM API stream completed",
+		zap.Int("prompt_tokens", usage.PromptTokens),
+		zap.Int("completion_tokens", usage.CompletionTokens),
+		zap.Int("total_tokens", usage.TotalTokens),
 	)
 
-	return resp.Choices[0].Message.Content, resp.Usage.TotalTokens, nil
+	return content.String(), usage, nil
+}
+
+// streamIdleTimeout 流式响应两次收到数据之间的最长间隔，与普通请求的超时相同
+func (s *LLMService) streamIdleTimeout() time.Duration {
+	if s.config.Timeout > 0 {
+		return time.Duration(s.config.Timeout) * time.Second
+	}
+	return 60 * time.Second
+}
+
+// postStream 发送流式请求，不解析响应体
+func (s *LLMService) postStream(ctx context.Context, req model.ChatRequest) (*resty.Response, error) {
+	httpResp, err := s.streamClient.R().
+		SetContext(ctx).
+		SetHeader("Accept", "text/event-stream").
+		SetBody(req).
+		SetDoNotParseResponse(true).
+		Post("/chat/completions")
+	if err != nil {
+		return nil, fmt.Errorf("LLM API request failed: %w", err)
+	}
+	return httpResp, nil
+}
+
+// newMessages 构建系统提示词 + 用户提示词的消息列表
+func newMessages(systemPrompt, userPrompt string) []model.Message {
+	return []model.Message{
+		{Role: "system", Content: systemPrompt},
+		{Role: "user", Content: userPrompt},
+	}
+}
+
+// newChatRequest 构建对话请求
+func (s *LLMService) newChatRequest(messages []model.Message, format *model.ResponseFormat) model.ChatRequest {
+	temperature := s.config.Temperature
+	return model.ChatRequest{
+		Model:          s.config.Model,
+		Messages:       messages,
+		MaxTokens:      s.config.MaxTokens,
+		Temperature:    &temperature,
+		ResponseFormat: s.responseFormat(format),
+	}
+}
+
+// responseFormat 按提供商支持的结构化输出能力调整请求格式
+// 只支持 JSON 模式的提供商降级为 json_object，不支持时返回 nil
+func (s *LLMService) responseFormat(format *model.ResponseFormat) *model.ResponseFormat {
+	if format == nil {
+		return nil
+	}
+
+	switch s.StructuredOutputMode() {
+	case model.ResponseFormatJSONSchema:
+		return format
+	case model.ResponseFormatJSONObject:
+		return &model.ResponseFormat{Type: model.ResponseFormatJSONObject}
+	default:
+		return nil
+	}
+}
+
+// StructuredOutputMode 返回实际使用的结构化输出模式：json_schema / json_object / off
+func (s *LLMService) StructuredOutputMode() string {
+	mode := strings.ToLower(s.config.StructuredOutput)
+	switch mode {
+	case model.ResponseFormatJSONSchema, model.ResponseFormatJSONObject, "off":
+		return mode
+	}
+
+	// auto：按提供商选择
+	switch strings.ToLower(s.config.Provider) {
+	case "openai", "azure":
+		return model.ResponseFormatJSONSchema
+	case "qwen", "ollama":
+		return model.ResponseFormatJSONObject
+	default:
+		return "off"
+	}
+}
+
+// GetProvider 返回 LLM 提供商
+func (s *LLMService) GetProvider() string {
+	return s.config.Provider
+}
+
+// GetTemperature 返回采样温度
+func (s *LLMService) GetTemperature() float64 {
+	return s.config.Temperature
+}
+
+// GetMaxTokens 返回单次回答的最大 Token 数
+func (s *LLMService) GetMaxTokens() int {
+	return s.config.MaxTokens
+}
+
+// GetContextWindow 返回配置的上下文窗口，0 表示按模型查表
+func (s *LLMService) GetContextWindow() int {
+	return s.config.ContextWindow
+}
+
+// StreamEnabled 是否启用流式输出
+func (s *LLMService) StreamEnabled() bool {
+	return s.config.Stream
 }
 
 func (s *LLMService) GetModel() string {
 	return s.config.Model
 }
 
+// WithTemperature 返回使用指定采样温度的 LLM 服务
+func (s *LLMService) WithTemperature(temperature float64) *LLMService {
+	if temperature == s.config.Temperature {
+		return s
+	}
+	cfg := s.config
+	cfg.Temperature = temperature
+	return &LLMService{
+		client:       s.client,
+		streamClient: s.streamClient,
+		config:       cfg,
+		logger:       s.logger,
+	}
+}
+
+// WithModel 返回使用同一提供商和凭据、但换用指定模型的 LLM 服务
+func (s *LLMService) WithModel(modelName string) *LLMService {
+	if modelName == "" || modelName == s.config.Model {
+		return s
+	}
+	cfg := s.config
+	cfg.Model = modelName
+	cfg.ContextWindow = 0
+	return &LLMService{
+		client:       s.client,
+		streamClient: s.streamClient,
+		config:       cfg,
+		logger:       s.logger,
+	}
+}
+
+// WithEndpoint 返回使用服务端定义的端点的 LLM 服务，地址和凭据全部来自端点配置，不与当前配置混用
+// modelName 为空时使用端点配置的模型；超时、回答长度等沿用当前配置
+func (s *LLMService) WithEndpoint(ep config.LLMEndpoint, modelName string) *LLMService {
+	cfg := s.config
+	cfg.ContextWindow = 0
+	if ep.Provider != "" {
+		cfg.Provider = ep.Provider
+	}
+	cfg.BaseURL = ep.BaseURL
+	cfg.APIKey = ep.APIKey
+	cfg.Model = ep.Model
+	if modelName != "" {
+		cfg.Model = modelName
+	}
+	return NewLLMService(cfg, s.logger)
+}
+
 // NewLLMServiceWithConfig 使用简化配置创建 LLM 服务（用于仓库级配置）
 func NewLLMServiceWithConfig(cfg LLMConfig, logger *zap.Logger) *LLMService {
 	baseURL := cfg.BaseURL
@@ -112,19 +457,11 @@ func NewLLMServiceWithConfig(cfg LLMConfig, logger *zap.Logger) *LLMService {
 		maxTokens = 4096
 	}
 
-	client := resty.New().
-		SetBaseURL(baseURL).
-		SetHeader("Content-Type", "application/json").
-		SetTimeout(time.Duration(timeout) * time.Second).
-		SetRetryCount(2).
-		SetRetryWaitTime(2 * time.Second)
-
-	if cfg.APIKey != "" {
-		client.SetHeader("Authorization", "Bearer "+cfg.APIKey)
-	}
+	client, streamClient := newLLMClients(baseURL, cfg.APIKey, timeout)
 
 	return &LLMService{
-		client: client,
+		client:       client,
+		streamClient: streamClient,
 		config: config.LLMConfig{
 			Provider:  cfg.Provider,
 			APIKey:    cfg.APIKey,
@@ -132,6 +469,11 @@ func NewLLMServiceWithConfig(cfg LLMConfig, logger *zap.Logger) *LLMService {
 			BaseURL:   baseURL,
 			Timeout:   timeout,
 			MaxTokens: maxTokens,
+			Stream:    cfg.Stream,
+
+			ContextWindow:    cfg.ContextWindow,
+			Temperature:      cfg.Temperature,
+			StructuredOutput: cfg.StructuredOutput,
 		},
 		logger: logger,
 	}
@@ -0,0 +1,229 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"code-sentinel/internal/model"
+	"code-sentinel/pkg/diff"
+
+	"go.uber.org/zap"
+)
+
+// maxPatternHitsPerFile 同一规则在同一文件中最多报告的次数，避免批量改动刷屏
+const maxPatternHitsPerFile = 5
+
+// patternRule 编译后的模式规则
+type patternRule struct {
+	rule       model.ReviewRule
+	pattern    *regexp.Regexp
+	exclude    *regexp.Regexp // 同时匹配时不报告，仅内置规则使用
+	suggestion string
+}
+
+// BuiltinPatternRule 内置模式规则
+type BuiltinPatternRule struct {
+	model.ReviewRule
+	Exclude    string `json:"exclude,omitempty"`
+	Suggestion string `json:"suggestion"`
+}
+
+// builtinPatternRules 内置模式规则，仓库可通过 disabled_builtin_rules 关闭，同 ID 的仓库规则会覆盖内置规则
+var builtinPatternRules = []BuiltinPatternRule{
+	{
+		ReviewRule: model.ReviewRule{
+			RuleID:      "builtin-go-println",
+			Description: "遗留的 fmt.Println 调试输出",
+			Severity:    "P2",
+			Category:    "style",
+			Pattern:     `\bfmt\.Println\(|^\s*println\(`,
+			Languages:   []string{"go"},
+		},
+		Suggestion: "删除调试输出，或改用项目的日志库",
+	},
+	{
+		ReviewRule: model.ReviewRule{
+			RuleID:      "builtin-todo-ticket",
+			Description: "TODO/FIXME 未关联 Issue 或工单",
+			Severity:    "P2",
+			Category:    "style",
+			Pattern:     `(//|#|/\*|--|^\s*\*)\s*(TODO|FIXME)\b`,
+		},
+		Exclude:    `(TODO|FIXME)\s*[(:\s]\s*(#\d+|[A-Z][A-Z0-9]+-\d+|https?://)`,
+		Suggestion: "在 TODO 中注明 Issue 编号，如 TODO(#123) 或 TODO(PROJ-123)",
+	},
+	{
+		ReviewRule: model.ReviewRule{
+			RuleID:      "builtin-sql-select-star",
+			Description: "SQL 使用 SELECT *",
+			Severity:    "P2",
+			Category:    "performance",
+			Pattern:     `(?i)\bselect\s+\*`,
+			Languages:   []string{"sql"},
+		},
+		Suggestion: "显式列出需要的字段，避免读取多余数据和表结构变更带来的兼容问题",
+	},
+	{
+		ReviewRule: model.ReviewRule{
+			RuleID:      "builtin-tls-insecure",
+			Description: "关闭了 TLS 证书校验",
+			Severity:    "P1",
+			Category:    "security",
+			Pattern:     `InsecureSkipVerify\s*:\s*true|\bverify\s*=\s*False\b|rejectUnauthorized\s*:\s*false|NODE_TLS_REJECT_UNAUTHORIZED['"]?\s*\]?\s*=\s*['"]?0|\bcurl\b.*\s(-k|--insecure)\b`,
+		},
+		Suggestion: "保持证书校验开启；自签名证书请配置受信任的 CA",
+	},
+}
+
+// BuiltinPatternRules 返回内置模式规则
+func BuiltinPatternRules() []BuiltinPatternRule {
+	return builtinPatternRules
+}
+
+// loadPatternRules 合并内置规则和仓库的模式规则，跳过已关闭的规则；正则无法编译的规则记录警告后跳过
+func (s *AnalyzerService) loadPatternRules(ctx context.Context, run *reviewRun) []patternRule {
+	var repoRules []model.ReviewRule
+	if run.review.RepoFullName != "" {
+		rules, err := s.store.ListReviewRules(ctx, run.review.RepoFullName)
+		if err != nil {
+			s.logger.Warn("Failed to load review rules", zap.String("repo", run.review.RepoFullName), zap.Error(err))
+		}
+		repoRules = rules
+	}
+
+	overridden := make(map[string]bool)
+	var compiled []patternRule
+	for _, rule := range repoRules {
+		if rule.Pattern == "" {
+			continue
+		}
+		// 关闭的仓库规则同样覆盖同 ID 的内置规则
+		overridden[strings.ToLower(rule.RuleID)] = true
+		if !rule.Enabled {
+			continue
+		}
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			s.logger.Warn("Invalid pattern rule", zap.String("rule_id", rule.RuleID), zap.Error(err))
+			continue
+		}
+		compiled = append(compiled, patternRule{rule: rule, pattern: re})
+	}
+
+	for _, b := range builtinPatternRules {
+		id := strings.ToLower(b.RuleID)
+		if overridden[id] || containsFold(run.config.DisabledBuiltinRules, id) {
+			continue
+		}
+		p := patternRule{rule: b.ReviewRule, pattern: regexp.MustCompile(b.Pattern), suggestion: b.Suggestion}
+		if b.Exclude != "" {
+			p.exclude = regexp.MustCompile(b.Exclude)
+		}
+		compiled = append(compiled, p)
+	}
+	return compiled
+}
+
+// scanPatterns 用模式规则匹配新增行，不依赖模型，模型调用失败时仍可发布结果
+func (s *AnalyzerService) scanPatterns(ctx context.Context, run *reviewRun, changes []diff.FileChange) []model.ReviewIssue {
+	rules := s.loadPatternRules(ctx, run)
+	if len(rules) == 0 {
+		return nil
+	}
+
+	var issues []model.ReviewIssue
+	for _, change := range changes {
+		for _, p := range rules {
+			if !patternApplies(p.rule, change) {
+				continue
+			}
+			hits := 0
+			for _, line := range change.Additions {
+				if !p.pattern.MatchString(line.Content) || (p.exclude != nil && p.exclude.MatchString(line.Content)) {
+					continue
+				}
+				issues = append(issues, model.ReviewIssue{
+					Severity:    p.rule.Severity,
+					Category:    p.rule.Category,
+					File:        change.Filename,
+					Line:        line.Number,
+					Title:       truncateRunes(firstLine(p.rule.Description), 80),
+					Description: fmt.Sprintf("%s：`%s`", p.rule.Description, truncateRunes(strings.TrimSpace(line.Content), 120)),
+					Suggestion:  p.suggestion,
+					RuleID:      p.rule.RuleID,
+					Source:      model.IssueSourceRule,
+				})
+				if hits++; hits >= maxPatternHitsPerFile {
+					break
+				}
+			}
+		}
+	}
+	return issues
+}
+
+// patternApplies 文件语言和路径是否在模式规则的适用范围内
+func patternApplies(rule model.ReviewRule, change diff.FileChange) bool {
+	if len(rule.Languages) > 0 && !containsString(rule.Languages, change.Language) {
+		return false
+	}
+	return ruleApplies(rule, change.Filename)
+}
+
+// mergePatternIssues 合并模型和模式规则发现的问题；同一位置同一类别的问题只保留规则结果
+func mergePatternIssues(llmIssues, ruleIssues []model.ReviewIssue) []model.ReviewIssue {
+	covered := make(map[string]bool, len(ruleIssues))
+	for _, issue := range ruleIssues {
+		covered[fmt.Sprintf("%s:%d:%s", issue.File, issue.Line, issue.Category)] = true
+	}
+
+	merged := make([]model.ReviewIssue, 0, len(llmIssues)+len(ruleIssues))
+	for _, issue := range llmIssues {
+		if covered[fmt.Sprintf("%s:%d:%s", issue.File, issue.Line, issue.Category)] {
+			continue
+		}
+		issue.Source = model.IssueSourceLLM
+		merged = append(merged, issue)
+	}
+	return append(merged, ruleIssues...)
+}
+
+// concatIssues 按顺序合并多组问题，返回新分配的切片，不与参数共用底层数组
+func concatIssues(groups ...[]model.ReviewIssue) []model.ReviewIssue {
+	n := 0
+	for _, g := range groups {
+		n += len(g)
+	}
+	merged := make([]model.ReviewIssue, 0, n)
+	for _, g := range groups {
+		merged = append(merged, g...)
+	}
+	return merged
+}
+
+// patternOnlyResult 模型审查失败时的结果，只包含模式规则等模型以外来源的问题，标记为降级
+func patternOnlyResult(err error) *model.ReviewResult {
+	return &model.ReviewResult{
+		Summary:  "模型审查未完成，以下仅为模式规则等模型以外的检查结果",
+		Degraded: true,
+		LLMError: err.Error(),
+	}
+}
+
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(strings.TrimSpace(item), s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,186 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"code-sentinel/internal/model"
+	"code-sentinel/pkg/diff"
+
+	"go.uber.org/zap"
+)
+
+// ErrInvalidPlayground 演练请求参数错误
+var ErrInvalidPlayground = errors.New("invalid playground request")
+
+// PlaygroundRequest 提示词演练请求：基于历史审查记录对应的 PR 或直接提供的 Diff，使用候选配置试运行审查
+type PlaygroundRequest struct {
+	ReviewID uint                `json:"review_id"` // 与 diff 二选一
+	Diff     string              `json:"diff"`      // 统一 Diff 格式
+	Repo     string              `json:"repo"`      // 提供 diff 时可选，用于加载仓库配置、规范文件和审查规则
+	Config   *model.ReviewConfig `json:"config"`    // 候选配置，为空时使用仓库当前配置
+}
+
+// PlaygroundResult 演练结果
+type PlaygroundResult struct {
+	SystemPrompt string              `json:"system_prompt"`
+	Exchanges    []LLMExchange       `json:"exchanges"` // 每次 LLM 调用的完整消息和原始输出（分批、采样、修复、复核各算一次）
+	Result       *model.ReviewResult `json:"result"`
+	Usage        model.Usage         `json:"usage"`
+	Cost         float64             `json:"cost"`
+	Files        int                 `json:"files"` // 过滤后参与审查的文件数
+	Lines        int                 `json:"lines"`
+	DurationMs   int64               `json:"duration_ms"`
+	Skipped      string              `json:"skipped,omitempty"` // 未调用模型的原因
+}
+
+// RunPlayground 按候选配置试运行 过滤 → 构建提示词 → 调用 LLM → 解析 流水线
+// 不发布 PR 评论，不写审查记录、工具调用记录、规则命中和仓库统计，总是跳过响应缓存
+func (s *AnalyzerService) RunPlayground(ctx context.Context, req *PlaygroundRequest) (*PlaygroundResult, error) {
+	if (req.ReviewID == 0) == (strings.TrimSpace(req.Diff) == "") {
+		return nil, fmt.Errorf("%w: exactly one of review_id and diff is required", ErrInvalidPlayground)
+	}
+
+	run, diffContent, err := s.preparePlayground(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	changes, err := diff.ParseDiff(diffContent)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPlayground, err)
+	}
+
+	result := &PlaygroundResult{}
+	secretIssues := s.scanSecrets(ctx, run, changes)
+	dependencies, depIssues := s.analyzeDependencies(ctx, run, changes)
+	changes = s.applyFilters(changes, run.config)
+	if len(changes) == 0 {
+		result.Skipped = "No reviewable changes after filtering"
+		return result, nil
+	}
+	waitLinters := s.startLinters(ctx, run, changes)
+	ruleIssues := concatIssues(secretIssues, depIssues, s.scanPatterns(ctx, run, changes))
+	changes = s.redactChanges(run, maskSecrets(run, changes))
+
+	if req.ReviewID != 0 {
+		run.builder = s.builder.WithPRContext(s.buildPRContext(ctx, run))
+	}
+	templates := s.templates.WithOverrides(run.config.LanguagePrompts)
+	systemPrompt, guidelineRefs := s.buildSystemPrompt(ctx, run, changes, templates)
+	result.SystemPrompt = systemPrompt
+	result.Files = len(changes)
+	result.Lines = s.countDiffLines(changes)
+
+	reviewResult, err := s.runReview(ctx, run, systemPrompt, changes, result.Lines)
+	result.Exchanges = run.transcript
+	result.Usage, result.Cost = run.totalUsage()
+	linterRuns, linterIssues := waitLinters()
+	ruleIssues = append(ruleIssues, linterIssues...)
+	if err != nil {
+		if len(ruleIssues) == 0 {
+			return result, err
+		}
+		reviewResult = patternOnlyResult(err)
+	}
+
+	s.verifyIssues(ctx, run, reviewResult, changes)
+	reviewResult.Issues = mergePatternIssues(reviewResult.Issues, ruleIssues)
+	reviewResult.Dependencies = dependencies
+	reviewResult.Linters = linterRuns
+	if run.redactor != nil {
+		reviewResult.Redactions = run.redactor.Counts()
+	}
+	s.finishResult(run, reviewResult, guidelineRefs)
+	reviewResult.Model = run.llmSvc.GetModel()
+
+	result.Result = reviewResult
+	result.Exchanges = run.transcript
+	result.Usage, result.Cost = run.totalUsage()
+	result.DurationMs = time.Since(start).Milliseconds()
+	reviewResult.Duration = result.DurationMs
+
+	s.logger.Info("Playground run completed",
+		zap.String("repo", run.review.RepoFullName),
+		zap.Uint("review_id", req.ReviewID),
+		zap.Int("llm_calls", len(result.Exchanges)),
+		zap.Int("issues_count", len(reviewResult.Issues)),
+	)
+	return result, nil
+}
+
+// preparePlayground 创建演练运行上下文并获取 Diff，审查记录只在内存中使用不落库
+func (s *AnalyzerService) preparePlayground(ctx context.Context, req *PlaygroundRequest) (*reviewRun, string, error) {
+	repoFullName := strings.TrimSpace(req.Repo)
+	event := &model.PullRequestEvent{Action: "playground"}
+
+	var prev *model.Review
+	if req.ReviewID != 0 {
+		var err error
+		prev, err = s.store.GetReview(ctx, req.ReviewID)
+		if err != nil {
+			return nil, "", fmt.Errorf("%w: review %d not found", ErrInvalidPlayground, req.ReviewID)
+		}
+		repoFullName = prev.RepoFullName
+		event.Number = prev.PRNumber
+		event.PullRequest = model.PullRequest{
+			Number: prev.PRNumber,
+			Title:  prev.PRTitle,
+			User:   model.User{Login: prev.PRAuthor},
+			Head:   model.Ref{SHA: prev.CommitSHA},
+		}
+	}
+	event.Repository = model.Repository{FullName: repoFullName}
+
+	config := req.Config
+	if config == nil {
+		config = s.getDefaultConfig()
+		if repoFullName != "" {
+			config = s.loadRepoConfig(ctx, repoFullName)
+		}
+	}
+	if repoFullName == "" {
+		// 没有仓库时无法读取文件，关闭依赖仓库内容的功能
+		copied := *config
+		copied.AgentEnabled = false
+		copied.GuidelineFiles = nil
+		config = &copied
+	}
+
+	run := &reviewRun{
+		event: event,
+		review: &model.Review{
+			RepoFullName: repoFullName,
+			PRNumber:     event.Number,
+			PRTitle:      event.PullRequest.Title,
+			PRAuthor:     event.PullRequest.User.Login,
+			CommitSHA:    event.PullRequest.Head.SHA,
+		},
+		config:    config,
+		llmSvc:    s.getLLMService(config),
+		githubSvc: s.getGitHubService(config),
+		opts:      AnalyzeOptions{Manual: true, BypassCache: true},
+		builder:   s.builder,
+		dryRun:    true,
+	}
+
+	if prev == nil {
+		return run, req.Diff, nil
+	}
+
+	// 使用 PR 最新的描述和 base 分支，用于 PR 上下文和规范文件；head 保持为当时审查的提交
+	if pr, err := run.githubSvc.GetPullRequest(ctx, repoFullName, prev.PRNumber); err == nil {
+		run.event.PullRequest = *pr
+		run.event.PullRequest.Head.SHA = prev.CommitSHA
+	}
+	// PR 之后可能有新提交，按当时审查的提交获取 Diff，与存储的审查结果对比才有意义
+	diffContent, err := run.githubSvc.GetReviewDiff(ctx, prev)
+	if err != nil {
+		return nil, "", err
+	}
+	return run, diffContent, nil
+}
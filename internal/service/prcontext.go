@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"code-sentinel/pkg/prompt"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultDescriptionMaxChars  = 2000
+	defaultCommitsMaxChars      = 2000
+	defaultLinkedIssuesMaxChars = 3000
+	// maxCommitMessageChars 单条提交信息的最大字符数
+	maxCommitMessageChars = 300
+	// maxLinkedIssues 最多获取的关联 Issue 数
+	maxLinkedIssues = 5
+)
+
+// linkedIssueRegex 匹配 GitHub 关闭关键字引用的 Issue，如 Fixes #123、closes owner/repo#45
+var linkedIssueRegex = regexp.MustCompile(`(?i)\b(?:close[sd]?|fix(?:e[sd])?|resolve[sd]?)\s*:?\s+([\w.-]+/[\w.-]+)?#(\d+)\b`)
+
+// buildPRContext 按配置收集 PR 标题和描述、提交信息、关联 Issue，各部分分别裁剪长度并脱敏
+// 获取失败的部分直接跳过，不影响审查
+func (s *AnalyzerService) buildPRContext(ctx context.Context, run *reviewRun) *prompt.PRContext {
+	config := run.config
+	pr := run.event.PullRequest
+	pc := &prompt.PRContext{}
+
+	if config.IncludeDescription {
+		limit := configuredLimit(config.DescriptionMaxChars, defaultDescriptionMaxChars)
+		pc.Title = run.redact(truncateRunes(strings.TrimSpace(pr.Title), limit))
+		pc.Body = run.redact(truncateRunes(strings.TrimSpace(pr.Body), limit))
+	}
+
+	// 提交信息同时用于查找关联 Issue
+	var messages []string
+	if config.IncludeCommits || config.IncludeLinkedIssues {
+		commits, err := run.githubSvc.GetPRCommits(ctx, run.review.RepoFullName, run.review.PRNumber)
+		if err != nil {
+			s.logger.Warn("Failed to get PR commits", zap.Uint("review_id", run.review.ID), zap.Error(err))
+		}
+		for _, c := range commits {
+			if len(c.Parents) > 1 {
+				continue // 跳过合并提交
+			}
+			messages = append(messages, strings.TrimSpace(c.Commit.Message))
+			if config.IncludeCommits {
+				sha := c.SHA
+				if len(sha) > 7 {
+					sha = sha[:7]
+				}
+				pc.Commits = append(pc.Commits, fmt.Sprintf("%s %s", sha, truncateRunes(oneLine(c.Commit.Message), maxCommitMessageChars)))
+			}
+		}
+		pc.Commits = capLines(pc.Commits, configuredLimit(config.CommitsMaxChars, defaultCommitsMaxChars))
+		for i := range pc.Commits {
+			pc.Commits[i] = run.redact(pc.Commits[i])
+		}
+	}
+
+	if config.IncludeLinkedIssues {
+		refs := linkedIssueRefs(run.review.RepoFullName, run.review.PRNumber, config.LinkedIssueRepos, append([]string{pr.Body}, messages...))
+		remaining := configuredLimit(config.LinkedIssuesMaxChars, defaultLinkedIssuesMaxChars)
+		for _, ref := range refs {
+			if remaining <= 0 {
+				break
+			}
+			issue, err := run.githubSvc.GetIssue(ctx, ref.repo, ref.number)
+			if err != nil {
+				s.logger.Warn("Failed to get linked issue",
+					zap.Uint("review_id", run.review.ID),
+					zap.String("issue", ref.String(run.review.RepoFullName)),
+					zap.Error(err),
+				)
+				continue
+			}
+			body := truncateRunes(strings.TrimSpace(issue.Body), remaining)
+			remaining -= len([]rune(body)) + len([]rune(issue.Title))
+			pc.LinkedIssues = append(pc.LinkedIssues, prompt.LinkedIssue{
+				Ref:   ref.String(run.review.RepoFullName),
+				Title: run.redact(issue.Title),
+				Body:  run.redact(body),
+			})
+		}
+	}
+
+	return pc
+}
+
+// issueRef 关联 Issue 的引用
+type issueRef struct {
+	repo   string
+	number int
+}
+
+// String 同仓库的 Issue 显示为 #123，跨仓库显示为 owner/repo#123
+func (r issueRef) String(currentRepo string) string {
+	if strings.EqualFold(r.repo, currentRepo) {
+		return fmt.Sprintf("#%d", r.number)
+	}
+	return fmt.Sprintf("%s#%d", r.repo, r.number)
+}
+
+// linkedIssueRefs 从文本中提取关联 Issue，去重并排除 PR 自身，最多 maxLinkedIssues 个
+// 引用由 PR 作者填写，而 Issue 用服务的 Token 获取：只跟踪本仓库和 allowed 中列出的仓库，避免读出作者无权访问的私有仓库
+func linkedIssueRefs(repo string, prNumber int, allowed []string, texts []string) []issueRef {
+	seen := make(map[string]bool)
+	var refs []issueRef
+	for _, text := range texts {
+		for _, m := range linkedIssueRegex.FindAllStringSubmatch(text, -1) {
+			ref := issueRef{repo: repo}
+			if m[1] != "" {
+				ref.repo = m[1]
+			}
+			ref.number, _ = strconv.Atoi(m[2])
+			key := strings.ToLower(ref.String(repo))
+			sameRepo := strings.EqualFold(ref.repo, repo)
+			if ref.number == 0 || (sameRepo && ref.number == prNumber) || seen[key] {
+				continue
+			}
+			if !sameRepo && !issueRepoAllowed(ref.repo, allowed) {
+				continue
+			}
+			seen[key] = true
+			refs = append(refs, ref)
+			if len(refs) >= maxLinkedIssues {
+				return refs
+			}
+		}
+	}
+	return refs
+}
+
+// issueRepoAllowed 仓库是否在允许跟踪关联 Issue 的列表中，支持 owner/* 通配
+func issueRepoAllowed(repo string, allowed []string) bool {
+	for _, pattern := range allowed {
+		if matchGlob(strings.ToLower(repo), strings.ToLower(strings.TrimSpace(pattern))) {
+			return true
+		}
+	}
+	return false
+}
+
+// configuredLimit 返回配置的长度上限，未配置时使用默认值
+func configuredLimit(configured, def int) int {
+	if configured > 0 {
+		return configured
+	}
+	return def
+}
+
+// capLines 保留总字符数不超过 limit 的前若干行，超出时追加省略说明
+func capLines(lines []string, limit int) []string {
+	used := 0
+	for i, line := range lines {
+		used += len([]rune(line))
+		if used > limit {
+			return append(lines[:i:i], fmt.Sprintf("...（另有 %d 个提交未列出）", len(lines)-i))
+		}
+	}
+	return lines
+}
+
+// oneLine 将多行文本合并为一行
+func oneLine(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"sync"
+	"time"
+
+	"code-sentinel/internal/model"
+)
+
+// progressBufferSize 每个订阅者的事件缓冲大小，消费过慢时丢弃 token 事件
+const progressBufferSize = 256
+
+// ProgressHub 审查进度事件分发中心
+type ProgressHub struct {
+	mu          sync.RWMutex
+	subscribers map[uint]map[chan model.ReviewEvent]struct{}
+	lastStage   map[uint]model.ReviewEvent
+}
+
+// NewProgressHub 创建 ProgressHub 实例
+func NewProgressHub() *ProgressHub {
+	return &ProgressHub{
+		subscribers: make(map[uint]map[chan model.ReviewEvent]struct{}),
+		lastStage:   make(map[uint]model.ReviewEvent),
+	}
+}
+
+// Subscribe 订阅指定审查的进度事件，返回事件通道和取消订阅函数
+// 如果审查正在进行，会先补发最近一次阶段事件
+func (h *ProgressHub) Subscribe(reviewID uint) (<-chan model.ReviewEvent, func()) {
+	ch := make(chan model.ReviewEvent, progressBufferSize)
+
+	h.mu.Lock()
+	if h.subscribers[reviewID] == nil {
+		h.subscribers[reviewID] = make(map[chan model.ReviewEvent]struct{})
+	}
+	h.subscribers[reviewID][ch] = struct{}{}
+	if last, ok := h.lastStage[reviewID]; ok {
+		ch <- last
+	}
+	h.mu.Unlock()
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			if subs, ok := h.subscribers[reviewID]; ok {
+				if _, ok := subs[ch]; ok {
+					delete(subs, ch)
+					close(ch)
+				}
+				if len(subs) == 0 {
+					delete(h.subscribers, reviewID)
+				}
+			}
+		})
+	}
+
+	return ch, cancel
+}
+
+// PublishStage 发布阶段切换事件
+func (h *ProgressHub) PublishStage(reviewID uint, stage model.ReviewStage, message string) {
+	event := model.ReviewEvent{
+		Type:     model.ReviewEventStage,
+		ReviewID: reviewID,
+		Stage:    stage,
+		Message:  message,
+		Time:     time.Now(),
+	}
+
+	h.mu.Lock()
+	h.lastStage[reviewID] = event
+	h.mu.Unlock()
+
+	h.publish(event)
+}
+
+// PublishToken 发布 LLM 增量输出事件
+func (h *ProgressHub) PublishToken(reviewID uint, delta string) {
+	h.publish(model.ReviewEvent{
+		Type:     model.ReviewEventToken,
+		ReviewID: reviewID,
+		Stage:    model.ReviewStageGenerating,
+		Delta:    delta,
+		Time:     time.Now(),
+	})
+}
+
+// Finish 发布结束事件并关闭该审查的所有订阅
+func (h *ProgressHub) Finish(reviewID uint, status model.ReviewStatus, message string) {
+	event := model.ReviewEvent{
+		Type:     model.ReviewEventDone,
+		ReviewID: reviewID,
+		Status:   status,
+		Message:  message,
+		Time:     time.Now(),
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.lastStage, reviewID)
+	for ch := range h.subscribers[reviewID] {
+		select {
+		case ch <- event:
+		default:
+		}
+		close(ch)
+	}
+	delete(h.subscribers, reviewID)
+}
+
+// publish 非阻塞地向所有订阅者投递事件
+func (h *ProgressHub) publish(event model.ReviewEvent) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for ch := range h.subscribers[event.ReviewID] {
+		select {
+		case ch <- event:
+		default:
+			// 订阅者消费过慢，丢弃该事件
+		}
+	}
+}
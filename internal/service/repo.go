@@ -205,6 +205,10 @@ func (s *RepoService) GetDefaultConfig() *model.ReviewConfig {
 		IgnoreFiles:  []string{"*.test.go", "vendor/*", "node_modules/*"},
 		MaxDiffLines: 1000,
 		AutoReview:   true,
+
+		IncludeDescription:  true,
+		IncludeCommits:      true,
+		IncludeLinkedIssues: true,
 	}
 }
 
@@ -222,6 +226,10 @@ func (s *RepoService) GetConfigTemplates() []ConfigTemplate {
 				MinSeverity:  "P1",
 				MaxDiffLines: 1000,
 				AutoReview:   true,
+
+				IncludeDescription:  true,
+				IncludeCommits:      true,
+				IncludeLinkedIssues: true,
 			},
 		},
 		{
@@ -236,6 +244,10 @@ func (s *RepoService) GetConfigTemplates() []ConfigTemplate {
 				MinSeverity:  "P1",
 				MaxDiffLines: 1000,
 				AutoReview:   true,
+
+				IncludeDescription:  true,
+				IncludeCommits:      true,
+				IncludeLinkedIssues: true,
 			},
 		},
 		{
@@ -250,6 +262,10 @@ func (s *RepoService) GetConfigTemplates() []ConfigTemplate {
 				MinSeverity:  "P0",
 				MaxDiffLines: 1000,
 				AutoReview:   true,
+
+				IncludeDescription:  true,
+				IncludeCommits:      true,
+				IncludeLinkedIssues: true,
 			},
 		},
 	}
@@ -0,0 +1,361 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"code-sentinel/internal/model"
+	"code-sentinel/internal/store"
+	"code-sentinel/pkg/diff"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// maxPromptRules 单次审查最多注入提示词的规则数
+	maxPromptRules = 30
+	// maxRuleExamplesChars 单条规则示例注入提示词的最大字符数
+	maxRuleExamplesChars = 1000
+)
+
+// ErrInvalidRule 规则内容校验失败
+var ErrInvalidRule = errors.New("invalid rule")
+
+// ErrRuleExists 仓库内已存在相同 rule_id 的规则
+var ErrRuleExists = errors.New("rule already exists")
+
+// ruleIDRegex 规则 ID 格式，如 SEC-001、no_global_state
+var ruleIDRegex = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]{0,49}$`)
+
+// rulePromptHeader 注入系统提示词的规则说明
+const rulePromptHeader = `
+
+## 仓库审查规则
+以下是本仓库维护者定义的审查规则，仅适用于所列路径的文件。发现违反规则的代码时请报告问题，severity 和 category 使用规则指定的值，并在 rule_id 字段中填写规则编号；问题与规则无关时 rule_id 留空。
+`
+
+// RuleService 仓库审查规则管理服务
+type RuleService struct {
+	store  store.Store
+	logger *zap.Logger
+}
+
+// NewRuleService 创建 RuleService 实例
+func NewRuleService(store store.Store, logger *zap.Logger) *RuleService {
+	return &RuleService{
+		store:  store,
+		logger: logger,
+	}
+}
+
+// RuleRequest 创建或更新规则请求，更新时 rule_id 不可修改
+type RuleRequest struct {
+	RuleID      string   `json:"rule_id"`
+	Description string   `json:"description"`
+	Severity    string   `json:"severity"`
+	Category    string   `json:"category"`
+	Paths       []string `json:"paths"`
+	Examples    string   `json:"examples"`
+	Pattern     string   `json:"pattern"`   // 非空时为模式规则
+	Languages   []string `json:"languages"` // 模式规则适用的语言
+	Enabled     *bool    `json:"enabled"`
+}
+
+// ListRules 获取仓库的全部规则
+func (s *RuleService) ListRules(ctx context.Context, repoFullName string) ([]model.ReviewRule, error) {
+	return s.store.ListReviewRules(ctx, repoFullName)
+}
+
+// GetRule 获取规则详情
+func (s *RuleService) GetRule(ctx context.Context, id uint) (*model.ReviewRule, error) {
+	return s.store.GetReviewRule(ctx, id)
+}
+
+// CreateRule 为仓库创建规则，未指定 enabled 时默认启用
+func (s *RuleService) CreateRule(ctx context.Context, repoFullName string, req *RuleRequest) (*model.ReviewRule, error) {
+	rule := &model.ReviewRule{
+		RepoFullName: repoFullName,
+		RuleID:       strings.TrimSpace(req.RuleID),
+		Enabled:      true,
+	}
+	applyRuleRequest(rule, req)
+	if err := validateRule(rule); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.store.ListReviewRules(ctx, repoFullName)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range existing {
+		if strings.EqualFold(r.RuleID, rule.RuleID) {
+			return nil, ErrRuleExists
+		}
+	}
+
+	if err := s.store.CreateReviewRule(ctx, rule); err != nil {
+		s.logger.Error("Failed to create rule", zap.String("repo", repoFullName), zap.Error(err))
+		return nil, err
+	}
+
+	s.logger.Info("Review rule created", zap.String("repo", repoFullName), zap.String("rule_id", rule.RuleID))
+	return rule, nil
+}
+
+// UpdateRule 更新规则内容，rule_id 保持不变以免历史问题和统计失去关联
+func (s *RuleService) UpdateRule(ctx context.Context, id uint, req *RuleRequest) (*model.ReviewRule, error) {
+	rule, err := s.store.GetReviewRule(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if req.RuleID != "" && !strings.EqualFold(strings.TrimSpace(req.RuleID), rule.RuleID) {
+		return nil, fmt.Errorf("%w: rule_id cannot be changed", ErrInvalidRule)
+	}
+
+	applyRuleRequest(rule, req)
+	if err := validateRule(rule); err != nil {
+		return nil, err
+	}
+
+	if err := s.store.UpdateReviewRule(ctx, rule); err != nil {
+		s.logger.Error("Failed to update rule", zap.Uint("id", id), zap.Error(err))
+		return nil, err
+	}
+	return rule, nil
+}
+
+// DeleteRule 删除规则，已记录的命中和反馈保留
+func (s *RuleService) DeleteRule(ctx context.Context, id uint) error {
+	return s.store.DeleteReviewRule(ctx, id)
+}
+
+// GetRuleStats 获取仓库各规则的命中和误报统计，没有命中的规则也会列出
+func (s *RuleService) GetRuleStats(ctx context.Context, repoFullName string) ([]store.RuleStats, error) {
+	rules, err := s.store.ListReviewRules(ctx, repoFullName)
+	if err != nil {
+		return nil, err
+	}
+	stats, err := s.store.GetRuleStats(ctx, repoFullName)
+	if err != nil {
+		return nil, err
+	}
+
+	listed := make(map[string]bool, len(stats))
+	for _, st := range stats {
+		listed[st.RuleID] = true
+	}
+	for _, r := range rules {
+		if !listed[r.RuleID] {
+			stats = append(stats, store.RuleStats{RuleID: r.RuleID})
+		}
+	}
+	for i := range stats {
+		if stats[i].Feedbacks > 0 {
+			stats[i].FalsePositiveRate = float64(stats[i].FalsePositives) / float64(stats[i].Feedbacks)
+		}
+	}
+	return stats, nil
+}
+
+// applyRuleRequest 将请求中的字段写入规则
+func applyRuleRequest(rule *model.ReviewRule, req *RuleRequest) {
+	rule.Description = strings.TrimSpace(req.Description)
+	rule.Severity = strings.ToUpper(strings.TrimSpace(req.Severity))
+	rule.Category = strings.ToLower(strings.TrimSpace(req.Category))
+	rule.Examples = strings.TrimSpace(req.Examples)
+	rule.Pattern = strings.TrimSpace(req.Pattern)
+	rule.Paths = rule.Paths[:0]
+	for _, p := range req.Paths {
+		if p = strings.TrimSpace(p); p != "" {
+			rule.Paths = append(rule.Paths, p)
+		}
+	}
+	rule.Languages = rule.Languages[:0]
+	for _, l := range req.Languages {
+		if l = strings.ToLower(strings.TrimSpace(l)); l != "" {
+			rule.Languages = append(rule.Languages, l)
+		}
+	}
+	if req.Enabled != nil {
+		rule.Enabled = *req.Enabled
+	}
+}
+
+// validateRule 校验规则 ID 格式、严重程度、类别、路径 glob 和模式规则的正则
+func validateRule(rule *model.ReviewRule) error {
+	if !ruleIDRegex.MatchString(rule.RuleID) {
+		return fmt.Errorf("%w: rule_id must start with a letter and contain only letters, digits, - and _ (max 50)", ErrInvalidRule)
+	}
+	if rule.Description == "" {
+		return fmt.Errorf("%w: description is required", ErrInvalidRule)
+	}
+	if !containsString(validSeverities, rule.Severity) {
+		return fmt.Errorf("%w: severity must be one of %s", ErrInvalidRule, strings.Join(validSeverities, "/"))
+	}
+	if !containsString(validCategories, rule.Category) {
+		return fmt.Errorf("%w: category must be one of %s", ErrInvalidRule, strings.Join(validCategories, "/"))
+	}
+	for _, p := range rule.Paths {
+		if _, err := path.Match(strings.ReplaceAll(p, "**", "*"), ""); err != nil {
+			return fmt.Errorf("%w: invalid path glob %q", ErrInvalidRule, p)
+		}
+	}
+	if rule.Pattern != "" {
+		if _, err := regexp.Compile(rule.Pattern); err != nil {
+			return fmt.Errorf("%w: invalid pattern: %v", ErrInvalidRule, err)
+		}
+	}
+	return nil
+}
+
+// matchRules 返回适用于本次变更的已启用自然语言规则，最多 maxPromptRules 条；模式规则不注入提示词
+func matchRules(rules []model.ReviewRule, changes []diff.FileChange) []model.ReviewRule {
+	var matched []model.ReviewRule
+	for _, rule := range rules {
+		if !rule.Enabled || rule.Pattern != "" {
+			continue
+		}
+		for _, c := range changes {
+			if ruleApplies(rule, c.Filename) {
+				matched = append(matched, rule)
+				break
+			}
+		}
+		if len(matched) >= maxPromptRules {
+			break
+		}
+	}
+	return matched
+}
+
+// ruleApplies 文件是否在规则的适用路径内
+func ruleApplies(rule model.ReviewRule, file string) bool {
+	if len(rule.Paths) == 0 {
+		return true
+	}
+	for _, p := range rule.Paths {
+		if matchPathGlob(p, file) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchPathGlob 按路径段匹配 glob，** 匹配任意层目录；不含 / 的模式只匹配文件名，以 / 结尾的模式匹配目录下所有文件
+func matchPathGlob(pattern, file string) bool {
+	pattern = strings.TrimPrefix(pattern, "/")
+	if strings.HasSuffix(pattern, "/") {
+		pattern += "**"
+	}
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(file))
+		return ok
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(file, "/"))
+}
+
+func matchSegments(pattern, parts []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(parts); i++ {
+				if matchSegments(pattern[1:], parts[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(parts) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], parts[0]); !ok {
+			return false
+		}
+		pattern, parts = pattern[1:], parts[1:]
+	}
+	return len(parts) == 0
+}
+
+// ruleSection 加载仓库规则，按变更路径匹配后生成系统提示词片段
+func (s *AnalyzerService) ruleSection(ctx context.Context, run *reviewRun, changes []diff.FileChange) string {
+	rules, err := s.store.ListReviewRules(ctx, run.review.RepoFullName)
+	if err != nil {
+		s.logger.Warn("Failed to load review rules", zap.String("repo", run.review.RepoFullName), zap.Error(err))
+		return ""
+	}
+
+	run.rules = matchRules(rules, changes)
+	if len(run.rules) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString(rulePromptHeader)
+	for _, rule := range run.rules {
+		fmt.Fprintf(&sb, "\n### %s（%s / %s）\n%s\n", rule.RuleID, rule.Severity, rule.Category, run.redact(rule.Description))
+		if len(rule.Paths) > 0 {
+			fmt.Fprintf(&sb, "适用路径：%s\n", strings.Join(rule.Paths, ", "))
+		}
+		if rule.Examples != "" {
+			fmt.Fprintf(&sb, "示例：\n%s\n", run.redact(truncateRunes(rule.Examples, maxRuleExamplesChars)))
+		}
+	}
+	return sb.String()
+}
+
+// applyRules 校正问题引用的规则：未注入或文件不在适用路径内的 rule_id 清空，
+// 引用有效时使用规则指定的严重程度和类别
+func applyRules(issues []model.ReviewIssue, rules []model.ReviewRule) {
+	byID := make(map[string]model.ReviewRule, len(rules))
+	for _, r := range rules {
+		byID[strings.ToUpper(r.RuleID)] = r
+	}
+
+	for i := range issues {
+		issue := &issues[i]
+		if issue.RuleID == "" {
+			continue
+		}
+		rule, ok := byID[strings.ToUpper(strings.TrimSpace(issue.RuleID))]
+		if !ok || !ruleApplies(rule, issue.File) {
+			issue.RuleID = ""
+			continue
+		}
+		issue.RuleID = rule.RuleID
+		issue.Severity = rule.Severity
+		issue.Category = rule.Category
+	}
+}
+
+// ruleIDs 返回规则 ID 列表
+func ruleIDs(rules []model.ReviewRule) []string {
+	ids := make([]string, 0, len(rules))
+	for _, r := range rules {
+		ids = append(ids, r.RuleID)
+	}
+	return ids
+}
+
+// recordRuleHits 记录发布的问题对规则的引用，用于规则命中和误报统计
+func (s *AnalyzerService) recordRuleHits(ctx context.Context, review *model.Review, issues []model.ReviewIssue) {
+	var hits []model.RuleHit
+	for _, issue := range issues {
+		if issue.RuleID == "" {
+			continue
+		}
+		hits = append(hits, model.RuleHit{
+			RepoFullName: review.RepoFullName,
+			RuleID:       issue.RuleID,
+			ReviewID:     review.ID,
+			File:         issue.File,
+			Line:         issue.Line,
+			Severity:     issue.Severity,
+		})
+	}
+	if err := s.store.CreateRuleHits(ctx, hits); err != nil {
+		s.logger.Warn("Failed to record rule hits", zap.Uint("review_id", review.ID), zap.Error(err))
+	}
+}
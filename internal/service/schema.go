@@ -0,0 +1,198 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"code-sentinel/internal/model"
+)
+
+// maxRepairAttempts 输出校验失败时最多请求模型修复的次数
+const maxRepairAttempts = 1
+
+var (
+	validSeverities = []string{"P0", "P1", "P2"}
+	validCategories = []string{"security", "performance", "logic", "style"}
+)
+
+// reviewResponseFormat 审查结果的结构化输出格式，与 model.ReviewResult 保持一致
+// 统计信息由服务端根据 issues 计算，不要求模型输出；score 为 0-100 的整体评分
+var reviewResponseFormat = &model.ResponseFormat{
+	Type: model.ResponseFormatJSONSchema,
+	JSONSchema: &model.JSONSchema{
+		Name:   "review_result",
+		Strict: true,
+		Schema: map[string]any{
+			"type":                 "object",
+			"additionalProperties": false,
+			"required":             []string{"summary", "score", "issues"},
+			"properties": map[string]any{
+				"summary": map[string]any{"type": "string"},
+				"score":   map[string]any{"type": "integer", "minimum": 0, "maximum": 100},
+				"issues": map[string]any{
+					"type": "array",
+					"items": map[string]any{
+						"type":                 "object",
+						"additionalProperties": false,
+						"required":             []string{"severity", "category", "file", "line", "title", "description", "suggestion", "code_fix", "guideline", "rule_id"},
+						"properties": map[string]any{
+							"severity":    map[string]any{"type": "string", "enum": validSeverities},
+							"category":    map[string]any{"type": "string", "enum": validCategories},
+							"file":        map[string]any{"type": "string"},
+							"line":        map[string]any{"type": "integer"},
+							"title":       map[string]any{"type": "string"},
+							"description": map[string]any{"type": "string"},
+							"suggestion":  map[string]any{"type": "string"},
+							"code_fix":    map[string]any{"type": "string"},
+							"guideline":   map[string]any{"type": "string"},
+							"rule_id":     map[string]any{"type": "string"},
+						},
+					},
+				},
+			},
+		},
+	},
+}
+
+// rawReviewResult 用于校验的原始结构，指针字段用于区分缺失和零值
+type rawReviewResult struct {
+	Summary *string           `json:"summary"`
+	Issues  *[]rawReviewIssue `json:"issues"`
+	Score   int               `json:"score"`
+}
+
+type rawReviewIssue struct {
+	Severity    string `json:"severity"`
+	Category    string `json:"category"`
+	File        string `json:"file"`
+	Line        *int   `json:"line"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Suggestion  string `json:"suggestion"`
+	CodeFix     string `json:"code_fix"`
+	Guideline   string `json:"guideline"`
+	RuleID      string `json:"rule_id"`
+}
+
+// parseReviewOutput 解析并校验模型输出，返回结果和校验错误列表
+// 有校验错误时结果为 nil
+func parseReviewOutput(output string) (*model.ReviewResult, []string) {
+	cleaned := extractJSON(output)
+	if cleaned == "" {
+		return nil, []string{"output does not contain a JSON object"}
+	}
+
+	var raw rawReviewResult
+	if err := json.Unmarshal([]byte(cleaned), &raw); err != nil {
+		return nil, []string{fmt.Sprintf("invalid JSON: %v", err)}
+	}
+
+	var problems []string
+	if raw.Summary == nil || strings.TrimSpace(*raw.Summary) == "" {
+		problems = append(problems, "summary: required non-empty string")
+	}
+	if raw.Issues == nil {
+		problems = append(problems, "issues: required array (use [] when there are no issues)")
+	}
+	if raw.Score < 0 || raw.Score > 100 {
+		problems = append(problems, fmt.Sprintf("score: must be between 0 and 100, got %d", raw.Score))
+	}
+
+	var issues []rawReviewIssue
+	if raw.Issues != nil {
+		issues = *raw.Issues
+	}
+	for i, issue := range issues {
+		field := func(name string) string { return fmt.Sprintf("issues[%d].%s", i, name) }
+		if !containsString(validSeverities, issue.Severity) {
+			problems = append(problems, fmt.Sprintf("%s: must be one of %s, got %q", field("severity"), strings.Join(validSeverities, "/"), issue.Severity))
+		}
+		if !containsString(validCategories, issue.Category) {
+			problems = append(problems, fmt.Sprintf("%s: must be one of %s, got %q", field("category"), strings.Join(validCategories, "/"), issue.Category))
+		}
+		if strings.TrimSpace(issue.File) == "" {
+			problems = append(problems, field("file")+": required non-empty string")
+		}
+		if issue.Line == nil {
+			problems = append(problems, field("line")+": required integer")
+		} else if *issue.Line < 0 {
+			problems = append(problems, fmt.Sprintf("%s: must be >= 0, got %d", field("line"), *issue.Line))
+		}
+		if strings.TrimSpace(issue.Title) == "" {
+			problems = append(problems, field("title")+": required non-empty string")
+		}
+		if strings.TrimSpace(issue.Description) == "" {
+			problems = append(problems, field("description")+": required non-empty string")
+		}
+	}
+
+	if len(problems) > 0 {
+		return nil, problems
+	}
+
+	result := &model.ReviewResult{
+		Summary: *raw.Summary,
+		Issues:  make([]model.ReviewIssue, 0, len(issues)),
+		Score:   raw.Score,
+	}
+	for _, issue := range issues {
+		result.Issues = append(result.Issues, model.ReviewIssue{
+			Severity:    issue.Severity,
+			Category:    issue.Category,
+			File:        issue.File,
+			Line:        *issue.Line,
+			Title:       issue.Title,
+			Description: issue.Description,
+			Suggestion:  issue.Suggestion,
+			CodeFix:     issue.CodeFix,
+			Guideline:   strings.TrimSpace(issue.Guideline),
+			RuleID:      strings.TrimSpace(issue.RuleID),
+		})
+	}
+	result.Stats = computeStats(result.Issues)
+
+	return result, nil
+}
+
+// extractJSON 去掉 markdown 代码块等包裹，截取最外层 JSON 对象
+func extractJSON(output string) string {
+	start := strings.Index(output, "{")
+	end := strings.LastIndex(output, "}")
+	if start < 0 || end < start {
+		return ""
+	}
+	return output[start : end+1]
+}
+
+// repairPrompt 构建修复请求：把校验错误反馈给模型，要求重新输出完整 JSON
+func repairPrompt(problems []string) string {
+	return fmt.Sprintf(`你上一次的输出未通过格式校验，错误如下：
+- %s
+
+请修正以上问题，重新输出完整的审查结果 JSON。只输出 JSON，不要包含 markdown 代码块或任何其他内容。`, strings.Join(problems, "\n- "))
+}
+
+// degradedSummary 降级结果的摘要，未经校验的模型输出不发布到 PR 评论
+const degradedSummary = "模型原始输出未在评论中展示，已随审查记录保存。"
+
+// degradedResult 修复失败时的降级结果：原始输出只随审查记录存储，不报告任何问题
+func degradedResult(output string, problems []string) *model.ReviewResult {
+	return &model.ReviewResult{
+		Summary:          degradedSummary,
+		Issues:           []model.ReviewIssue{},
+		Degraded:         true,
+		ValidationErrors: problems,
+		RawOutput:        strings.TrimSpace(output),
+	}
+}
+
+// containsString 判断切片中是否包含指定字符串
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
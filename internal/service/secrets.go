@@ -0,0 +1,273 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"code-sentinel/internal/model"
+	"code-sentinel/pkg/diff"
+	"code-sentinel/pkg/secrets"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultSecretAllowlistFile 默认的密钥 allowlist 文件
+	defaultSecretAllowlistFile = ".code-sentinel/secrets-allowlist"
+	// secretStatusContext 密钥扫描的提交状态名称，可在分支保护中设为必需检查
+	secretStatusContext = "code-sentinel/secrets"
+	// secretPlaceholder 发送给模型的内容中密钥的替换文本
+	secretPlaceholder = "[REDACTED:secret]"
+	// secretCommentMarker 密钥扫描评论的标记，每次推送更新同一条评论
+	secretCommentMarker = "<!-- code-sentinel:secret-scan -->"
+)
+
+// secretLabels 密钥类型的展示名称
+var secretLabels = map[string]string{
+	secrets.TypeGitHubToken:       "GitHub Token",
+	secrets.TypeAWSAccessKey:      "AWS Access Key",
+	secrets.TypeAWSSecretKey:      "AWS Secret Key",
+	secrets.TypeGCPServiceAccount: "GCP 服务账号密钥",
+	secrets.TypeSlackToken:        "Slack Token",
+	secrets.TypeSlackWebhook:      "Slack Webhook",
+	secrets.TypePrivateKey:        "私钥",
+	secrets.TypeGeneric:           "疑似密钥",
+}
+
+// secretAllowlist 仓库的密钥 allowlist
+// 每行一条：指纹（评论中展示的 16 位十六进制）、path:<glob> 忽略匹配的文件、regex:<正则> 忽略匹配的密钥值，# 开头为注释
+type secretAllowlist struct {
+	fingerprints map[string]bool
+	paths        []string
+	patterns     []*regexp.Regexp
+}
+
+// parseSecretAllowlist 解析 allowlist，无法编译的正则被跳过
+func parseSecretAllowlist(content string) (*secretAllowlist, []string) {
+	allow := &secretAllowlist{fingerprints: make(map[string]bool)}
+	var invalid []string
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+		case strings.HasPrefix(line, "path:"):
+			allow.paths = append(allow.paths, strings.TrimSpace(strings.TrimPrefix(line, "path:")))
+		case strings.HasPrefix(line, "regex:"):
+			re, err := regexp.Compile(strings.TrimSpace(strings.TrimPrefix(line, "regex:")))
+			if err != nil {
+				invalid = append(invalid, line)
+				continue
+			}
+			allow.patterns = append(allow.patterns, re)
+		default:
+			allow.fingerprints[strings.ToLower(line)] = true
+		}
+	}
+	return allow, invalid
+}
+
+// allows 密钥是否在 allowlist 中
+func (a *secretAllowlist) allows(f secrets.Finding) bool {
+	if a == nil {
+		return false
+	}
+	if a.fingerprints[f.Fingerprint()] {
+		return true
+	}
+	for _, p := range a.paths {
+		if matchPathGlob(p, f.File) {
+			return true
+		}
+	}
+	for _, re := range a.patterns {
+		if re.MatchString(f.Secret) {
+			return true
+		}
+	}
+	return false
+}
+
+// loadSecretAllowlist 从目标分支读取 allowlist，PR 不能通过修改 allowlist 放行自己提交的密钥
+func (s *AnalyzerService) loadSecretAllowlist(ctx context.Context, run *reviewRun) *secretAllowlist {
+	file := run.config.SecretAllowlistFile
+	if file == "" {
+		file = defaultSecretAllowlistFile
+	}
+	ref := baseRef(run.event)
+	if run.review.RepoFullName == "" || ref == "" {
+		return nil
+	}
+	path, err := cleanRepoPath(file)
+	if err != nil || path == "" {
+		return nil
+	}
+
+	content, err := run.githubSvc.GetFileContent(ctx, run.review.RepoFullName, path, ref)
+	if err != nil {
+		// allowlist 文件不存在是常态
+		return nil
+	}
+	allow, invalid := parseSecretAllowlist(content)
+	if len(invalid) > 0 {
+		s.logger.Warn("Invalid secret allowlist entries skipped",
+			zap.String("repo", run.review.RepoFullName),
+			zap.Strings("entries", invalid),
+		)
+	}
+	return allow
+}
+
+// scanSecrets 扫描所有新增行（不受忽略文件和语言过滤影响）中的密钥，返回 P0 安全问题
+// 密钥原值（含 allowlist 放行的）记录在 run 中，之后发送给模型的 Diff、文件内容和工具输出都会遮盖；
+// allowlist 只免除 P0 问题
+func (s *AnalyzerService) scanSecrets(ctx context.Context, run *reviewRun, changes []diff.FileChange) []model.ReviewIssue {
+	if run.config.DisableSecretScan {
+		return nil
+	}
+	findings := secrets.Scan(changes)
+	if len(findings) == 0 {
+		return nil
+	}
+
+	allow := s.loadSecretAllowlist(ctx, run)
+	var issues []model.ReviewIssue
+	for _, f := range findings {
+		run.secrets = append(run.secrets, f.Values...)
+		if allow.allows(f) {
+			continue
+		}
+
+		label := secretLabels[f.Type]
+		issues = append(issues, model.ReviewIssue{
+			Severity:    "P0",
+			Category:    "security",
+			File:        f.File,
+			Line:        f.Line,
+			Title:       fmt.Sprintf("提交了%s", label),
+			Description: fmt.Sprintf("检测到%s `%s`（指纹 `%s`）。密钥推送到仓库后即应视为已泄露，删除提交也无法撤回。", label, f.Masked(), f.Fingerprint()),
+			Suggestion:  "立即吊销并轮换该凭据，改为从环境变量或密钥管理服务读取；确认为测试数据时，可将指纹加入目标分支的 allowlist 文件",
+			Source:      model.IssueSourceSecret,
+		})
+	}
+
+	if len(issues) > 0 {
+		s.logger.Warn("Secrets detected in diff",
+			zap.String("repo", run.review.RepoFullName),
+			zap.Int("pr_number", run.review.PRNumber),
+			zap.Int("count", len(issues)),
+		)
+	}
+	return issues
+}
+
+// maskSecrets 遮盖变更中的密钥，无论是否启用脱敏，密钥都不会发送给模型
+func maskSecrets(run *reviewRun, changes []diff.FileChange) []diff.FileChange {
+	return secrets.Mask(changes, run.secrets, secretPlaceholder)
+}
+
+// setSecretStatus 按密钥扫描结果设置提交状态，演练和对比模式不设置
+func (s *AnalyzerService) setSecretStatus(ctx context.Context, run *reviewRun, issues []model.ReviewIssue) {
+	sha := run.event.PullRequest.Head.SHA
+	if run.config.DisableSecretScan || run.dryRun || run.secondary || sha == "" {
+		return
+	}
+
+	state, description := "success", "No secrets detected"
+	if len(issues) > 0 {
+		state, description = "failure", fmt.Sprintf("%d secret(s) detected in added lines", len(issues))
+	}
+	if err := run.githubSvc.CreateCommitStatus(ctx, run.review.RepoFullName, sha, state, description, secretStatusContext); err != nil {
+		s.logger.Warn("Failed to set secret scan status",
+			zap.String("repo", run.review.RepoFullName),
+			zap.String("sha", sha),
+			zap.Error(err),
+		)
+	}
+}
+
+// secretGate 未启用自动审查时只执行密钥扫描：设置提交状态，发现密钥时发布评论，不创建审查记录
+// 每次推送更新同一条评论，密钥移除后评论改为已解决
+func (s *AnalyzerService) secretGate(ctx context.Context, event *model.PullRequestEvent, config *model.ReviewConfig) error {
+	if config.DisableSecretScan {
+		return nil
+	}
+	run := &reviewRun{
+		event: event,
+		review: &model.Review{
+			RepoFullName: event.Repository.FullName,
+			PRNumber:     event.Number,
+			CommitSHA:    event.PullRequest.Head.SHA,
+		},
+		config:    config,
+		githubSvc: s.getGitHubService(config),
+	}
+
+	diffContent, err := run.githubSvc.GetPRDiff(ctx, run.review.RepoFullName, run.review.PRNumber)
+	if err != nil {
+		return err
+	}
+	changes, err := diff.ParseDiff(diffContent)
+	if err != nil {
+		return err
+	}
+
+	issues := s.scanSecrets(ctx, run, changes)
+	s.setSecretStatus(ctx, run, issues)
+
+	repo, prNumber := run.review.RepoFullName, run.review.PRNumber
+	var existing *model.Comment
+	if comments, err := run.githubSvc.ListPRComments(ctx, repo, prNumber); err == nil {
+		for i := len(comments) - 1; i >= 0; i-- {
+			if strings.HasPrefix(comments[i].Body, secretCommentMarker) {
+				existing = &comments[i]
+				break
+			}
+		}
+	} else {
+		s.logger.Warn("Failed to list PR comments for secret scan",
+			zap.String("repo", repo),
+			zap.Int("pr_number", prNumber),
+			zap.Error(err),
+		)
+	}
+
+	body := formatSecretComment(issues)
+	switch {
+	case existing == nil && len(issues) == 0:
+		return nil
+	case existing == nil:
+		return run.githubSvc.CreatePRComment(ctx, repo, prNumber, body)
+	case existing.Body == body:
+		return nil
+	}
+	if err := run.githubSvc.UpdatePRComment(ctx, repo, existing.ID, body); err != nil {
+		// 带标记的评论可能不是本服务发布的，无法修改时发布新评论
+		if len(issues) == 0 {
+			return err
+		}
+		return run.githubSvc.CreatePRComment(ctx, repo, prNumber, body)
+	}
+	return nil
+}
+
+// formatSecretComment 只包含密钥扫描结果的评论，没有问题时为已解决的说明
+func formatSecretComment(issues []model.ReviewIssue) string {
+	var sb strings.Builder
+	sb.WriteString(secretCommentMarker + "\n")
+	sb.WriteString("## 🔐 Code-Sentinel 密钥扫描\n\n")
+	if len(issues) == 0 {
+		fmt.Fprintf(&sb, "✅ 最新提交的新增行中已不再检测到密钥，提交状态 `%s` 已恢复。此前推送的密钥仍应视为已泄露并完成轮换。\n", secretStatusContext)
+		return sb.String()
+	}
+	fmt.Fprintf(&sb, "本次变更的新增行中检测到 **%d** 处密钥，提交状态 `%s` 已标记为失败。\n\n", len(issues), secretStatusContext)
+	for _, issue := range issues {
+		fmt.Fprintf(&sb, "### 🔴 [P0] %s\n", issue.Title)
+		fmt.Fprintf(&sb, "**文件**：`%s:%d`\n", issue.File, issue.Line)
+		fmt.Fprintf(&sb, "**问题**：%s\n", issue.Description)
+		fmt.Fprintf(&sb, "**建议**：%s\n\n", issue.Suggestion)
+	}
+	sb.WriteString("---\n\n> 📚 Powered by [Code-Sentinel](https://github.com/code-sentinel)\n")
+	return sb.String()
+}
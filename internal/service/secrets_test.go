@@ -0,0 +1,66 @@
+package service
+
+import (
+	"testing"
+
+	"code-sentinel/pkg/secrets"
+)
+
+func TestParseSecretAllowlist(t *testing.T) {
+	content := `
+# 测试数据
+0123456789ABCDEF
+path: testdata/
+path:**/*_test.go
+regex: ^sk_test_
+regex:[unclosed
+`
+	allow, invalid := parseSecretAllowlist(content)
+	if !allow.fingerprints["0123456789abcdef"] || len(allow.fingerprints) != 1 {
+		t.Errorf("fingerprints = %v, want lowercased entry only", allow.fingerprints)
+	}
+	if len(allow.paths) != 2 || allow.paths[0] != "testdata/" || allow.paths[1] != "**/*_test.go" {
+		t.Errorf("paths = %q", allow.paths)
+	}
+	if len(allow.patterns) != 1 {
+		t.Errorf("got %d patterns, want 1", len(allow.patterns))
+	}
+	if len(invalid) != 1 || invalid[0] != "regex:[unclosed" {
+		t.Errorf("invalid = %q", invalid)
+	}
+}
+
+func TestSecretAllowlistAllows(t *testing.T) {
+	token := secrets.Finding{Type: secrets.TypeGitHubToken, File: "cmd/main.go", Secret: "ghp_" + "aB3dE5fG7hJ9kL1mN3pQ5rS7tU9vW1xY3z05"}
+	allow, _ := parseSecretAllowlist(token.Fingerprint() + `
+path: testdata/
+path: *_test.go
+regex: ^sk_test_
+`)
+
+	tests := []struct {
+		name    string
+		finding secrets.Finding
+		want    bool
+	}{
+		{"fingerprint", token, true},
+		{"same value other type", secrets.Finding{Type: secrets.TypeGeneric, File: token.File, Secret: token.Secret}, false},
+		{"directory path", secrets.Finding{Type: secrets.TypeGeneric, File: "testdata/keys/a.pem", Secret: "x"}, true},
+		{"base name glob", secrets.Finding{Type: secrets.TypeGeneric, File: "pkg/auth/client_test.go", Secret: "x"}, true},
+		{"regex", secrets.Finding{Type: secrets.TypeGeneric, File: "config.go", Secret: "sk_test_4eC39HqLyjWDarjtT1zdp7dc"}, true},
+		{"regex not anchored elsewhere", secrets.Finding{Type: secrets.TypeGeneric, File: "config.go", Secret: "sk_live_sk_test_4eC39HqL"}, false},
+		{"no match", secrets.Finding{Type: secrets.TypeAWSAccessKey, File: "config.go", Secret: "AKIA" + "Z7Q4M2X9V6B3N8K5"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := allow.allows(tt.finding); got != tt.want {
+				t.Errorf("allows = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	var empty *secretAllowlist
+	if empty.allows(token) {
+		t.Error("nil allowlist allows a finding")
+	}
+}
@@ -0,0 +1,301 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"code-sentinel/internal/model"
+	"code-sentinel/pkg/diff"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// maxVerifyIssues 单次审查最多复核的问题数
+	maxVerifyIssues = 20
+	// verifyContextLines 复核时附带的问题行上下文行数（上下各取）
+	verifyContextLines = 20
+)
+
+// 复核被否定问题的处理方式
+const (
+	VerifyActionDrop      = "drop"
+	VerifyActionDowngrade = "downgrade"
+)
+
+// verifySystemPrompt 复核提示词
+const verifySystemPrompt = `你是严谨的代码审查复核专家。另一位审查者在代码变更中报告了一个高严重程度问题，你的任务是独立判断该问题是否真实存在。
+
+## 判断标准
+- confirmed：结合给出的代码可以确认问题真实存在，且严重程度基本合理
+- refuted：问题不存在、基于错误的假设、已被周围代码处理，或明显夸大了严重程度
+- uncertain：仅凭给出的代码无法判断
+
+## 输出格式要求
+请严格按照以下 JSON 格式输出，不要添加任何额外内容：
+
+{
+  "verdict": "confirmed|refuted|uncertain",
+  "reasoning": "判断理由（1-3句话，引用具体代码）"
+}`
+
+// verifyResponseFormat 复核结论的结构化输出格式
+var verifyResponseFormat = &model.ResponseFormat{
+	Type: model.ResponseFormatJSONSchema,
+	JSONSchema: &model.JSONSchema{
+		Name:   "issue_verification",
+		Strict: true,
+		Schema: map[string]any{
+			"type":                 "object",
+			"additionalProperties": false,
+			"required":             []string{"verdict", "reasoning"},
+			"properties": map[string]any{
+				"verdict":   map[string]any{"type": "string", "enum": []string{model.VerdictConfirmed, model.VerdictRefuted, model.VerdictUncertain}},
+				"reasoning": map[string]any{"type": "string"},
+			},
+		},
+	},
+}
+
+// verifyIssues 对 P0/P1 问题进行二次复核，被否定的问题按配置丢弃或降为 P2
+// 复核失败或无法判断的问题保持不变，所有结论记录在 result.Verifications 中
+func (s *AnalyzerService) verifyIssues(ctx context.Context, run *reviewRun, result *model.ReviewResult, changes []diff.FileChange) {
+	config := run.config
+	if !config.VerifyEnabled || result.Degraded {
+		return
+	}
+
+	var candidates []int
+	for _, severity := range []string{"P0", "P1"} {
+		for i, issue := range result.Issues {
+			if issue.Severity == severity && len(candidates) < maxVerifyIssues {
+				candidates = append(candidates, i)
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return
+	}
+
+	verifySvc, err := s.endpointLLM(run, model.LLMEndpoint{Endpoint: config.VerifyEndpoint, Model: config.VerifyModel})
+	if err != nil {
+		// 复核端点配置有误时不复核，问题保持不变
+		s.logger.Warn("Invalid verify model endpoint, skipping verification",
+			zap.Uint("review_id", run.review.ID),
+			zap.Error(err),
+		)
+		return
+	}
+	s.publishStage(run, model.ReviewStageVerifying, fmt.Sprintf("%s, %d issues", verifySvc.GetModel(), len(candidates)))
+
+	concurrency := config.BatchConcurrency
+	if concurrency <= 0 {
+		concurrency = defaultBatchConcurrency
+	}
+
+	contexts := newFileContextCache(run)
+	verdicts := make([]model.IssueVerification, len(candidates))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	for n, idx := range candidates {
+		wg.Add(1)
+		go func(n int, issue model.ReviewIssue) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			verdicts[n] = s.verifyIssue(ctx, run, verifySvc, issue, contexts.snippet(ctx, issue, changes))
+		}(n, result.Issues[idx])
+	}
+	wg.Wait()
+
+	refuted := make(map[int]bool)
+	for n, idx := range candidates {
+		v := &verdicts[n]
+		v.Action = "kept"
+		if v.Verdict != model.VerdictRefuted {
+			continue
+		}
+		if config.VerifyAction == VerifyActionDowngrade {
+			v.Action = "downgraded"
+			result.Issues[idx].Severity = "P2"
+		} else {
+			v.Action = "dropped"
+			refuted[idx] = true
+		}
+	}
+
+	if len(refuted) > 0 {
+		kept := make([]model.ReviewIssue, 0, len(result.Issues)-len(refuted))
+		for i, issue := range result.Issues {
+			if !refuted[i] {
+				kept = append(kept, issue)
+			}
+		}
+		result.Issues = kept
+	}
+	result.Verifications = verdicts
+
+	s.logger.Info("High severity issues verified",
+		zap.Uint("review_id", run.review.ID),
+		zap.String("model", verifySvc.GetModel()),
+		zap.Int("verified", len(candidates)),
+		zap.Int("refuted", countVerdicts(verdicts, model.VerdictRefuted)),
+	)
+}
+
+// verifyIssue 调用模型复核单个问题
+func (s *AnalyzerService) verifyIssue(ctx context.Context, run *reviewRun, verifySvc *LLMService, issue model.ReviewIssue, snippet string) model.IssueVerification {
+	verification := model.IssueVerification{
+		File:     issue.File,
+		Line:     issue.Line,
+		Title:    issue.Title,
+		Severity: issue.Severity,
+		Verdict:  model.VerdictUncertain,
+		Model:    verifySvc.GetModel(),
+	}
+
+	userPrompt := fmt.Sprintf(`## 待复核问题
+- 严重程度：%s
+- 类别：%s
+- 位置：%s:%d
+- 标题：%s
+- 描述：%s
+
+## 相关代码
+%s
+
+请判断该问题是否真实存在，并按要求输出 JSON 格式的结论。`,
+		issue.Severity, issue.Category, issue.File, issue.Line, issue.Title, issue.Description, snippet)
+
+	output, _, err := s.chat(ctx, run, verifySvc, newMessages(verifySystemPrompt, userPrompt), verifyResponseFormat, false)
+	if err != nil {
+		s.logger.Warn("Failed to verify issue",
+			zap.String("file", issue.File),
+			zap.Int("line", issue.Line),
+			zap.Error(err),
+		)
+		verification.Error = err.Error()
+		return verification
+	}
+
+	var parsed struct {
+		Verdict   string `json:"verdict"`
+		Reasoning string `json:"reasoning"`
+	}
+	if err := json.Unmarshal([]byte(extractJSON(output)), &parsed); err != nil {
+		verification.Error = fmt.Sprintf("invalid verification output: %v", err)
+		return verification
+	}
+
+	switch parsed.Verdict {
+	case model.VerdictConfirmed, model.VerdictRefuted:
+		verification.Verdict = parsed.Verdict
+	}
+	verification.Reasoning = parsed.Reasoning
+	return verification
+}
+
+// fileContextCache 复核时按文件缓存 head 版本的文件内容
+// 每个文件只获取一次，不同文件的获取互不阻塞
+type fileContextCache struct {
+	run   *reviewRun
+	mu    sync.Mutex
+	files map[string]*fileContent
+}
+
+// fileContent 单个文件的获取结果，获取失败时 lines 为空
+type fileContent struct {
+	once  sync.Once
+	lines []string
+}
+
+func newFileContextCache(run *reviewRun) *fileContextCache {
+	return &fileContextCache{run: run, files: make(map[string]*fileContent)}
+}
+
+// snippet 返回问题行附近的代码（带行号）；获取文件失败时退化为 Diff 中附近的新增行
+func (c *fileContextCache) snippet(ctx context.Context, issue model.ReviewIssue, changes []diff.FileChange) string {
+	lines, ok := c.lines(ctx, issue.File)
+	if !ok {
+		return diffSnippet(issue, changes)
+	}
+
+	start, end := 1, len(lines)
+	if issue.Line > 0 {
+		start = max(1, issue.Line-verifyContextLines)
+		end = min(len(lines), issue.Line+verifyContextLines)
+	} else if end > verifyContextLines*2 {
+		end = verifyContextLines * 2
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "```\n// %s\n", issue.File)
+	for n := start; n <= end; n++ {
+		marker := "  "
+		if n == issue.Line {
+			marker = "> "
+		}
+		fmt.Fprintf(&sb, "%s%d: %s\n", marker, n, lines[n-1])
+	}
+	sb.WriteString("```")
+	return sb.String()
+}
+
+// lines 获取文件内容并按行切分，同一文件的并发请求等待同一次获取
+func (c *fileContextCache) lines(ctx context.Context, file string) ([]string, bool) {
+	c.mu.Lock()
+	entry, ok := c.files[file]
+	if !ok {
+		entry = &fileContent{}
+		c.files[file] = entry
+	}
+	c.mu.Unlock()
+
+	entry.once.Do(func() {
+		content, err := c.run.githubSvc.GetFileContent(ctx, c.run.review.RepoFullName, file, c.run.review.CommitSHA)
+		if err == nil {
+			entry.lines = strings.Split(c.run.redact(content), "\n")
+		}
+	})
+	return entry.lines, entry.lines != nil
+}
+
+// diffSnippet 从 Diff 中截取问题行附近的新增行
+func diffSnippet(issue model.ReviewIssue, changes []diff.FileChange) string {
+	for _, change := range changes {
+		if change.Filename != issue.File {
+			continue
+		}
+		var nearby []diff.Line
+		for _, line := range change.Additions {
+			if issue.Line == 0 || (line.Number >= issue.Line-verifyContextLines && line.Number <= issue.Line+verifyContextLines) {
+				nearby = append(nearby, line)
+			}
+		}
+		if len(nearby) == 0 {
+			break
+		}
+		return "（仅包含变更中新增的行）\n" + diff.FormatChangesForPrompt([]diff.FileChange{{
+			Filename:  change.Filename,
+			Language:  change.Language,
+			Additions: nearby,
+		}})
+	}
+	return "（无法获取相关代码）"
+}
+
+// countVerdicts 统计指定结论的数量
+func countVerdicts(verdicts []model.IssueVerification, verdict string) int {
+	n := 0
+	for _, v := range verdicts {
+		if v.Verdict == verdict {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,239 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"code-sentinel/internal/model"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// maxSampleCount 单次审查的最大采样次数
+	maxSampleCount = 7
+	// defaultSampleTemperature 模型温度为 0 且未配置采样温度时使用的温度
+	defaultSampleTemperature = 0.7
+	// titleSimilarityThreshold 行号或类别不同时，标题相似度达到该值才视为同一问题
+	titleSimilarityThreshold = 0.5
+)
+
+// sampleCount 返回配置的采样次数（已限制上限）
+func sampleCount(config *model.ReviewConfig) int {
+	return min(config.SampleCount, maxSampleCount)
+}
+
+// ErrAgentSampling 工具调用审查不支持自洽性投票
+var ErrAgentSampling = errors.New("agent_enabled cannot be combined with sample_count > 1")
+
+// ValidateReviewConfig 校验仓库配置中相互冲突的选项
+func ValidateReviewConfig(config *model.ReviewConfig) error {
+	if config.AgentEnabled && sampleCount(config) > 1 {
+		return ErrAgentSampling
+	}
+	return nil
+}
+
+// issueCluster 多次采样中被视为同一问题的一组报告
+type issueCluster struct {
+	members []model.ReviewIssue
+	samples map[int]bool
+}
+
+// sampleReview 以大于 0 的温度对同一提示词采样多次，按问题聚类投票
+// 只保留至少 MinVotes 次采样报告的问题，并在 ReviewIssue.Votes 中记录票数
+func (s *AnalyzerService) sampleReview(ctx context.Context, run *reviewRun, systemPrompt, userPrompt string) (*model.ReviewResult, error) {
+	config := run.config
+	k := sampleCount(config)
+
+	temperature := config.SampleTemperature
+	if temperature <= 0 {
+		temperature = run.llmSvc.GetTemperature()
+	}
+	if temperature <= 0 {
+		temperature = defaultSampleTemperature
+	}
+	sampler := run.llmSvc.WithTemperature(temperature)
+
+	s.publishStage(run, model.ReviewStageGenerating, fmt.Sprintf("%s, %d samples at temperature %.2f", sampler.GetModel(), k, temperature))
+
+	results := make([]*model.ReviewResult, k)
+	errs := make([]error, k)
+	var wg sync.WaitGroup
+	for i := 0; i < k; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// 并发采样时增量输出会交错，不推送 token 事件
+			results[i], errs[i] = s.generate(ctx, run, sampler, systemPrompt, userPrompt, i+1, false)
+		}(i)
+	}
+	wg.Wait()
+
+	var valid []*model.ReviewResult
+	var firstDegraded *model.ReviewResult
+	var lastErr error
+	for i, result := range results {
+		switch {
+		case errs[i] != nil:
+			lastErr = errs[i]
+		case result.Degraded:
+			if firstDegraded == nil {
+				firstDegraded = result
+			}
+		default:
+			valid = append(valid, result)
+		}
+	}
+
+	if len(valid) == 0 {
+		if firstDegraded != nil {
+			return firstDegraded, nil
+		}
+		return nil, fmt.Errorf("all %d samples failed: %w", k, lastErr)
+	}
+
+	// 门槛不随失败的采样降低：有效采样不足 minVotes 时任何问题都无法达到票数，结果标记为降级
+	minVotes := config.SampleMinVotes
+	if minVotes <= 0 {
+		minVotes = k/2 + 1
+	}
+	minVotes = min(minVotes, k)
+
+	merged := voteIssues(valid, minVotes)
+	if len(valid) < minVotes {
+		merged.Degraded = true
+		merged.Summary = fmt.Sprintf("%d 次采样中只有 %d 次成功，少于最少票数 %d，无法完成自洽性投票", k, len(valid), minVotes)
+		s.logger.Warn("Too few successful samples for self-consistency voting",
+			zap.Uint("review_id", run.review.ID),
+			zap.Int("samples", k),
+			zap.Int("valid_samples", len(valid)),
+			zap.Int("min_votes", minVotes),
+		)
+		return merged, nil
+	}
+
+	s.logger.Info("Self-consistency voting completed",
+		zap.Uint("review_id", run.review.ID),
+		zap.Int("samples", k),
+		zap.Int("valid_samples", len(valid)),
+		zap.Int("min_votes", minVotes),
+		zap.Int("issues", len(merged.Issues)),
+	)
+
+	return merged, nil
+}
+
+// voteIssues 按文件、行号邻近度和类别/标题相似度聚类各采样的问题，保留票数不低于 minVotes 的问题
+func voteIssues(samples []*model.ReviewResult, minVotes int) *model.ReviewResult {
+	var clusters []*issueCluster
+	for n, sample := range samples {
+		for _, issue := range sample.Issues {
+			cluster := findCluster(clusters, issue, n)
+			if cluster == nil {
+				cluster = &issueCluster{samples: make(map[int]bool)}
+				clusters = append(clusters, cluster)
+			}
+			cluster.members = append(cluster.members, issue)
+			cluster.samples[n] = true
+		}
+	}
+
+	issues := []model.ReviewIssue{}
+	for _, cluster := range clusters {
+		votes := len(cluster.samples)
+		if votes < minVotes {
+			continue
+		}
+		issue := cluster.representative()
+		issue.Votes = votes
+		issues = append(issues, issue)
+	}
+
+	// 票数高的问题排在前面
+	sort.SliceStable(issues, func(i, j int) bool {
+		return issues[i].Votes > issues[j].Votes
+	})
+
+	first := samples[0]
+	return &model.ReviewResult{
+		Summary:  first.Summary,
+		Issues:   issues,
+		Stats:    computeStats(issues),
+		Score:    first.Score,
+		Samples:  len(samples),
+		MinVotes: minVotes,
+	}
+}
+
+// findCluster 查找与问题等价的聚类，同一采样的多个问题不会并入同一聚类
+// 同一行同一类别直接视为同一问题；行号不同的邻近问题还需标题相似，避免同类的不同问题合并后共享票数
+func findCluster(clusters []*issueCluster, issue model.ReviewIssue, sample int) *issueCluster {
+	for _, cluster := range clusters {
+		if cluster.samples[sample] {
+			continue
+		}
+		anchor := cluster.members[0]
+		if anchor.File != issue.File {
+			continue
+		}
+		d := anchor.Line - issue.Line
+		if d < 0 {
+			d = -d
+		}
+		if (anchor.Line == 0) != (issue.Line == 0) || d > compareLineTolerance {
+			continue
+		}
+		if (d == 0 && anchor.Category == issue.Category) || titleSimilarity(anchor.Title, issue.Title) >= titleSimilarityThreshold {
+			return cluster
+		}
+	}
+	return nil
+}
+
+// representative 选取聚类中出现最多的严重程度对应的第一个问题，票数相同时取更严重的级别
+func (c *issueCluster) representative() model.ReviewIssue {
+	counts := make(map[string]int)
+	for _, m := range c.members {
+		counts[m.Severity]++
+	}
+
+	best := c.members[0]
+	for _, m := range c.members[1:] {
+		if counts[m.Severity] > counts[best.Severity] ||
+			(counts[m.Severity] == counts[best.Severity] && m.Severity < best.Severity) {
+			best = m
+		}
+	}
+	return best
+}
+
+// titleSimilarity 基于字符二元组的 Jaccard 相似度，同时适用于中英文标题
+func titleSimilarity(a, b string) float64 {
+	ga, gb := bigrams(a), bigrams(b)
+	if len(ga) == 0 || len(gb) == 0 {
+		return 0
+	}
+
+	shared := 0
+	for g := range ga {
+		if gb[g] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(ga)+len(gb)-shared)
+}
+
+// bigrams 返回去除空白、转小写后的字符二元组集合
+func bigrams(s string) map[string]bool {
+	runes := []rune(strings.ToLower(strings.Join(strings.Fields(s), "")))
+	set := make(map[string]bool)
+	for i := 0; i+1 < len(runes); i++ {
+		set[string(runes[i:i+2])] = true
+	}
+	return set
+}
@@ -5,11 +5,13 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"code-sentinel/internal/model"
 
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"gorm.io/gorm/logger"
 )
 
@@ -30,10 +32,17 @@ func NewSQLiteStore(dbPath string) (*SQLiteStore, error) {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
-	if err := db.AutoMigrate(&model.Repo{}, &model.Config{}, &model.Review{}, &model.Feedback{}); err != nil {
+	if err := db.AutoMigrate(&model.Repo{}, &model.Config{}, &model.Review{}, &model.Feedback{}, &model.LLMCache{}, &model.ReviewComparison{}, &model.ToolCallTrace{}, &model.ReviewRule{}, &model.RuleHit{}, &model.PromptVersion{}, &model.PromptExperiment{}, &model.Advisory{}); err != nil {
 		return nil, fmt.Errorf("failed to migrate database: %w", err)
 	}
 
+	// 旧版本 is_false_positive 默认值为 true，显式写入的 false 被替换，漏报反馈因此被记为误报
+	if err := db.Model(&model.Feedback{}).
+		Where("category = ? AND is_false_positive = ?", "missed", true).
+		Update("is_false_positive", false).Error; err != nil {
+		return nil, fmt.Errorf("failed to backfill missed feedbacks: %w", err)
+	}
+
 	return &SQLiteStore{db: db}, nil
 }
 
@@ -316,3 +325,413 @@ func (s *SQLiteStore) GetFeedbackStats(ctx context.Context, repoFullName string,
 
 	return stats, nil
 }
+
+// Cost methods
+
+func (s *SQLiteStore) GetCostStats(ctx context.Context, filter *CostFilter, groupBy CostGroupBy) ([]CostAggregate, error) {
+	query := s.db.WithContext(ctx).Model(&model.Review{})
+	if filter != nil {
+		if filter.RepoFullName != "" {
+			query = query.Where("repo_full_name = ?", filter.RepoFullName)
+		}
+		if filter.Model != "" {
+			query = query.Where("model = ?", filter.Model)
+		}
+		if filter.StartDate != "" {
+			query = query.Where("created_at >= ?", filter.StartDate+" 00:00:00")
+		}
+		if filter.EndDate != "" {
+			query = query.Where("created_at <= ?", filter.EndDate+" 23:59:59")
+		}
+	}
+
+	var keyExpr string
+	switch groupBy {
+	case CostGroupRepo:
+		keyExpr = "repo_full_name"
+	case CostGroupModel:
+		keyExpr = "model"
+	case CostGroupDay:
+		keyExpr = "substr(created_at, 1, 10)"
+	default:
+		keyExpr = "''"
+	}
+
+	query = query.Select(keyExpr + ` AS key,
+		COUNT(*) AS reviews,
+		COALESCE(SUM(prompt_tokens), 0) AS prompt_tokens,
+		COALESCE(SUM(completion_tokens), 0) AS completion_tokens,
+		COALESCE(SUM(token_used), 0) AS total_tokens,
+		COALESCE(SUM(cost), 0) AS cost`)
+	if groupBy != CostGroupNone {
+		query = query.Group(keyExpr)
+	}
+	if groupBy == CostGroupDay {
+		query = query.Order("key ASC")
+	} else {
+		query = query.Order("cost DESC")
+	}
+
+	var stats []CostAggregate
+	if err := query.Scan(&stats).Error; err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// Review Comparison methods
+
+func (s *SQLiteStore) CreateReviewComparison(ctx context.Context, comparison *model.ReviewComparison) error {
+	return s.db.WithContext(ctx).Create(comparison).Error
+}
+
+func (s *SQLiteStore) ListReviewComparisons(ctx context.Context, reviewID uint) ([]model.ReviewComparison, error) {
+	var comparisons []model.ReviewComparison
+	err := s.db.WithContext(ctx).
+		Where("review_id = ?", reviewID).
+		Order("is_primary DESC, id ASC").
+		Find(&comparisons).Error
+	return comparisons, err
+}
+
+func (s *SQLiteStore) GetComparisonModelStats(ctx context.Context, repoFullName string) ([]ComparisonModelStats, error) {
+	query := s.db.WithContext(ctx).
+		Table("review_comparisons").
+		Select(`review_comparisons.model AS model,
+			COUNT(*) AS runs,
+			SUM(CASE WHEN review_comparisons.status = 'failed' THEN 1 ELSE 0 END) AS failures,
+			COALESCE(AVG(CASE WHEN review_comparisons.status != 'failed' THEN review_comparisons.issue_count END), 0) AS avg_issues,
+			AVG(review_comparisons.duration_ms) AS avg_duration_ms,
+			AVG(review_comparisons.token_used) AS avg_tokens,
+			AVG(review_comparisons.cost) AS avg_cost,
+			COALESCE(SUM(review_comparisons.cost), 0) AS total_cost`).
+		Group("review_comparisons.model").
+		Order("runs DESC")
+
+	if repoFullName != "" {
+		query = query.
+			Joins("JOIN reviews ON reviews.id = review_comparisons.review_id").
+			Where("reviews.repo_full_name = ?", repoFullName)
+	}
+
+	var stats []ComparisonModelStats
+	if err := query.Scan(&stats).Error; err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// Tool Call Trace methods
+
+func (s *SQLiteStore) CreateToolCallTrace(ctx context.Context, trace *model.ToolCallTrace) error {
+	return s.db.WithContext(ctx).Create(trace).Error
+}
+
+func (s *SQLiteStore) ListToolCallTraces(ctx context.Context, reviewID uint) ([]model.ToolCallTrace, error) {
+	var traces []model.ToolCallTrace
+	err := s.db.WithContext(ctx).
+		Where("review_id = ?", reviewID).
+		Order("id ASC").
+		Find(&traces).Error
+	return traces, err
+}
+
+// Review Rule methods
+
+func (s *SQLiteStore) ListReviewRules(ctx context.Context, repoFullName string) ([]model.ReviewRule, error) {
+	var rules []model.ReviewRule
+	err := s.db.WithContext(ctx).
+		Where("repo_full_name = ?", repoFullName).
+		Order("rule_id ASC").
+		Find(&rules).Error
+	return rules, err
+}
+
+func (s *SQLiteStore) GetReviewRule(ctx context.Context, id uint) (*model.ReviewRule, error) {
+	var rule model.ReviewRule
+	if err := s.db.WithContext(ctx).First(&rule, id).Error; err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (s *SQLiteStore) CreateReviewRule(ctx context.Context, rule *model.ReviewRule) error {
+	return s.db.WithContext(ctx).Create(rule).Error
+}
+
+func (s *SQLiteStore) UpdateReviewRule(ctx context.Context, rule *model.ReviewRule) error {
+	return s.db.WithContext(ctx).Save(rule).Error
+}
+
+func (s *SQLiteStore) DeleteReviewRule(ctx context.Context, id uint) error {
+	return s.db.WithContext(ctx).Delete(&model.ReviewRule{}, id).Error
+}
+
+func (s *SQLiteStore) CreateRuleHits(ctx context.Context, hits []model.RuleHit) error {
+	if len(hits) == 0 {
+		return nil
+	}
+	return s.db.WithContext(ctx).Create(&hits).Error
+}
+
+func (s *SQLiteStore) GetRuleStats(ctx context.Context, repoFullName string) ([]RuleStats, error) {
+	var hits []RuleStats
+	err := s.db.WithContext(ctx).
+		Model(&model.RuleHit{}).
+		Select("rule_id, COUNT(*) AS hits, COUNT(DISTINCT review_id) AS reviews").
+		Where("repo_full_name = ?", repoFullName).
+		Group("rule_id").
+		Scan(&hits).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var feedbacks []RuleStats
+	err = s.db.WithContext(ctx).
+		Model(&model.Feedback{}).
+		Select("rule_id, COUNT(*) AS feedbacks, SUM(CASE WHEN is_false_positive THEN 1 ELSE 0 END) AS false_positives").
+		Where("repo_full_name = ? AND rule_id != ''", repoFullName).
+		Group("rule_id").
+		Scan(&feedbacks).Error
+	if err != nil {
+		return nil, err
+	}
+
+	index := make(map[string]int, len(hits))
+	for i, h := range hits {
+		index[h.RuleID] = i
+	}
+	stats := hits
+	for _, f := range feedbacks {
+		if i, ok := index[f.RuleID]; ok {
+			stats[i].Feedbacks = f.Feedbacks
+			stats[i].FalsePositives = f.FalsePositives
+			continue
+		}
+		stats = append(stats, f)
+	}
+	return stats, nil
+}
+
+// Prompt Version methods
+
+// CreatePromptVersion 创建提示词版本，版本号为同名模板的最大版本号加一
+func (s *SQLiteStore) CreatePromptVersion(ctx context.Context, version *model.PromptVersion) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var latest int
+		if err := tx.Model(&model.PromptVersion{}).
+			Where("name = ?", version.Name).
+			Select("COALESCE(MAX(version), 0)").
+			Scan(&latest).Error; err != nil {
+			return err
+		}
+		version.Version = latest + 1
+		return tx.Create(version).Error
+	})
+}
+
+func (s *SQLiteStore) GetPromptVersion(ctx context.Context, id uint) (*model.PromptVersion, error) {
+	var version model.PromptVersion
+	if err := s.db.WithContext(ctx).First(&version, id).Error; err != nil {
+		return nil, err
+	}
+	return &version, nil
+}
+
+func (s *SQLiteStore) ListPromptVersions(ctx context.Context, name string) ([]model.PromptVersion, error) {
+	query := s.db.WithContext(ctx).Order("name ASC, version DESC")
+	if name != "" {
+		query = query.Where("name = ?", name)
+	}
+	var versions []model.PromptVersion
+	err := query.Find(&versions).Error
+	return versions, err
+}
+
+// Prompt Experiment methods
+
+func (s *SQLiteStore) CreateExperiment(ctx context.Context, experiment *model.PromptExperiment) error {
+	return s.db.WithContext(ctx).Create(experiment).Error
+}
+
+func (s *SQLiteStore) GetExperiment(ctx context.Context, id uint) (*model.PromptExperiment, error) {
+	var experiment model.PromptExperiment
+	if err := s.db.WithContext(ctx).First(&experiment, id).Error; err != nil {
+		return nil, err
+	}
+	return &experiment, nil
+}
+
+func (s *SQLiteStore) UpdateExperiment(ctx context.Context, experiment *model.PromptExperiment) error {
+	return s.db.WithContext(ctx).Save(experiment).Error
+}
+
+func (s *SQLiteStore) ListExperiments(ctx context.Context, repoFullName string) ([]model.PromptExperiment, error) {
+	var experiments []model.PromptExperiment
+	err := s.db.WithContext(ctx).
+		Where("repo_full_name = ?", repoFullName).
+		Order("id DESC").
+		Find(&experiments).Error
+	return experiments, err
+}
+
+func (s *SQLiteStore) GetRunningExperiment(ctx context.Context, repoFullName string) (*model.PromptExperiment, error) {
+	var experiment model.PromptExperiment
+	if err := s.db.WithContext(ctx).
+		Where("repo_full_name = ? AND status = ?", repoFullName, model.ExperimentStatusRunning).
+		Order("id DESC").
+		First(&experiment).Error; err != nil {
+		return nil, err
+	}
+	return &experiment, nil
+}
+
+// GetExperimentStats 按提示词版本汇总实验中已完成审查的问题数、误报反馈、Token 和耗时
+func (s *SQLiteStore) GetExperimentStats(ctx context.Context, experimentID uint) ([]model.VariantStats, error) {
+	var stats []model.VariantStats
+	err := s.db.WithContext(ctx).
+		Model(&model.Review{}).
+		Select(`prompt_version_id,
+			COUNT(*) AS reviews,
+			COALESCE(SUM(issue_count), 0) AS issues,
+			AVG(token_used) AS avg_tokens,
+			AVG(duration_ms) AS avg_duration_ms,
+			AVG(cost) AS avg_cost`).
+		Where("experiment_id = ? AND status IN ?", experimentID, []model.ReviewStatus{model.ReviewStatusCompleted, model.ReviewStatusDegraded}).
+		Group("prompt_version_id").
+		Scan(&stats).Error
+	if err != nil {
+		return nil, err
+	}
+
+	type falsePositiveCount struct {
+		PromptVersionID uint
+		Count           int
+	}
+	var counts []falsePositiveCount
+	err = s.db.WithContext(ctx).
+		Table("feedbacks").
+		Select("reviews.prompt_version_id AS prompt_version_id, COUNT(*) AS count").
+		Joins("JOIN reviews ON reviews.id = feedbacks.review_id").
+		Where("reviews.experiment_id = ? AND feedbacks.is_false_positive = ?", experimentID, true).
+		Group("reviews.prompt_version_id").
+		Scan(&counts).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, c := range counts {
+		for i := range stats {
+			if stats[i].PromptVersionID == c.PromptVersionID {
+				stats[i].FalsePositives = c.Count
+			}
+		}
+	}
+	return stats, nil
+}
+
+// Advisory methods
+
+// UpsertAdvisories 批量写入公告，同一公告同一包已存在时覆盖
+func (s *SQLiteStore) UpsertAdvisories(ctx context.Context, advisories []model.Advisory) error {
+	if len(advisories) == 0 {
+		return nil
+	}
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "advisory_id"}, {Name: "ecosystem"}, {Name: "package"}},
+			DoUpdates: clause.AssignmentColumns([]string{"summary", "aliases", "severity", "ranges", "versions", "modified", "updated_at"}),
+		}).CreateInBatches(advisories, 200).Error
+	})
+}
+
+// DeleteAdvisories 删除撤回的公告
+func (s *SQLiteStore) DeleteAdvisories(ctx context.Context, advisoryIDs []string) error {
+	if len(advisoryIDs) == 0 {
+		return nil
+	}
+	return s.db.WithContext(ctx).Where("advisory_id IN ?", advisoryIDs).Delete(&model.Advisory{}).Error
+}
+
+func (s *SQLiteStore) FindAdvisories(ctx context.Context, ecosystem, pkg string) ([]model.Advisory, error) {
+	var advisories []model.Advisory
+	err := s.db.WithContext(ctx).
+		Where("ecosystem = ? AND package = ?", ecosystem, pkg).
+		Order("advisory_id ASC").
+		Find(&advisories).Error
+	return advisories, err
+}
+
+func (s *SQLiteStore) ListAdvisories(ctx context.Context, filter *AdvisoryFilter, page, pageSize int) ([]model.Advisory, int64, error) {
+	var advisories []model.Advisory
+	var total int64
+
+	query := s.db.WithContext(ctx).Model(&model.Advisory{})
+	if filter != nil {
+		if filter.Ecosystem != "" {
+			query = query.Where("ecosystem = ?", filter.Ecosystem)
+		}
+		if filter.Package != "" {
+			query = query.Where("package = ?", filter.Package)
+		}
+		if filter.Search != "" {
+			like := "%" + filter.Search + "%"
+			query = query.Where("advisory_id LIKE ? OR aliases LIKE ?", like, like)
+		}
+	}
+
+	query.Count(&total)
+
+	offset := (page - 1) * pageSize
+	if err := query.Order("modified DESC").Offset(offset).Limit(pageSize).Find(&advisories).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return advisories, total, nil
+}
+
+// LLM Cache methods
+
+func (s *SQLiteStore) GetLLMCache(ctx context.Context, key string) (*model.LLMCache, error) {
+	var entry model.LLMCache
+	if err := s.db.WithContext(ctx).Where("cache_key = ? AND expires_at > ?", key, time.Now()).First(&entry).Error; err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (s *SQLiteStore) SaveLLMCache(ctx context.Context, entry *model.LLMCache, maxEntries int) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 相同键覆盖旧条目（例如跳过缓存的手动重审）
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "cache_key"}},
+			DoUpdates: clause.AssignmentColumns([]string{"model", "response", "token_used", "prompt_tokens", "completion_tokens", "hit_count", "expires_at", "created_at"}),
+		}).Create(entry).Error; err != nil {
+			return err
+		}
+
+		// 清理过期条目
+		if err := tx.Where("expires_at <= ?", time.Now()).Delete(&model.LLMCache{}).Error; err != nil {
+			return err
+		}
+
+		// 超出容量时淘汰最早写入的条目
+		if maxEntries <= 0 {
+			return nil
+		}
+		var total int64
+		if err := tx.Model(&model.LLMCache{}).Count(&total).Error; err != nil {
+			return err
+		}
+		if overflow := int(total) - maxEntries; overflow > 0 {
+			oldest := tx.Model(&model.LLMCache{}).Select("id").Order("created_at ASC").Limit(overflow)
+			return tx.Where("id IN (?)", oldest).Delete(&model.LLMCache{}).Error
+		}
+		return nil
+	})
+}
+
+func (s *SQLiteStore) TouchLLMCache(ctx context.Context, id uint) error {
+	return s.db.WithContext(ctx).Model(&model.LLMCache{}).Where("id = ?", id).
+		UpdateColumn("hit_count", gorm.Expr("hit_count + 1")).Error
+}
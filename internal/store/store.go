@@ -15,6 +15,24 @@ type ReviewFilter struct {
 	EndDate      string // YYYY-MM-DD
 }
 
+// CostFilter 成本统计筛选条件
+type CostFilter struct {
+	RepoFullName string
+	Model        string
+	StartDate    string // YYYY-MM-DD
+	EndDate      string // YYYY-MM-DD
+}
+
+// CostGroupBy 成本聚合维度
+type CostGroupBy string
+
+const (
+	CostGroupNone  CostGroupBy = ""
+	CostGroupRepo  CostGroupBy = "repo"
+	CostGroupModel CostGroupBy = "model"
+	CostGroupDay   CostGroupBy = "day"
+)
+
 // FeedbackFilter 反馈列表筛选条件
 type FeedbackFilter struct {
 	RepoFullName string
@@ -24,6 +42,13 @@ type FeedbackFilter struct {
 	EndDate      string
 }
 
+// AdvisoryFilter 漏洞公告列表筛选条件
+type AdvisoryFilter struct {
+	Ecosystem string
+	Package   string
+	Search    string // 按公告 ID 或别名模糊匹配
+}
+
 type Store interface {
 	// Repo
 	CreateRepo(ctx context.Context, repo *model.Repo) error
@@ -51,6 +76,51 @@ type Store interface {
 	ListFeedbacks(ctx context.Context, filter *FeedbackFilter, page, pageSize int) ([]model.Feedback, int64, error)
 	GetFeedbackStats(ctx context.Context, repoFullName string, startDate, endDate string) (*FeedbackStats, error)
 
+	// Cost
+	GetCostStats(ctx context.Context, filter *CostFilter, groupBy CostGroupBy) ([]CostAggregate, error)
+
+	// Review Comparison
+	CreateReviewComparison(ctx context.Context, comparison *model.ReviewComparison) error
+	ListReviewComparisons(ctx context.Context, reviewID uint) ([]model.ReviewComparison, error)
+	GetComparisonModelStats(ctx context.Context, repoFullName string) ([]ComparisonModelStats, error)
+
+	// Tool Call Trace
+	CreateToolCallTrace(ctx context.Context, trace *model.ToolCallTrace) error
+	ListToolCallTraces(ctx context.Context, reviewID uint) ([]model.ToolCallTrace, error)
+
+	// Review Rule
+	ListReviewRules(ctx context.Context, repoFullName string) ([]model.ReviewRule, error)
+	GetReviewRule(ctx context.Context, id uint) (*model.ReviewRule, error)
+	CreateReviewRule(ctx context.Context, rule *model.ReviewRule) error
+	UpdateReviewRule(ctx context.Context, rule *model.ReviewRule) error
+	DeleteReviewRule(ctx context.Context, id uint) error
+	CreateRuleHits(ctx context.Context, hits []model.RuleHit) error
+	GetRuleStats(ctx context.Context, repoFullName string) ([]RuleStats, error)
+
+	// Prompt Version
+	CreatePromptVersion(ctx context.Context, version *model.PromptVersion) error
+	GetPromptVersion(ctx context.Context, id uint) (*model.PromptVersion, error)
+	ListPromptVersions(ctx context.Context, name string) ([]model.PromptVersion, error)
+
+	// Prompt Experiment
+	CreateExperiment(ctx context.Context, experiment *model.PromptExperiment) error
+	GetExperiment(ctx context.Context, id uint) (*model.PromptExperiment, error)
+	UpdateExperiment(ctx context.Context, experiment *model.PromptExperiment) error
+	ListExperiments(ctx context.Context, repoFullName string) ([]model.PromptExperiment, error)
+	GetRunningExperiment(ctx context.Context, repoFullName string) (*model.PromptExperiment, error)
+	GetExperimentStats(ctx context.Context, experimentID uint) ([]model.VariantStats, error)
+
+	// Advisory
+	UpsertAdvisories(ctx context.Context, advisories []model.Advisory) error
+	DeleteAdvisories(ctx context.Context, advisoryIDs []string) error
+	FindAdvisories(ctx context.Context, ecosystem, pkg string) ([]model.Advisory, error)
+	ListAdvisories(ctx context.Context, filter *AdvisoryFilter, page, pageSize int) ([]model.Advisory, int64, error)
+
+	// LLM Cache
+	GetLLMCache(ctx context.Context, key string) (*model.LLMCache, error)
+	SaveLLMCache(ctx context.Context, entry *model.LLMCache, maxEntries int) error
+	TouchLLMCache(ctx context.Context, id uint) error
+
 	// Health
 	Ping(ctx context.Context) error
 }
@@ -62,3 +132,36 @@ type FeedbackStats struct {
 	ByCategory        map[string]int `json:"by_category"`
 	BySeverity        map[string]int `json:"by_severity"`
 }
+
+// CostAggregate 成本聚合结果
+type CostAggregate struct {
+	Key              string  `json:"key"` // 仓库名/模型名/日期，汇总时为空
+	Reviews          int     `json:"reviews"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	TotalTokens      int64   `json:"total_tokens"`
+	Cost             float64 `json:"cost"`
+}
+
+// ComparisonModelStats 对比模式下各模型的汇总表现
+type ComparisonModelStats struct {
+	Model         string  `json:"model"`
+	Runs          int     `json:"runs"`
+	Failures      int     `json:"failures"`
+	AvgIssues     float64 `json:"avg_issues"`
+	AvgDurationMs float64 `json:"avg_duration_ms"`
+	AvgTokens     float64 `json:"avg_tokens"`
+	AvgCost       float64 `json:"avg_cost"`
+	TotalCost     float64 `json:"total_cost"`
+}
+
+// RuleStats 单条审查规则的命中和反馈统计
+type RuleStats struct {
+	RuleID         string `json:"rule_id"`
+	Hits           int    `json:"hits"`            // 发布的问题中引用该规则的次数
+	Reviews        int    `json:"reviews"`         // 命中该规则的审查数
+	Feedbacks      int    `json:"feedbacks"`       // 引用该规则的问题收到的反馈数
+	FalsePositives int    `json:"false_positives"` // 其中被标记为误报的数量
+
+	FalsePositiveRate float64 `json:"false_positive_rate"`
+}
@@ -0,0 +1,361 @@
+package deps
+
+import (
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"code-sentinel/pkg/diff"
+)
+
+// 生态名称，与 OSV 的 ecosystem 一致
+const (
+	EcosystemGo    = "Go"
+	EcosystemNPM   = "npm"
+	EcosystemPyPI  = "PyPI"
+	EcosystemMaven = "Maven"
+)
+
+// 依赖变更类型
+const (
+	KindAdded      = "added"
+	KindRemoved    = "removed"
+	KindUpgraded   = "upgraded"
+	KindDowngraded = "downgraded"
+	KindChanged    = "changed" // 版本约束变化但无法比较大小，如 ^1.2 → ~1.3
+)
+
+// Change 一个依赖的版本变更
+type Change struct {
+	Ecosystem  string `json:"ecosystem"`
+	Name       string `json:"name"`
+	File       string `json:"file"`
+	Line       int    `json:"line"` // 新版本所在的新文件行号，删除的依赖为 0
+	OldVersion string `json:"old_version,omitempty"`
+	NewVersion string `json:"new_version,omitempty"`
+	Kind       string `json:"kind"`
+	Direct     bool   `json:"direct"` // 清单中直接声明的依赖；锁文件条目和 go.mod 的 // indirect 为间接依赖
+}
+
+// diffLine 带操作符的 Diff 行：' ' 上下文、'+' 新增、'-' 删除
+type diffLine struct {
+	op      byte
+	text    string
+	newLine int // 新文件行号，删除行为下一新行的行号
+}
+
+// entry 单侧（旧或新）解析出的依赖版本
+type entry struct {
+	name    string
+	key     string // 区分同名依赖的键，如锁文件中的安装路径
+	version string
+	line    int
+	direct  bool
+}
+
+var (
+	goRequireRegex = regexp.MustCompile(`^\s*(?:require\s+)?([A-Za-z0-9._~/-]+\.[A-Za-z0-9._~/-]+)\s+(v[0-9][^\s]*)(\s*//\s*indirect)?`)
+	npmDepRegex    = regexp.MustCompile(`^\s*"([^"]+)"\s*:\s*"([^"]*)"`)
+	npmSpecRegex   = regexp.MustCompile(`^(?:[~^<>=*]|\d|x$|latest$|next$|(?:npm|git|git\+\w+|github|file|link|workspace):|https?://)`)
+	lockPathRegex  = regexp.MustCompile(`^\s*"((?:[^"]*/)?node_modules/((?:@[^/"]+/)?[^/"]+))"\s*:\s*\{`)
+	lockVerRegex   = regexp.MustCompile(`^\s*"version"\s*:\s*"([^"]+)"`)
+	pyReqRegex     = regexp.MustCompile(`^\s*([A-Za-z0-9][A-Za-z0-9._-]*)\s*(?:\[[^\]]*\])?\s*((?:===?|~=|!=|<=?|>=?)[^;#\s]*(?:\s*,\s*(?:===?|~=|!=|<=?|>=?)[^;#\s,]*)*)?\s*(?:[;#].*)?$`)
+	xmlTagRegex    = regexp.MustCompile(`<(groupId|artifactId|version)>\s*([^<]*?)\s*</(?:groupId|artifactId|version)>`)
+)
+
+// npmSections package.json 中声明依赖的字段
+var npmSections = []string{"dependencies", "devDependencies", "peerDependencies", "optionalDependencies"}
+
+// Parse 从文件变更中解析依赖清单和锁文件的版本变更
+// 支持 go.mod、package.json、package-lock.json、requirements*.txt 和 pom.xml
+func Parse(files []diff.FileChange) []Change {
+	var changes []Change
+	for _, file := range files {
+		if file.Status == diff.StatusDeleted || len(file.Hunks) == 0 {
+			continue
+		}
+		name, lines := file.Filename, diffLines(file.Hunks)
+		var old, new []entry
+		var ecosystem string
+		switch base := path.Base(name); {
+		case base == "go.mod":
+			ecosystem = EcosystemGo
+			old, new = parseGoMod(lines)
+		case base == "package.json":
+			ecosystem = EcosystemNPM
+			old, new = parsePackageJSON(lines)
+		case base == "package-lock.json":
+			ecosystem = EcosystemNPM
+			old, new = parsePackageLock(lines)
+		case strings.HasPrefix(base, "requirements") && strings.HasSuffix(base, ".txt"):
+			ecosystem = EcosystemPyPI
+			old, new = parseRequirements(lines)
+		case base == "pom.xml":
+			ecosystem = EcosystemMaven
+			old, new = parsePom(lines)
+		default:
+			continue
+		}
+		changes = append(changes, diffEntries(ecosystem, name, old, new)...)
+	}
+	return changes
+}
+
+// diffLines 展开各 hunk 的行，保留上下文行；删除行取下一新行的行号
+func diffLines(hunks []diff.Hunk) []diffLine {
+	var lines []diffLine
+	for _, h := range hunks {
+		newLine := h.NewStart
+		for _, l := range h.Lines {
+			switch l.Type {
+			case diff.LineAdd:
+				lines = append(lines, diffLine{op: '+', text: l.Content, newLine: l.NewNumber})
+				newLine = l.NewNumber + 1
+			case diff.LineDelete:
+				lines = append(lines, diffLine{op: '-', text: l.Content, newLine: newLine})
+			default:
+				lines = append(lines, diffLine{op: ' ', text: l.Content, newLine: l.NewNumber})
+				newLine = l.NewNumber + 1
+			}
+		}
+	}
+	return lines
+}
+
+// sides 将一行的解析结果计入旧侧和/或新侧：上下文行两侧都有
+func sides(op byte, e entry, old, new *[]entry) {
+	if op != '+' {
+		*old = append(*old, e)
+	}
+	if op != '-' {
+		*new = append(*new, e)
+	}
+}
+
+// parseGoMod 解析 require 指令，// indirect 标记为间接依赖
+func parseGoMod(lines []diffLine) (old, new []entry) {
+	for _, l := range lines {
+		text := strings.TrimSpace(l.text)
+		if strings.HasPrefix(text, "module ") || strings.HasPrefix(text, "replace ") || strings.HasPrefix(text, "exclude ") || strings.HasPrefix(text, "//") {
+			continue
+		}
+		m := goRequireRegex.FindStringSubmatch(l.text)
+		if m == nil {
+			continue
+		}
+		sides(l.op, entry{name: m[1], key: m[1], version: m[2], line: l.newLine, direct: m[3] == ""}, &old, &new)
+	}
+	return old, new
+}
+
+// parsePackageJSON 解析 dependencies 等字段中的依赖，通过上下文行追踪当前字段
+func parsePackageJSON(lines []diffLine) (old, new []entry) {
+	// 旧侧和新侧分别追踪所在字段，hunk 从依赖字段中间开始时按版本格式判断
+	section := map[byte]string{'-': "", '+': ""}
+	for _, l := range lines {
+		text := strings.TrimSpace(l.text)
+		for _, side := range []byte{'-', '+'} {
+			if l.op != ' ' && l.op != side {
+				continue
+			}
+			if strings.HasSuffix(text, "{") {
+				if m := npmDepRegex.FindStringSubmatch(strings.TrimSuffix(text, "{") + `""`); m != nil {
+					section[side] = m[1]
+				}
+			} else if strings.HasPrefix(text, "}") {
+				section[side] = ""
+			}
+		}
+
+		m := npmDepRegex.FindStringSubmatch(l.text)
+		if m == nil || strings.HasSuffix(text, "{") {
+			continue
+		}
+		name, spec := m[1], m[2]
+		cur := section['+']
+		if l.op == '-' {
+			cur = section['-']
+		}
+		if cur != "" && !containsString(npmSections, cur) {
+			continue
+		}
+		if cur == "" && (name == "name" || name == "version" || !npmSpecRegex.MatchString(spec)) {
+			continue
+		}
+		sides(l.op, entry{name: name, key: name, version: spec, line: l.newLine, direct: true}, &old, &new)
+	}
+	return old, new
+}
+
+// parsePackageLock 解析 lockfileVersion 2/3 的 packages 条目，条目名来自上下文中的 node_modules 路径
+func parsePackageLock(lines []diffLine) (old, new []entry) {
+	current := map[byte]string{'-': "", '+': ""}
+	names := map[string]string{}
+	for _, l := range lines {
+		if m := lockPathRegex.FindStringSubmatch(l.text); m != nil {
+			for _, side := range []byte{'-', '+'} {
+				if l.op == ' ' || l.op == side {
+					current[side] = m[1]
+				}
+			}
+			names[m[1]] = m[2]
+			continue
+		}
+		m := lockVerRegex.FindStringSubmatch(l.text)
+		if m == nil {
+			continue
+		}
+		key := current['+']
+		if l.op == '-' {
+			key = current['-']
+		}
+		if key == "" {
+			continue
+		}
+		sides(l.op, entry{name: names[key], key: key, version: m[1], line: l.newLine}, &old, &new)
+	}
+	return old, new
+}
+
+// parseRequirements 解析 requirements.txt，忽略选项行和 URL 依赖
+func parseRequirements(lines []diffLine) (old, new []entry) {
+	for _, l := range lines {
+		text := strings.TrimSpace(l.text)
+		if text == "" || strings.HasPrefix(text, "#") || strings.HasPrefix(text, "-") || strings.Contains(text, "://") {
+			continue
+		}
+		m := pyReqRegex.FindStringSubmatch(text)
+		if m == nil {
+			continue
+		}
+		name := NormalizeName(EcosystemPyPI, m[1])
+		sides(l.op, entry{name: name, key: name, version: strings.ReplaceAll(m[2], " ", ""), line: l.newLine, direct: true}, &old, &new)
+	}
+	return old, new
+}
+
+// parsePom 解析 <dependency> 中的 groupId、artifactId 和 version，版本可能在上下文行中
+func parsePom(lines []diffLine) (old, new []entry) {
+	type dep struct {
+		group, artifact, version string
+		line                     int
+		changed                  bool // 块内有新增或删除的行
+	}
+	cur := map[byte]*dep{}
+	flush := func(side byte) {
+		d := cur[side]
+		cur[side] = nil
+		if d == nil || !d.changed || d.artifact == "" {
+			return
+		}
+		name := d.artifact
+		if d.group != "" {
+			name = d.group + ":" + d.artifact
+		}
+		e := entry{name: name, key: name, version: d.version, line: d.line, direct: true}
+		if side == '-' {
+			old = append(old, e)
+		} else {
+			new = append(new, e)
+		}
+	}
+
+	for _, l := range lines {
+		text := strings.TrimSpace(l.text)
+		for _, side := range []byte{'-', '+'} {
+			if l.op != ' ' && l.op != side {
+				continue
+			}
+			switch {
+			case strings.HasPrefix(text, "<dependency>"):
+				cur[side] = &dep{line: l.newLine}
+			case strings.HasPrefix(text, "</dependency>"):
+				if l.op != ' ' && cur[side] != nil {
+					cur[side].changed = true
+				}
+				flush(side)
+			case cur[side] != nil:
+				d := cur[side]
+				if l.op != ' ' {
+					d.changed = true
+				}
+				for _, m := range xmlTagRegex.FindAllStringSubmatch(text, -1) {
+					switch m[1] {
+					case "groupId":
+						d.group = m[2]
+					case "artifactId":
+						d.artifact = m[2]
+					case "version":
+						d.version = m[2]
+						d.line = l.newLine
+					}
+				}
+			}
+		}
+	}
+	return old, new
+}
+
+// diffEntries 对比两侧的依赖，版本未变化的条目（上下文行）不报告
+func diffEntries(ecosystem, file string, old, new []entry) []Change {
+	oldByKey := make(map[string]entry, len(old))
+	for _, e := range old {
+		oldByKey[e.key] = e
+	}
+	newByKey := make(map[string]entry, len(new))
+	for _, e := range new {
+		newByKey[e.key] = e
+	}
+
+	var changes []Change
+	for _, n := range new {
+		if _, dup := newByKey[n.key]; !dup {
+			continue
+		}
+		delete(newByKey, n.key)
+		c := Change{Ecosystem: ecosystem, Name: n.name, File: file, Line: n.line, NewVersion: n.version, Direct: n.direct, Kind: KindAdded}
+		if o, ok := oldByKey[n.key]; ok {
+			if o.version == n.version {
+				continue
+			}
+			c.OldVersion = o.version
+			c.Direct = n.direct || o.direct
+			switch cmp := Compare(Concrete(o.version), Concrete(n.version)); {
+			case Concrete(o.version) == "" || Concrete(n.version) == "":
+				c.Kind = KindChanged
+			case cmp < 0:
+				c.Kind = KindUpgraded
+			case cmp > 0:
+				c.Kind = KindDowngraded
+			default:
+				c.Kind = KindChanged
+			}
+		}
+		changes = append(changes, c)
+	}
+
+	newKeys := make(map[string]bool, len(new))
+	for _, e := range new {
+		newKeys[e.key] = true
+	}
+	for _, o := range old {
+		if newKeys[o.key] {
+			continue
+		}
+		newKeys[o.key] = true
+		changes = append(changes, Change{Ecosystem: ecosystem, Name: o.name, File: file, OldVersion: o.version, Direct: o.direct, Kind: KindRemoved})
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].Direct && !changes[j].Direct })
+	return changes
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
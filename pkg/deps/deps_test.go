@@ -0,0 +1,177 @@
+package deps
+
+import (
+	"os"
+	"testing"
+
+	"code-sentinel/pkg/diff"
+)
+
+// loadChanges 解析 testdata 中由 git diff 生成的清单变更，按文件分组
+func loadChanges(t *testing.T, name string) map[string][]Change {
+	t.Helper()
+	data, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := diff.ParseDiff(string(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	byFile := make(map[string][]Change)
+	for _, c := range Parse(files) {
+		byFile[c.File] = append(byFile[c.File], c)
+	}
+	return byFile
+}
+
+func TestParse(t *testing.T) {
+	changes := loadChanges(t, "manifests.diff")
+
+	tests := []struct {
+		name string
+		file string
+		want []Change
+	}{
+		{
+			name: "go.mod require block with indirect and pseudo-version",
+			file: "go.mod",
+			want: []Change{
+				{Ecosystem: EcosystemGo, Name: "github.com/google/uuid", File: "go.mod", Line: 7, OldVersion: "v1.3.0", NewVersion: "v1.3.1", Kind: KindUpgraded, Direct: true},
+				{Ecosystem: EcosystemGo, Name: "github.com/redis/go-redis/v9", File: "go.mod", Line: 11, NewVersion: "v9.2.1", Kind: KindAdded, Direct: true},
+				{Ecosystem: EcosystemGo, Name: "golang.org/x/net", File: "go.mod", Line: 8, OldVersion: "v0.17.0", NewVersion: "v0.0.0-20231010170000-abcdef012345", Kind: KindDowngraded},
+			},
+		},
+		{
+			// 第二个 hunk 从 devDependencies 中间开始，engines 字段不是依赖
+			name: "package.json sections across hunks",
+			file: "web/package.json",
+			want: []Change{
+				{Ecosystem: EcosystemNPM, Name: "lodash", File: "web/package.json", Line: 13, OldVersion: "^4.17.20", NewVersion: "^4.17.21", Kind: KindUpgraded, Direct: true},
+				{Ecosystem: EcosystemNPM, Name: "vite", File: "web/package.json", Line: 24, OldVersion: "^4.4.9", NewVersion: "^4.5.0", Kind: KindUpgraded, Direct: true},
+				{Ecosystem: EcosystemNPM, Name: "vitest", File: "web/package.json", Line: 25, OldVersion: "^0.34.3", NewVersion: "^0.34.1", Kind: KindDowngraded, Direct: true},
+				{Ecosystem: EcosystemNPM, Name: "react-router-dom", File: "web/package.json", OldVersion: "^6.15.0", Kind: KindRemoved, Direct: true},
+			},
+		},
+		{
+			// 同名包按 node_modules 路径区分，未变化的 node_modules/semver 不报告
+			name: "package-lock.json nested paths",
+			file: "web/package-lock.json",
+			want: []Change{
+				{Ecosystem: EcosystemNPM, Name: "lodash", File: "web/package-lock.json", Line: 17, OldVersion: "4.17.20", NewVersion: "4.17.21", Kind: KindUpgraded},
+				{Ecosystem: EcosystemNPM, Name: "semver", File: "web/package-lock.json", Line: 25, OldVersion: "6.3.0", NewVersion: "6.3.1", Kind: KindUpgraded},
+			},
+		},
+		{
+			name: "requirements.txt normalized names",
+			file: "svc/requirements.txt",
+			want: []Change{
+				{Ecosystem: EcosystemPyPI, Name: "django", File: "svc/requirements.txt", Line: 2, OldVersion: "==4.2.5", NewVersion: "==4.2.7", Kind: KindUpgraded, Direct: true},
+				{Ecosystem: EcosystemPyPI, Name: "pyyaml", File: "svc/requirements.txt", Line: 4, OldVersion: "==6.0", NewVersion: "==6.0.1", Kind: KindUpgraded, Direct: true},
+				{Ecosystem: EcosystemPyPI, Name: "requests-oauthlib", File: "svc/requirements.txt", Line: 5, NewVersion: "~=1.3", Kind: KindAdded, Direct: true},
+				{Ecosystem: EcosystemPyPI, Name: "urllib3", File: "svc/requirements.txt", OldVersion: "==1.26.16", Kind: KindRemoved, Direct: true},
+			},
+		},
+		{
+			// groupId 和 artifactId 只出现在上下文行，未修改的 junit 块不报告
+			name: "pom.xml blocks spanning context lines",
+			file: "svc/pom.xml",
+			want: []Change{
+				{Ecosystem: EcosystemMaven, Name: "org.springframework:spring-core", File: "svc/pom.xml", Line: 10, OldVersion: "5.3.20", NewVersion: "5.3.27", Kind: KindUpgraded, Direct: true},
+				{Ecosystem: EcosystemMaven, Name: "com.fasterxml.jackson.core:jackson-databind", File: "svc/pom.xml", Line: 15, OldVersion: "2.15.2", NewVersion: "2.16.0-rc1", Kind: KindUpgraded, Direct: true},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := changes[tt.file]
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d changes, want %d: %+v", len(got), len(tt.want), got)
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Errorf("change %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.2.3", "1.2.3", 0},
+		{"v1.2.3", "1.2.3", 0},
+		{"1.0", "1.0.0", 0},
+		{"1.0.0+build.5", "1.0.0", 0},
+		{"1.9.0", "1.10.0", -1},
+		{"2.0.0", "1.99.99", 1},
+		// Go 伪版本：v0.0.0- 前缀低于任何正式版本，vX.Y.(Z+1)-0. 前缀介于 vX.Y.Z 和 vX.Y.(Z+1) 之间
+		{"v0.0.0-20231010170000-abcdef012345", "v0.17.0", -1},
+		{"v1.2.4-0.20231010170000-abcdef012345", "v1.2.3", 1},
+		{"v1.2.4-0.20231010170000-abcdef012345", "v1.2.4", -1},
+		{"v0.0.0-20231010170000-abcdef012345", "v0.0.0-20240101000000-abcdef012345", -1},
+		// SemVer 预发布
+		{"1.0.0-rc.1", "1.0.0", -1},
+		{"1.0.0-alpha", "1.0.0-alpha.1", -1},
+		{"1.0.0-alpha.1", "1.0.0-beta", -1},
+		{"1.0.0-beta.2", "1.0.0-beta.11", -1},
+		{"1.0.0-1", "1.0.0-alpha", -1},
+		{"2.16.0-rc1", "2.15.2", 1},
+		// PyPI 预发布
+		{"1.0rc1", "1.0", -1},
+		{"2.0b2", "2.0rc1", -1},
+		{"2.0a1", "2.0b1", -1},
+	}
+	for _, tt := range tests {
+		if got := Compare(tt.a, tt.b); got != tt.want {
+			t.Errorf("Compare(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+		if got := Compare(tt.b, tt.a); got != -tt.want {
+			t.Errorf("Compare(%q, %q) = %d, want %d", tt.b, tt.a, got, -tt.want)
+		}
+	}
+}
+
+func TestAffected(t *testing.T) {
+	semver := func(events ...OSVEvent) []OSVRange {
+		return []OSVRange{{Type: "SEMVER", Events: events}}
+	}
+
+	tests := []struct {
+		name     string
+		version  string
+		ranges   []OSVRange
+		versions []string
+		want     bool
+	}{
+		{"before fixed", "1.2.2", semver(OSVEvent{Introduced: "0"}, OSVEvent{Fixed: "1.2.3"}), nil, true},
+		{"at fixed", "1.2.3", semver(OSVEvent{Introduced: "0"}, OSVEvent{Fixed: "1.2.3"}), nil, false},
+		{"after fixed", "v1.3.0", semver(OSVEvent{Introduced: "0"}, OSVEvent{Fixed: "1.2.3"}), nil, false},
+		{"before introduced", "0.9.0", semver(OSVEvent{Introduced: "1.0.0"}, OSVEvent{Fixed: "1.0.5"}), nil, false},
+		{"first of two intervals", "1.0.4", semver(OSVEvent{Introduced: "1.0.0"}, OSVEvent{Fixed: "1.0.5"}, OSVEvent{Introduced: "2.0.0"}, OSVEvent{Fixed: "2.0.1"}), nil, true},
+		{"between intervals", "1.5.0", semver(OSVEvent{Introduced: "1.0.0"}, OSVEvent{Fixed: "1.0.5"}, OSVEvent{Introduced: "2.0.0"}, OSVEvent{Fixed: "2.0.1"}), nil, false},
+		{"second of two intervals", "2.0.0", semver(OSVEvent{Introduced: "1.0.0"}, OSVEvent{Fixed: "1.0.5"}, OSVEvent{Introduced: "2.0.0"}, OSVEvent{Fixed: "2.0.1"}), nil, true},
+		{"at last_affected", "1.4.0", semver(OSVEvent{Introduced: "0"}, OSVEvent{LastAffected: "1.4.0"}), nil, true},
+		{"after last_affected", "1.4.1", semver(OSVEvent{Introduced: "0"}, OSVEvent{LastAffected: "1.4.0"}), nil, false},
+		{"at limit", "3.0.0", semver(OSVEvent{Introduced: "2.0.0"}, OSVEvent{Limit: "3.0.0"}), nil, false},
+		{"open ended", "9.9.9", semver(OSVEvent{Introduced: "2.0.0"}), nil, true},
+		{"prerelease before fix", "2.0.0-rc.1", semver(OSVEvent{Introduced: "0"}, OSVEvent{Fixed: "2.0.0"}), nil, true},
+		{"prerelease fix", "2.0.0-rc.2", semver(OSVEvent{Introduced: "0"}, OSVEvent{Fixed: "2.0.0-rc.2"}), nil, false},
+		{"pseudo-version before fix", "v0.0.0-20231010170000-abcdef012345", semver(OSVEvent{Introduced: "0"}, OSVEvent{Fixed: "0.17.0"}), nil, true},
+		{"ecosystem range", "2.2rc1", []OSVRange{{Type: "ECOSYSTEM", Events: []OSVEvent{{Introduced: "0"}, {Fixed: "2.2"}}}}, nil, true},
+		{"explicit versions", "v1.1.0", nil, []string{"1.0.0", "1.1.0"}, true},
+		{"not in explicit versions", "1.1.1", nil, []string{"1.0.0", "1.1.0"}, false},
+		{"git range ignored", "1.0.0", []OSVRange{{Type: "GIT", Events: []OSVEvent{{Introduced: "0"}}}}, nil, false},
+		{"empty version", "", semver(OSVEvent{Introduced: "0"}), nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Affected(tt.version, tt.ranges, tt.versions); got != tt.want {
+				t.Errorf("Affected(%q) = %v, want %v", tt.version, got, tt.want)
+			}
+		})
+	}
+}
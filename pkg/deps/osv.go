@@ -0,0 +1,185 @@
+package deps
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// OSVEntry OSV 格式的漏洞公告，只保留匹配版本所需的字段
+// 格式说明：https://ossf.github.io/osv-schema/
+type OSVEntry struct {
+	ID               string          `json:"id"`
+	Summary          string          `json:"summary"`
+	Details          string          `json:"details"`
+	Aliases          []string        `json:"aliases"`
+	Modified         string          `json:"modified"`
+	Withdrawn        string          `json:"withdrawn"`
+	Affected         []OSVAffected   `json:"affected"`
+	DatabaseSpecific json.RawMessage `json:"database_specific"`
+}
+
+// OSVAffected 受影响的包及版本
+type OSVAffected struct {
+	Package struct {
+		Ecosystem string `json:"ecosystem"`
+		Name      string `json:"name"`
+	} `json:"package"`
+	Ranges   []OSVRange `json:"ranges"`
+	Versions []string   `json:"versions"`
+}
+
+// OSVRange 受影响的版本区间
+type OSVRange struct {
+	Type   string     `json:"type"` // SEMVER / ECOSYSTEM / GIT
+	Events []OSVEvent `json:"events"`
+}
+
+// OSVEvent 区间事件，每个事件只有一个字段有值
+type OSVEvent struct {
+	Introduced   string `json:"introduced,omitempty"`
+	Fixed        string `json:"fixed,omitempty"`
+	LastAffected string `json:"last_affected,omitempty"`
+	Limit        string `json:"limit,omitempty"`
+}
+
+// ParseOSV 解析 OSV 数据：单个公告、公告数组，或 OSV 按生态导出的 zip（每个文件一个公告）
+func ParseOSV(data []byte) ([]OSVEntry, error) {
+	if bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		return parseOSVZip(data)
+	}
+
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		var entries []OSVEntry
+		if err := json.Unmarshal(trimmed, &entries); err != nil {
+			return nil, fmt.Errorf("invalid OSV array: %w", err)
+		}
+		return entries, nil
+	}
+
+	var entry OSVEntry
+	if err := json.Unmarshal(trimmed, &entry); err != nil {
+		return nil, fmt.Errorf("invalid OSV entry: %w", err)
+	}
+	return []OSVEntry{entry}, nil
+}
+
+func parseOSVZip(data []byte) ([]OSVEntry, error) {
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid zip: %w", err)
+	}
+
+	var entries []OSVEntry
+	for _, f := range reader.File {
+		if f.FileInfo().IsDir() || !strings.HasSuffix(f.Name, ".json") {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		content, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		var entry OSVEntry
+		if err := json.Unmarshal(content, &entry); err != nil {
+			return nil, fmt.Errorf("invalid OSV entry %s: %w", f.Name, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// Severity 公告的严重程度（GitHub 公告的 database_specific.severity），没有时返回空
+func (e OSVEntry) Severity() string {
+	var specific struct {
+		Severity string `json:"severity"`
+	}
+	if len(e.DatabaseSpecific) > 0 && json.Unmarshal(e.DatabaseSpecific, &specific) == nil {
+		return strings.ToUpper(specific.Severity)
+	}
+	return ""
+}
+
+// Affected 版本是否受影响：在显式版本列表中，或落在任一 SEMVER/ECOSYSTEM 区间内
+// GIT 区间按提交标识，无法与版本号比较，忽略
+func Affected(version string, ranges []OSVRange, versions []string) bool {
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	if version == "" {
+		return false
+	}
+	for _, v := range versions {
+		if strings.TrimPrefix(v, "v") == version {
+			return true
+		}
+	}
+
+	for _, r := range ranges {
+		if r.Type == "GIT" {
+			continue
+		}
+		// 事件按版本顺序排列，introduced 开启区间，fixed/last_affected 关闭区间
+		affected := false
+		for _, e := range r.Events {
+			switch {
+			case e.Introduced != "":
+				if e.Introduced == "0" || Compare(version, e.Introduced) >= 0 {
+					affected = true
+				}
+			case e.Fixed != "":
+				if affected && Compare(version, e.Fixed) >= 0 {
+					affected = false
+				}
+			case e.LastAffected != "":
+				if affected && Compare(version, e.LastAffected) > 0 {
+					affected = false
+				}
+			case e.Limit != "":
+				if affected && Compare(version, e.Limit) >= 0 {
+					affected = false
+				}
+			}
+		}
+		if affected {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeName 统一包名写法：PyPI 不区分大小写且 _ . - 等价，其他生态保持原样
+func NormalizeName(ecosystem, name string) string {
+	name = strings.TrimSpace(name)
+	if ecosystem == EcosystemPyPI {
+		name = strings.ToLower(name)
+		name = strings.NewReplacer("_", "-", ".", "-").Replace(name)
+	}
+	return name
+}
+
+// FixedVersion 返回修复该版本漏洞的最低版本，区间中没有更高的 fixed 事件时返回空
+func FixedVersion(version string, ranges []OSVRange) string {
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	fixed := ""
+	for _, r := range ranges {
+		if r.Type == "GIT" {
+			continue
+		}
+		for _, e := range r.Events {
+			if e.Fixed == "" || Compare(e.Fixed, version) <= 0 {
+				continue
+			}
+			if fixed == "" || Compare(e.Fixed, fixed) < 0 {
+				fixed = e.Fixed
+			}
+		}
+	}
+	return fixed
+}
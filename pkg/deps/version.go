@@ -0,0 +1,148 @@
+package deps
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// concreteRegex 版本约束中的第一个具体版本号
+	concreteRegex = regexp.MustCompile(`\d+(?:\.[0-9A-Za-z]+)*(?:[-+][0-9A-Za-z.-]+)?`)
+	versionPart   = regexp.MustCompile(`\d+|[A-Za-z]+`)
+	// pyPrerelease PyPI 风格的预发布后缀，如 1.0rc1、1.0b2
+	pyPrerelease = regexp.MustCompile(`\d(a|b|rc|dev|alpha|beta)\d*$`)
+)
+
+// Concrete 从版本约束中取出具体版本，如 ^1.2.3 → 1.2.3、>=2.0,<3 → 2.0；无法确定时返回空
+func Concrete(spec string) string {
+	spec = strings.TrimSpace(spec)
+	if spec == "" || strings.Contains(spec, "://") || strings.Contains(spec, "${") {
+		return ""
+	}
+	return concreteRegex.FindString(spec)
+}
+
+// Compare 比较两个版本号：数字段按数值比较，带预发布后缀的版本小于同号正式版
+func Compare(a, b string) int {
+	a, b = strings.TrimPrefix(a, "v"), strings.TrimPrefix(b, "v")
+	aMain, aPre := splitPrerelease(a)
+	bMain, bPre := splitPrerelease(b)
+
+	if c := compareParts(versionPart.FindAllString(aMain, -1), versionPart.FindAllString(bMain, -1), true); c != 0 {
+		return c
+	}
+	switch {
+	case aPre == bPre:
+		return 0
+	case aPre == "":
+		return 1
+	case bPre == "":
+		return -1
+	}
+	return compareParts(versionPart.FindAllString(aPre, -1), versionPart.FindAllString(bPre, -1), false)
+}
+
+// Major 主版本号；0.x 版本的次版本号变化同样视为不兼容，返回 "0.次版本号"
+func Major(version string) string {
+	parts := versionPart.FindAllString(strings.TrimPrefix(version, "v"), 2)
+	if len(parts) == 0 {
+		return ""
+	}
+	if parts[0] == "0" && len(parts) > 1 {
+		return "0." + parts[1]
+	}
+	return parts[0]
+}
+
+// IsUnpinned 版本约束是否没有上界，如 *、latest、>=1.0 或不带版本的 Python 包
+// npm 的 ^、~ 和 PyPI 的 ~= 限定了兼容范围，不视为未固定
+func IsUnpinned(ecosystem, spec string) bool {
+	spec = strings.ToLower(strings.TrimSpace(spec))
+	switch ecosystem {
+	case EcosystemNPM:
+		switch {
+		case spec == "" || spec == "*" || spec == "x" || spec == "latest" || spec == "next":
+			return true
+		case strings.Contains(spec, "://") || strings.HasPrefix(spec, "git") || strings.HasPrefix(spec, "github:"):
+			// Git 依赖未指定提交或标签时跟随默认分支
+			return !strings.Contains(spec, "#")
+		}
+		return strings.HasPrefix(spec, ">") && !strings.Contains(spec, "<")
+	case EcosystemPyPI:
+		return !strings.Contains(spec, "==") && !strings.Contains(spec, "~=") && !strings.Contains(spec, "<")
+	case EcosystemMaven:
+		// 属性引用的版本在父 POM 或 properties 中确定，这里无法判断
+		if spec == "" || strings.Contains(spec, "${") {
+			return false
+		}
+		return spec == "latest" || spec == "release" || strings.HasSuffix(spec, "-snapshot") ||
+			strings.HasSuffix(spec, ",)") || strings.HasSuffix(spec, ",]")
+	}
+	return false
+}
+
+// splitPrerelease 拆分正式版本号和预发布后缀，构建元数据（+ 之后）忽略
+func splitPrerelease(v string) (string, string) {
+	if i := strings.IndexByte(v, '+'); i >= 0 {
+		v = v[:i]
+	}
+	if i := strings.IndexByte(v, '-'); i >= 0 {
+		return v[:i], v[i+1:]
+	}
+	if loc := pyPrerelease.FindStringIndex(v); loc != nil {
+		return v[:loc[0]+1], v[loc[0]+1:]
+	}
+	return v, ""
+}
+
+// compareParts 逐段比较；numberHigh 为 true 时数字段大于字母段（如 1.0.1 > 1.0.Final），
+// 预发布后缀按 SemVer 规则数字段小于字母段（如 1.0.0-1 < 1.0.0-alpha）
+func compareParts(a, b []string, numberHigh bool) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var x, y string
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if x == y {
+			continue
+		}
+		xn, xErr := strconv.Atoi(x)
+		yn, yErr := strconv.Atoi(y)
+		switch {
+		case x == "":
+			if yErr == nil && yn == 0 {
+				continue
+			}
+			return -1
+		case y == "":
+			if xErr == nil && xn == 0 {
+				continue
+			}
+			return 1
+		case xErr == nil && yErr == nil:
+			if xn != yn {
+				if xn < yn {
+					return -1
+				}
+				return 1
+			}
+		case xErr == nil:
+			if numberHigh {
+				return 1
+			}
+			return -1
+		case yErr == nil:
+			if numberHigh {
+				return -1
+			}
+			return 1
+		default:
+			return strings.Compare(x, y)
+		}
+	}
+	return 0
+}
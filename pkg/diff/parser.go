@@ -1,37 +1,68 @@
 package diff
 
 import (
+	"fmt"
 	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
 )
 
+// 文件变更状态，与 GitHub API 的 status 一致
+const (
+	StatusAdded    = "added"
+	StatusModified = "modified"
+	StatusDeleted  = "deleted"
+	StatusRenamed  = "renamed"
+	StatusCopied   = "copied"
+)
+
+// FileChange 单个文件的变更
 type FileChange struct {
-	Filename  string
+	Filename  string // 新路径，删除的文件为旧路径
 	Language  string
-	OldPath   string
-	NewPath   string
-	Additions []Line
-	Deletions []Line
+	OldPath   string // 新增的文件为空
+	NewPath   string // 删除的文件为空
+	Status    string
+	Binary    bool   // 二进制文件没有 hunk
+	Additions []Line // 所有 hunk 中的新增行
+	Deletions []Line // 所有 hunk 中的删除行
 	Hunks     []Hunk
 }
 
+// LineType Diff 行类型
+type LineType string
+
+const (
+	LineContext LineType = "context"
+	LineAdd     LineType = "add"
+	LineDelete  LineType = "delete"
+)
+
+// Line Diff 中的一行
 type Line struct {
-	Number  int
-	Content string
+	Number    int // 新增和上下文行为新文件行号，删除行为旧文件行号
+	Content   string
+	Type      LineType
+	OldNumber int // 旧文件行号，新增行为 0
+	NewNumber int // 新文件行号，删除行为 0
+	Position  int // 在该文件 Diff 中的位置，即 GitHub 行内评论的 position：第一个 @@ 的下一行为 1，后续 @@ 行同样计数
 }
 
+// Hunk 一段连续的变更
 type Hunk struct {
 	OldStart int
 	OldLines int
 	NewStart int
 	NewLines int
-	Content  string
+	Section  string // @@ 之后的上下文，通常是所在函数的签名
+	Position int    // @@ 行的位置，第一个 hunk 为 0
+	Lines    []Line
 }
 
-var hunkHeaderRegex = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+var hunkHeaderRegex = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@ ?(.*)$`)
 
+// ParseDiff 解析 git diff 输出（统一格式），每个 diff --git 段落对应一个 FileChange
 func ParseDiff(diffContent string) ([]FileChange, error) {
 	var changes []FileChange
 
@@ -51,6 +82,72 @@ func ParseDiff(diffContent string) ([]FileChange, error) {
 	return changes, nil
 }
 
+// PositionOf 返回新文件行号在 Diff 中的位置，不在任何 hunk 中（或为删除行）时返回 0
+func (c *FileChange) PositionOf(newLine int) int {
+	for _, h := range c.Hunks {
+		if newLine < h.NewStart || newLine >= h.NewStart+h.NewLines {
+			continue
+		}
+		for _, l := range h.Lines {
+			if l.Type != LineDelete && l.NewNumber == newLine {
+				return l.Position
+			}
+		}
+	}
+	return 0
+}
+
+// String 按统一 Diff 格式输出 hunk
+func (h Hunk) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "@@ -%d,%d +%d,%d @@", h.OldStart, h.OldLines, h.NewStart, h.NewLines)
+	if h.Section != "" {
+		sb.WriteString(" ")
+		sb.WriteString(h.Section)
+	}
+	sb.WriteString("\n")
+	for _, l := range h.Lines {
+		switch l.Type {
+		case LineAdd:
+			sb.WriteString("+")
+		case LineDelete:
+			sb.WriteString("-")
+		default:
+			sb.WriteString(" ")
+		}
+		sb.WriteString(l.Content)
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// MapLines 返回用 fn 改写各 hunk 中的行后的副本，Additions 和 Deletions 由改写后的 hunk 重新生成
+// fn 需返回等长的切片；没有 hunk 的变更（如手工构造的）直接改写 Additions 和 Deletions
+func (c FileChange) MapLines(fn func([]Line) []Line) FileChange {
+	if len(c.Hunks) == 0 {
+		c.Additions = fn(c.Additions)
+		c.Deletions = fn(c.Deletions)
+		return c
+	}
+
+	hunks := make([]Hunk, len(c.Hunks))
+	c.Additions, c.Deletions = nil, nil
+	for i, h := range c.Hunks {
+		h.Lines = fn(h.Lines)
+		hunks[i] = h
+		for _, l := range h.Lines {
+			switch l.Type {
+			case LineAdd:
+				c.Additions = append(c.Additions, l)
+			case LineDelete:
+				c.Deletions = append(c.Deletions, l)
+			}
+		}
+	}
+	c.Hunks = hunks
+	return c
+}
+
 func splitByFile(diffContent string) []string {
 	var files []string
 	var current strings.Builder
@@ -74,59 +171,196 @@ func splitByFile(diffContent string) []string {
 	return files
 }
 
+// parseFileDiff 解析单个文件的 Diff：先读扩展头（路径、模式、重命名），再按 @@ 中的行数读取 hunk 内容
+// hunk 内容按行数而不是前缀判断结束位置，因此以 "--- " 或 "+++ " 开头的删除、新增行不会被误认为文件头
 func parseFileDiff(fileDiff string) FileChange {
-	change := FileChange{}
-	lines := strings.Split(fileDiff, "\n")
+	change := FileChange{Status: StatusModified}
+	lines := strings.Split(strings.TrimRight(fileDiff, "\n"), "\n")
 
-	var inHunk bool
-	var currentHunk strings.Builder
-	var hunkNewLine int
+	var hunk *Hunk
+	oldLeft, newLeft := 0, 0
+	oldLine, newLine := 0, 0
+	position := -1 // 第一个 @@ 行为 0
+
+	flush := func() {
+		if hunk == nil {
+			return
+		}
+		change.Hunks = append(change.Hunks, *hunk)
+		hunk = nil
+	}
 
 	for _, line := range lines {
-		if strings.HasPrefix(line, "--- a/") {
-			change.OldPath = strings.TrimPrefix(line, "--- a/")
-		} else if strings.HasPrefix(line, "+++ b/") {
-			change.NewPath = strings.TrimPrefix(line, "+++ b/")
-			change.Filename = change.NewPath
-			change.Language = detectLanguage(change.Filename)
-		} else if strings.HasPrefix(line, "@@") {
-			if inHunk && currentHunk.Len() > 0 {
-				// Save previous hunk
+		if hunk != nil && (oldLeft > 0 || newLeft > 0) {
+			position++
+			l := Line{Position: position}
+			switch {
+			case strings.HasPrefix(line, "+"):
+				l.Type, l.NewNumber, l.Number = LineAdd, newLine, newLine
+				newLine++
+				newLeft--
+			case strings.HasPrefix(line, "-"):
+				l.Type, l.OldNumber, l.Number = LineDelete, oldLine, oldLine
+				oldLine++
+				oldLeft--
+			case strings.HasPrefix(line, "\\"):
+				// "\ No newline at end of file" 不是文件内容，但占用一个位置
+				continue
+			default:
+				// 部分工具会去掉空上下文行的前导空格
+				l.Type, l.OldNumber, l.NewNumber, l.Number = LineContext, oldLine, newLine, newLine
+				oldLine++
+				newLine++
+				oldLeft--
+				newLeft--
+			}
+			if line != "" {
+				l.Content = line[1:]
 			}
-			inHunk = true
-			currentHunk.Reset()
+			hunk.Lines = append(hunk.Lines, l)
+			switch l.Type {
+			case LineAdd:
+				change.Additions = append(change.Additions, l)
+			case LineDelete:
+				change.Deletions = append(change.Deletions, l)
+			}
+			continue
+		}
 
-			matches := hunkHeaderRegex.FindStringSubmatch(line)
-			if len(matches) >= 4 {
-				newStart, _ := strconv.Atoi(matches[3])
-				hunkNewLine = newStart
+		switch {
+		case strings.HasPrefix(line, "@@"):
+			m := hunkHeaderRegex.FindStringSubmatch(line)
+			if m == nil {
+				continue
 			}
-			currentHunk.WriteString(line)
-			currentHunk.WriteString("\n")
-		} else if inHunk {
-			currentHunk.WriteString(line)
-			currentHunk.WriteString("\n")
-
-			if strings.HasPrefix(line, "+") && !strings.HasPrefix(line, "+++") {
-				change.Additions = append(change.Additions, Line{
-					Number:  hunkNewLine,
-					Content: strings.TrimPrefix(line, "+"),
-				})
-				hunkNewLine++
-			} else if strings.HasPrefix(line, "-") && !strings.HasPrefix(line, "---") {
-				change.Deletions = append(change.Deletions, Line{
-					Number:  hunkNewLine,
-					Content: strings.TrimPrefix(line, "-"),
-				})
-			} else if !strings.HasPrefix(line, "\\") {
-				hunkNewLine++
+			flush()
+			position++
+			hunk = &Hunk{
+				OldStart: atoi(m[1]),
+				OldLines: atoiDefault(m[2], 1),
+				NewStart: atoi(m[3]),
+				NewLines: atoiDefault(m[4], 1),
+				Section:  m[5],
+				Position: position,
 			}
+			oldLeft, newLeft = hunk.OldLines, hunk.NewLines
+			oldLine, newLine = hunk.OldStart, hunk.NewStart
+		case hunk != nil && strings.HasPrefix(line, "\\"):
+			// 最后一行之后的 "\ No newline at end of file"
+			position++
+		case strings.HasPrefix(line, "diff --git "):
+			change.OldPath, change.NewPath = parseGitHeader(strings.TrimPrefix(line, "diff --git "))
+		case strings.HasPrefix(line, "--- "):
+			change.OldPath = parsePath(strings.TrimPrefix(line, "--- "), "a/")
+		case strings.HasPrefix(line, "+++ "):
+			change.NewPath = parsePath(strings.TrimPrefix(line, "+++ "), "b/")
+		case strings.HasPrefix(line, "new file mode"):
+			change.Status = StatusAdded
+		case strings.HasPrefix(line, "deleted file mode"):
+			change.Status = StatusDeleted
+		case strings.HasPrefix(line, "rename from "):
+			change.Status = StatusRenamed
+			change.OldPath = unquote(strings.TrimPrefix(line, "rename from "))
+		case strings.HasPrefix(line, "rename to "):
+			change.NewPath = unquote(strings.TrimPrefix(line, "rename to "))
+		case strings.HasPrefix(line, "copy from "):
+			change.Status = StatusCopied
+			change.OldPath = unquote(strings.TrimPrefix(line, "copy from "))
+		case strings.HasPrefix(line, "copy to "):
+			change.NewPath = unquote(strings.TrimPrefix(line, "copy to "))
+		case strings.HasPrefix(line, "Binary files ") || line == "GIT binary patch":
+			change.Binary = true
 		}
 	}
+	flush()
 
+	switch change.Status {
+	case StatusAdded:
+		change.OldPath = ""
+	case StatusDeleted:
+		change.NewPath = ""
+	}
+	change.Filename = change.NewPath
+	if change.Filename == "" {
+		change.Filename = change.OldPath
+	}
+	change.Language = detectLanguage(change.Filename)
 	return change
 }
 
+// parseGitHeader 从 diff --git 行解析新旧路径，没有 ---/+++ 行的变更（纯重命名、模式变化、二进制文件）只能依靠该行
+func parseGitHeader(rest string) (string, string) {
+	if strings.HasPrefix(rest, `"`) {
+		// 含特殊字符的路径被引号包围：diff --git "a/..." "b/..."
+		if end := closingQuote(rest); end > 0 {
+			old := unquote(rest[:end+1])
+			return strings.TrimPrefix(old, "a/"), parsePath(strings.TrimSpace(rest[end+1:]), "b/")
+		}
+	}
+	// 未重命名时两个路径相同，可以准确拆分含空格的路径
+	if n := len(rest); n%2 == 1 && rest[n/2] == ' ' && strings.HasPrefix(rest, "a/") && rest[2:n/2] == rest[n/2+3:] {
+		return rest[2 : n/2], rest[n/2+3:]
+	}
+	if i := strings.LastIndex(rest, " b/"); i > 0 {
+		return strings.TrimPrefix(rest[:i], "a/"), rest[i+3:]
+	}
+	if i := strings.LastIndex(rest, ` "b/`); i > 0 {
+		return strings.TrimPrefix(rest[:i], "a/"), parsePath(rest[i+1:], "b/")
+	}
+	return "", ""
+}
+
+// parsePath 解析 ---/+++ 行中的路径：/dev/null 返回空，去掉引号、前缀和 git 为含空格路径追加的制表符
+func parsePath(p, prefix string) string {
+	p = strings.TrimSuffix(p, "\t")
+	if i := strings.IndexByte(p, '\t'); i >= 0 && !strings.HasPrefix(p, `"`) {
+		// GNU diff 在路径后附带时间戳
+		p = p[:i]
+	}
+	p = unquote(p)
+	if p == "/dev/null" {
+		return ""
+	}
+	return strings.TrimPrefix(p, prefix)
+}
+
+// unquote 还原 git 用 C 风格转义并加引号的路径（含非 ASCII 字符、引号或反斜杠时）
+func unquote(p string) string {
+	if len(p) < 2 || p[0] != '"' || p[len(p)-1] != '"' {
+		return p
+	}
+	if s, err := strconv.Unquote(p); err == nil {
+		return s
+	}
+	return p[1 : len(p)-1]
+}
+
+// closingQuote 返回以引号开头的字符串中配对引号的下标
+func closingQuote(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+// atoiDefault @@ 中省略行数时表示 1 行
+func atoiDefault(s string, def int) int {
+	if s == "" {
+		return def
+	}
+	return atoi(s)
+}
+
 func detectLanguage(filename string) string {
 	ext := strings.ToLower(filepath.Ext(filename))
 	switch ext {
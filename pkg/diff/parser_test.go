@@ -0,0 +1,361 @@
+package diff
+
+import (
+	"os"
+	"testing"
+)
+
+// loadFixture 读取 testdata 中由 git diff 生成的样例
+func loadFixture(t *testing.T, name string) map[string]FileChange {
+	t.Helper()
+	data, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes, err := ParseDiff(string(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	byName := make(map[string]FileChange, len(changes))
+	for _, c := range changes {
+		byName[c.Filename] = c
+	}
+	return byName
+}
+
+func TestParseDiffFiles(t *testing.T) {
+	changes := loadFixture(t, "git.diff")
+	if len(changes) != 10 {
+		t.Fatalf("got %d files, want 10", len(changes))
+	}
+
+	tests := []struct {
+		filename  string
+		oldPath   string
+		newPath   string
+		status    string
+		binary    bool
+		language  string
+		additions int
+		deletions int
+		hunks     int
+	}{
+		{"added.txt", "", "added.txt", StatusAdded, false, "unknown", 1, 0, 1},
+		{"edited2.txt", "edited.txt", "edited2.txt", StatusRenamed, false, "unknown", 1, 1, 1},
+		{"gone.txt", "gone.txt", "", StatusDeleted, false, "unknown", 0, 1, 1},
+		{"héllo.txt", "", "héllo.txt", StatusAdded, false, "unknown", 1, 0, 1},
+		{"logo.png", "logo.png", "logo.png", StatusModified, true, "unknown", 0, 0, 0},
+		{"main.go", "main.go", "main.go", StatusModified, false, "go", 3, 2, 2},
+		{"my file.txt", "my file.txt", "my file.txt", StatusModified, false, "unknown", 1, 0, 1},
+		{"q.sql", "q.sql", "q.sql", StatusModified, false, "sql", 1, 1, 1},
+		{"renamed.txt", "moved.txt", "renamed.txt", StatusRenamed, false, "unknown", 0, 0, 0},
+		{"tail.txt", "tail.txt", "tail.txt", StatusModified, false, "unknown", 1, 1, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filename, func(t *testing.T) {
+			c, ok := changes[tt.filename]
+			if !ok {
+				t.Fatalf("file %q not parsed", tt.filename)
+			}
+			if c.OldPath != tt.oldPath || c.NewPath != tt.newPath {
+				t.Errorf("paths = %q -> %q, want %q -> %q", c.OldPath, c.NewPath, tt.oldPath, tt.newPath)
+			}
+			if c.Status != tt.status {
+				t.Errorf("status = %q, want %q", c.Status, tt.status)
+			}
+			if c.Binary != tt.binary {
+				t.Errorf("binary = %v, want %v", c.Binary, tt.binary)
+			}
+			if c.Language != tt.language {
+				t.Errorf("language = %q, want %q", c.Language, tt.language)
+			}
+			if len(c.Additions) != tt.additions || len(c.Deletions) != tt.deletions {
+				t.Errorf("+%d -%d, want +%d -%d", len(c.Additions), len(c.Deletions), tt.additions, tt.deletions)
+			}
+			if len(c.Hunks) != tt.hunks {
+				t.Errorf("hunks = %d, want %d", len(c.Hunks), tt.hunks)
+			}
+		})
+	}
+}
+
+func TestParseDiffLines(t *testing.T) {
+	changes := loadFixture(t, "git.diff")
+
+	tests := []struct {
+		name  string
+		file  string
+		hunk  int
+		lines []Line
+	}{
+		{
+			name: "first hunk",
+			file: "main.go",
+			hunk: 0,
+			lines: []Line{
+				{Number: 1, Content: "line 1", Type: LineContext, OldNumber: 1, NewNumber: 1, Position: 1},
+				{Number: 2, Content: "line 2", Type: LineContext, OldNumber: 2, NewNumber: 2, Position: 2},
+				{Number: 3, Content: "line 3", Type: LineDelete, OldNumber: 3, Position: 3},
+				{Number: 3, Content: "line three", Type: LineAdd, NewNumber: 3, Position: 4},
+				{Number: 4, Content: "line 4", Type: LineContext, OldNumber: 4, NewNumber: 4, Position: 5},
+				{Number: 5, Content: "line 5", Type: LineContext, OldNumber: 5, NewNumber: 5, Position: 6},
+				{Number: 6, Content: "line 6", Type: LineContext, OldNumber: 6, NewNumber: 6, Position: 7},
+			},
+		},
+		{
+			name: "second hunk counts its header",
+			file: "main.go",
+			hunk: 1,
+			lines: []Line{
+				{Number: 22, Content: "line 22", Type: LineContext, OldNumber: 22, NewNumber: 22, Position: 9},
+				{Number: 23, Content: "line 23", Type: LineContext, OldNumber: 23, NewNumber: 23, Position: 10},
+				{Number: 24, Content: "line 24", Type: LineContext, OldNumber: 24, NewNumber: 24, Position: 11},
+				{Number: 25, Content: "line 25", Type: LineDelete, OldNumber: 25, Position: 12},
+				{Number: 25, Content: "line twenty-five", Type: LineAdd, NewNumber: 25, Position: 13},
+				{Number: 26, Content: "line 25b", Type: LineAdd, NewNumber: 26, Position: 14},
+				{Number: 27, Content: "line 26", Type: LineContext, OldNumber: 26, NewNumber: 27, Position: 15},
+				{Number: 28, Content: "line 27", Type: LineContext, OldNumber: 27, NewNumber: 28, Position: 16},
+				{Number: 29, Content: "line 28", Type: LineContext, OldNumber: 28, NewNumber: 29, Position: 17},
+			},
+		},
+		{
+			name: "content resembling file headers",
+			file: "q.sql",
+			hunk: 0,
+			lines: []Line{
+				{Number: 1, Content: "sql", Type: LineContext, OldNumber: 1, NewNumber: 1, Position: 1},
+				{Number: 2, Content: "-- comment", Type: LineDelete, OldNumber: 2, Position: 2},
+				{Number: 2, Content: "-- changed", Type: LineAdd, NewNumber: 2, Position: 3},
+				{Number: 3, Content: "++ inc", Type: LineContext, OldNumber: 3, NewNumber: 3, Position: 4},
+				{Number: 4, Content: "", Type: LineContext, OldNumber: 4, NewNumber: 4, Position: 5},
+				{Number: 5, Content: "end", Type: LineContext, OldNumber: 5, NewNumber: 5, Position: 6},
+			},
+		},
+		{
+			name: "no newline at end of file",
+			file: "tail.txt",
+			hunk: 0,
+			lines: []Line{
+				{Number: 1, Content: "no newline", Type: LineDelete, OldNumber: 1, Position: 1},
+				{Number: 1, Content: "no newline changed", Type: LineAdd, NewNumber: 1, Position: 3},
+			},
+		},
+		{
+			name: "deleted file",
+			file: "gone.txt",
+			hunk: 0,
+			lines: []Line{
+				{Number: 1, Content: "old", Type: LineDelete, OldNumber: 1, Position: 1},
+			},
+		},
+		{
+			name: "new file",
+			file: "added.txt",
+			hunk: 0,
+			lines: []Line{
+				{Number: 1, Content: "new", Type: LineAdd, NewNumber: 1, Position: 1},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := changes[tt.file]
+			if tt.hunk >= len(c.Hunks) {
+				t.Fatalf("%s has %d hunks", tt.file, len(c.Hunks))
+			}
+			got := c.Hunks[tt.hunk].Lines
+			if len(got) != len(tt.lines) {
+				t.Fatalf("got %d lines, want %d: %+v", len(got), len(tt.lines), got)
+			}
+			for i := range got {
+				if got[i] != tt.lines[i] {
+					t.Errorf("line %d = %+v, want %+v", i, got[i], tt.lines[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseDiffHunkHeaders(t *testing.T) {
+	changes := loadFixture(t, "git.diff")
+
+	tests := []struct {
+		file string
+		hunk int
+		want Hunk
+	}{
+		{"main.go", 0, Hunk{OldStart: 1, OldLines: 6, NewStart: 1, NewLines: 6, Position: 0}},
+		{"main.go", 1, Hunk{OldStart: 22, OldLines: 7, NewStart: 22, NewLines: 8, Section: "line 21", Position: 8}},
+		{"added.txt", 0, Hunk{OldStart: 0, OldLines: 0, NewStart: 1, NewLines: 1}},
+		{"gone.txt", 0, Hunk{OldStart: 1, OldLines: 1, NewStart: 0, NewLines: 0}},
+		{"tail.txt", 0, Hunk{OldStart: 1, OldLines: 1, NewStart: 1, NewLines: 1}},
+	}
+	for _, tt := range tests {
+		h := changes[tt.file].Hunks[tt.hunk]
+		if h.OldStart != tt.want.OldStart || h.OldLines != tt.want.OldLines ||
+			h.NewStart != tt.want.NewStart || h.NewLines != tt.want.NewLines ||
+			h.Section != tt.want.Section || h.Position != tt.want.Position {
+			t.Errorf("%s hunk %d = %+v, want %+v", tt.file, tt.hunk, h, tt.want)
+		}
+	}
+}
+
+func TestParseDiffAdditionsAndDeletions(t *testing.T) {
+	c := loadFixture(t, "git.diff")["main.go"]
+
+	wantAdd := []int{3, 25, 26}
+	for i, l := range c.Additions {
+		if l.Number != wantAdd[i] || l.Type != LineAdd {
+			t.Errorf("addition %d = %+v, want new line %d", i, l, wantAdd[i])
+		}
+	}
+	wantDel := []int{3, 25}
+	for i, l := range c.Deletions {
+		if l.Number != wantDel[i] || l.OldNumber != wantDel[i] || l.Type != LineDelete {
+			t.Errorf("deletion %d = %+v, want old line %d", i, l, wantDel[i])
+		}
+	}
+}
+
+func TestPositionOf(t *testing.T) {
+	c := loadFixture(t, "git.diff")["main.go"]
+
+	tests := []struct {
+		newLine int
+		want    int
+	}{
+		{1, 1},
+		{3, 4},  // 新增行
+		{6, 7},  // 上下文行
+		{7, 0},  // 两个 hunk 之间
+		{22, 9}, // 第二个 hunk 的第一行，位置包含第二个 @@ 行
+		{26, 14},
+		{29, 17},
+		{30, 0},
+		{0, 0},
+	}
+	for _, tt := range tests {
+		if got := c.PositionOf(tt.newLine); got != tt.want {
+			t.Errorf("PositionOf(%d) = %d, want %d", tt.newLine, got, tt.want)
+		}
+	}
+}
+
+func TestParseDiffEdgeCases(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		filename string
+		lines    []Line
+	}{
+		{
+			// 部分工具会去掉上下文空行前的空格
+			name: "empty context line without leading space",
+			input: "diff --git a/a.go b/a.go\n" +
+				"--- a/a.go\n" +
+				"+++ b/a.go\n" +
+				"@@ -1,3 +1,3 @@\n" +
+				" a\n" +
+				"\n" +
+				"-b\n" +
+				"+c\n",
+			filename: "a.go",
+			lines: []Line{
+				{Number: 1, Content: "a", Type: LineContext, OldNumber: 1, NewNumber: 1, Position: 1},
+				{Number: 2, Content: "", Type: LineContext, OldNumber: 2, NewNumber: 2, Position: 2},
+				{Number: 3, Content: "b", Type: LineDelete, OldNumber: 3, Position: 3},
+				{Number: 3, Content: "c", Type: LineAdd, NewNumber: 3, Position: 4},
+			},
+		},
+		{
+			// 删除行的内容以 "-- " 开头，与文件头相同
+			name: "deleted line looking like a file header",
+			input: "diff --git a/b.sql b/b.sql\n" +
+				"--- a/b.sql\n" +
+				"+++ b/b.sql\n" +
+				"@@ -1,2 +1,2 @@\n" +
+				"--- a/b.sql\n" +
+				"+++ b/b.sql\n" +
+				" x\n",
+			filename: "b.sql",
+			lines: []Line{
+				{Number: 1, Content: "-- a/b.sql", Type: LineDelete, OldNumber: 1, Position: 1},
+				{Number: 1, Content: "++ b/b.sql", Type: LineAdd, NewNumber: 1, Position: 2},
+				{Number: 2, Content: "x", Type: LineContext, OldNumber: 2, NewNumber: 2, Position: 3},
+			},
+		},
+		{
+			name: "quoted path with escapes",
+			input: "diff --git \"a/dir/tab\\there.go\" \"b/dir/tab\\there.go\"\n" +
+				"--- \"a/dir/tab\\there.go\"\n" +
+				"+++ \"b/dir/tab\\there.go\"\n" +
+				"@@ -1 +1 @@\n" +
+				"-a\n" +
+				"+b\n",
+			filename: "dir/tab\there.go",
+			lines: []Line{
+				{Number: 1, Content: "a", Type: LineDelete, OldNumber: 1, Position: 1},
+				{Number: 1, Content: "b", Type: LineAdd, NewNumber: 1, Position: 2},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes, err := ParseDiff(tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(changes) != 1 {
+				t.Fatalf("got %d files, want 1", len(changes))
+			}
+			c := changes[0]
+			if c.Filename != tt.filename {
+				t.Errorf("filename = %q, want %q", c.Filename, tt.filename)
+			}
+			if len(c.Hunks) != 1 {
+				t.Fatalf("got %d hunks, want 1", len(c.Hunks))
+			}
+			got := c.Hunks[0].Lines
+			if len(got) != len(tt.lines) {
+				t.Fatalf("got %d lines, want %d: %+v", len(got), len(tt.lines), got)
+			}
+			for i := range got {
+				if got[i] != tt.lines[i] {
+					t.Errorf("line %d = %+v, want %+v", i, got[i], tt.lines[i])
+				}
+			}
+		})
+	}
+}
+
+func TestMapLines(t *testing.T) {
+	c := loadFixture(t, "git.diff")["main.go"]
+
+	mapped := c.MapLines(func(lines []Line) []Line {
+		out := make([]Line, len(lines))
+		for i, l := range lines {
+			l.Content = "x"
+			out[i] = l
+		}
+		return out
+	})
+	if len(mapped.Additions) != len(c.Additions) || len(mapped.Deletions) != len(c.Deletions) {
+		t.Fatalf("+%d -%d, want +%d -%d", len(mapped.Additions), len(mapped.Deletions), len(c.Additions), len(c.Deletions))
+	}
+	for i, l := range mapped.Additions {
+		if l.Content != "x" || l.Number != c.Additions[i].Number {
+			t.Errorf("addition %d = %+v", i, l)
+		}
+	}
+	for _, h := range mapped.Hunks {
+		for _, l := range h.Lines {
+			if l.Content != "x" {
+				t.Errorf("hunk line not mapped: %+v", l)
+			}
+		}
+	}
+	// 原变更不受影响
+	if c.Hunks[0].Lines[0].Content != "line 1" {
+		t.Errorf("original modified: %+v", c.Hunks[0].Lines[0])
+	}
+}
@@ -0,0 +1,299 @@
+package lint
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"code-sentinel/pkg/diff"
+)
+
+// 支持的输出格式
+const (
+	FormatSARIF    = "sarif"    // SARIF 2.1.0，多数工具支持
+	FormatGolangci = "golangci" // golangci-lint --out-format json
+	FormatESLint   = "eslint"   // eslint -f json
+	FormatRuff     = "ruff"     // ruff check --output-format json
+)
+
+// 问题级别，与 SARIF 的 level 一致
+const (
+	LevelError   = "error"
+	LevelWarning = "warning"
+	LevelNote    = "note"
+)
+
+// Finding 检查工具报告的一个问题
+type Finding struct {
+	Tool    string `json:"tool,omitempty"` // SARIF 中声明的工具名，其他格式为空
+	Rule    string `json:"rule,omitempty"`
+	File    string `json:"file"` // 相对仓库根目录的路径
+	Line    int    `json:"line"`
+	Level   string `json:"level"`
+	Message string `json:"message"`
+}
+
+// Formats 返回支持的输出格式
+func Formats() []string {
+	return []string{FormatSARIF, FormatGolangci, FormatESLint, FormatRuff}
+}
+
+// Parse 解析检查工具的输出，root 为执行工具的仓库根目录，绝对路径会转换为相对路径
+// 输出前如有非 JSON 内容（如包管理器的提示）会被跳过
+func Parse(format string, data []byte, root string) ([]Finding, error) {
+	start := bytes.IndexAny(data, "{[")
+	if start < 0 {
+		if len(bytes.TrimSpace(data)) == 0 {
+			return nil, fmt.Errorf("empty output")
+		}
+		return nil, fmt.Errorf("no JSON in output")
+	}
+	dec := json.NewDecoder(bytes.NewReader(data[start:]))
+
+	var findings []Finding
+	var err error
+	switch format {
+	case FormatSARIF:
+		findings, err = parseSARIF(dec)
+	case FormatGolangci:
+		findings, err = parseGolangci(dec)
+	case FormatESLint:
+		findings, err = parseESLint(dec)
+	case FormatRuff:
+		findings, err = parseRuff(dec)
+	default:
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range findings {
+		findings[i].File = relPath(root, findings[i].File)
+		findings[i].Message = strings.TrimSpace(findings[i].Message)
+		if findings[i].Level == "" {
+			findings[i].Level = LevelWarning
+		}
+	}
+	return findings, nil
+}
+
+func parseSARIF(dec *json.Decoder) ([]Finding, error) {
+	var log struct {
+		Runs []struct {
+			Tool struct {
+				Driver struct {
+					Name  string `json:"name"`
+					Rules []struct {
+						ID                   string `json:"id"`
+						DefaultConfiguration struct {
+							Level string `json:"level"`
+						} `json:"defaultConfiguration"`
+					} `json:"rules"`
+				} `json:"driver"`
+			} `json:"tool"`
+			Results []struct {
+				RuleID  string `json:"ruleId"`
+				Level   string `json:"level"`
+				Message struct {
+					Text string `json:"text"`
+				} `json:"message"`
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct {
+							URI string `json:"uri"`
+						} `json:"artifactLocation"`
+						Region struct {
+							StartLine int `json:"startLine"`
+						} `json:"region"`
+					} `json:"physicalLocation"`
+				} `json:"locations"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	if err := dec.Decode(&log); err != nil {
+		return nil, fmt.Errorf("invalid SARIF: %w", err)
+	}
+
+	var findings []Finding
+	for _, run := range log.Runs {
+		ruleLevels := make(map[string]string, len(run.Tool.Driver.Rules))
+		for _, r := range run.Tool.Driver.Rules {
+			ruleLevels[r.ID] = r.DefaultConfiguration.Level
+		}
+		for _, res := range run.Results {
+			if len(res.Locations) == 0 {
+				continue
+			}
+			loc := res.Locations[0].PhysicalLocation
+			level := res.Level
+			if level == "" {
+				level = ruleLevels[res.RuleID]
+			}
+			if level == "none" {
+				continue
+			}
+			findings = append(findings, Finding{
+				Tool:    run.Tool.Driver.Name,
+				Rule:    res.RuleID,
+				File:    loc.ArtifactLocation.URI,
+				Line:    loc.Region.StartLine,
+				Level:   level,
+				Message: res.Message.Text,
+			})
+		}
+	}
+	return findings, nil
+}
+
+func parseGolangci(dec *json.Decoder) ([]Finding, error) {
+	var out struct {
+		Issues []struct {
+			FromLinter string `json:"FromLinter"`
+			Text       string `json:"Text"`
+			Severity   string `json:"Severity"`
+			Pos        struct {
+				Filename string `json:"Filename"`
+				Line     int    `json:"Line"`
+			} `json:"Pos"`
+		} `json:"Issues"`
+	}
+	if err := dec.Decode(&out); err != nil {
+		return nil, fmt.Errorf("invalid golangci-lint output: %w", err)
+	}
+
+	findings := make([]Finding, 0, len(out.Issues))
+	for _, issue := range out.Issues {
+		findings = append(findings, Finding{
+			Rule:    issue.FromLinter,
+			File:    issue.Pos.Filename,
+			Line:    issue.Pos.Line,
+			Level:   normalizeLevel(issue.Severity),
+			Message: issue.Text,
+		})
+	}
+	return findings, nil
+}
+
+func parseESLint(dec *json.Decoder) ([]Finding, error) {
+	var out []struct {
+		FilePath string `json:"filePath"`
+		Messages []struct {
+			RuleID   string `json:"ruleId"`
+			Severity int    `json:"severity"` // 1 警告，2 错误
+			Message  string `json:"message"`
+			Line     int    `json:"line"`
+		} `json:"messages"`
+	}
+	if err := dec.Decode(&out); err != nil {
+		return nil, fmt.Errorf("invalid eslint output: %w", err)
+	}
+
+	var findings []Finding
+	for _, file := range out {
+		for _, m := range file.Messages {
+			level := LevelWarning
+			if m.Severity >= 2 {
+				level = LevelError
+			}
+			findings = append(findings, Finding{
+				Rule:    m.RuleID,
+				File:    file.FilePath,
+				Line:    m.Line,
+				Level:   level,
+				Message: m.Message,
+			})
+		}
+	}
+	return findings, nil
+}
+
+func parseRuff(dec *json.Decoder) ([]Finding, error) {
+	var out []struct {
+		Code     string `json:"code"`
+		Message  string `json:"message"`
+		Filename string `json:"filename"`
+		Location struct {
+			Row int `json:"row"`
+		} `json:"location"`
+	}
+	if err := dec.Decode(&out); err != nil {
+		return nil, fmt.Errorf("invalid ruff output: %w", err)
+	}
+
+	findings := make([]Finding, 0, len(out))
+	for _, v := range out {
+		level := LevelWarning
+		// 语法错误没有规则编号
+		if v.Code == "" {
+			level = LevelError
+		}
+		findings = append(findings, Finding{
+			Rule:    v.Code,
+			File:    v.Filename,
+			Line:    v.Location.Row,
+			Level:   level,
+			Message: v.Message,
+		})
+	}
+	return findings, nil
+}
+
+// OnAddedLines 只保留位于新增行上的问题，同一位置重复报告的相同问题只保留一个
+func OnAddedLines(findings []Finding, changes []diff.FileChange) []Finding {
+	added := make(map[string]map[int]bool, len(changes))
+	for _, c := range changes {
+		lines := make(map[int]bool, len(c.Additions))
+		for _, l := range c.Additions {
+			lines[l.Number] = true
+		}
+		added[c.Filename] = lines
+	}
+
+	var kept []Finding
+	seen := make(map[string]bool)
+	for _, f := range findings {
+		if !added[f.File][f.Line] {
+			continue
+		}
+		key := fmt.Sprintf("%s:%d:%s:%s", f.File, f.Line, f.Rule, f.Message)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		kept = append(kept, f)
+	}
+	return kept
+}
+
+// normalizeLevel 统一工具自定义的严重程度写法
+func normalizeLevel(level string) string {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "error", "critical", "high", "blocker":
+		return LevelError
+	case "info", "note", "low", "hint":
+		return LevelNote
+	}
+	return LevelWarning
+}
+
+// relPath 将工具输出的路径（file:// URI、绝对路径或相对路径）转换为相对仓库根目录的路径
+func relPath(root, p string) string {
+	if strings.HasPrefix(p, "file://") {
+		if u, err := url.Parse(p); err == nil {
+			p = u.Path
+		}
+	} else if unescaped, err := url.PathUnescape(p); err == nil {
+		p = unescaped
+	}
+
+	if filepath.IsAbs(p) && root != "" {
+		if rel, err := filepath.Rel(root, p); err == nil && !strings.HasPrefix(rel, "..") {
+			p = rel
+		}
+	}
+	return strings.TrimPrefix(filepath.ToSlash(filepath.Clean(p)), "./")
+}
@@ -0,0 +1,162 @@
+package lint
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"code-sentinel/pkg/diff"
+)
+
+// root 样例输出中工具所在的仓库根目录
+const root = "/tmp/ws/repo"
+
+// loadOutput 读取 testdata 中的工具输出样例
+func loadOutput(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		file   string
+		want   []Finding
+	}{
+		{
+			name:   "golangci-lint severities",
+			format: FormatGolangci,
+			file:   "golangci.json",
+			want: []Finding{
+				{Rule: "errcheck", File: "internal/store/file.go", Line: 24, Level: LevelWarning, Message: "Error return value of `f.Close` is not checked"},
+				{Rule: "gosec", File: "internal/store/hash.go", Line: 15, Level: LevelError, Message: "G401: Use of weak cryptographic primitive"},
+				{Rule: "gocritic", File: "internal/store/file.go", Line: 40, Level: LevelNote, Message: "ifElseChain: rewrite if-else to switch statement"},
+			},
+		},
+		{
+			name:   "eslint absolute paths",
+			format: FormatESLint,
+			file:   "eslint.json",
+			want: []Finding{
+				{Rule: "no-unused-vars", File: "web/src/App.tsx", Line: 3, Level: LevelWarning, Message: "'count' is assigned a value but never used."},
+				{Rule: "no-eval", File: "web/src/App.tsx", Line: 8, Level: LevelError, Message: "eval can be harmful."},
+			},
+		},
+		{
+			name:   "ruff with syntax error",
+			format: FormatRuff,
+			file:   "ruff.json",
+			want: []Finding{
+				{Rule: "F401", File: "app/main.py", Line: 1, Level: LevelWarning, Message: "`os` imported but unused"},
+				{File: "app/views.py", Line: 11, Level: LevelError, Message: "SyntaxError: Expected ')', found newline"},
+			},
+		},
+		{
+			// G101 没有 level，取规则的默认级别；level 为 none 和没有位置的结果被跳过
+			name:   "gosec SARIF",
+			format: FormatSARIF,
+			file:   "gosec.sarif",
+			want: []Finding{
+				{Tool: "gosec", Rule: "G114", File: "cmd/server/main.go", Line: 31, Level: LevelError, Message: "Use of net/http serve function that has no support for setting timeouts"},
+				{Tool: "gosec", Rule: "G101", File: "internal/auth/token.go", Line: 12, Level: LevelWarning, Message: "Potential hardcoded credentials"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.format, loadOutput(t, tt.file), root)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLeadingNoise(t *testing.T) {
+	data := append([]byte("npm WARN config production Use `--omit=dev` instead.\n"), loadOutput(t, "eslint.json")...)
+	got, err := Parse(FormatESLint, data, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Errorf("got %d findings, want 2", len(got))
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		data   string
+	}{
+		{"empty output", FormatRuff, "  \n"},
+		{"no JSON", FormatRuff, "ruff: command not found"},
+		{"unsupported format", "checkstyle", "{}"},
+		{"wrong shape", FormatESLint, `{"filePath": "a.js"}`},
+		{"truncated", FormatSARIF, `{"runs": [`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(tt.format, []byte(tt.data), root); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestRelPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"file:///tmp/ws/repo/a/b.go", "a/b.go"},
+		{"/tmp/ws/repo/a/b.go", "a/b.go"},
+		{"./a/b.go", "a/b.go"},
+		{"a/my%20file.go", "a/my file.go"},
+		{"/etc/passwd", "/etc/passwd"},
+	}
+	for _, tt := range tests {
+		if got := relPath(root, tt.path); got != tt.want {
+			t.Errorf("relPath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestOnAddedLines(t *testing.T) {
+	changes := []diff.FileChange{
+		{
+			Filename:  "internal/store/file.go",
+			Additions: []diff.Line{{Number: 24}, {Number: 25}},
+		},
+		{
+			Filename:  "internal/store/hash.go",
+			Additions: []diff.Line{{Number: 3}},
+		},
+	}
+	findings, err := Parse(FormatGolangci, loadOutput(t, "golangci.json"), root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 同一问题重复报告只保留一次
+	findings = append(findings, findings[0])
+
+	got := OnAddedLines(findings, changes)
+	if len(got) != 1 {
+		t.Fatalf("got %d findings, want 1: %+v", len(got), got)
+	}
+	if got[0].File != "internal/store/file.go" || got[0].Line != 24 || got[0].Rule != "errcheck" {
+		t.Errorf("kept %+v, want errcheck at internal/store/file.go:24", got[0])
+	}
+
+	if got := OnAddedLines(findings, nil); len(got) != 0 {
+		t.Errorf("got %d findings without changes, want 0", len(got))
+	}
+}
@@ -0,0 +1,284 @@
+package prompt
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"code-sentinel/pkg/diff"
+	"code-sentinel/pkg/tokenizer"
+)
+
+const (
+	// safetyMarginPercent 按词表分词时的安全余量（百分比），覆盖消息格式等未计入的开销
+	safetyMarginPercent = 5
+	// heuristicMarginPercent 启发式估算时的安全余量（百分比）：
+	// 代码中的长标识符、转义字符和非拉丁文字都可能被低估
+	heuristicMarginPercent = 15
+	// DefaultContextWindow 未知模型且未配置上下文窗口时假定的保守窗口大小
+	DefaultContextWindow = 8192
+)
+
+// Budget 提示词 Token 预算
+type Budget struct {
+	Model           string // 用于选择 Token 估算器和查询上下文窗口
+	ContextWindow   int    // 上下文窗口大小，0 表示按模型查表
+	MaxOutputTokens int    // 为模型回答预留的 Token 数
+}
+
+// Window 上下文窗口大小：优先使用配置，其次按模型查表；
+// 未知模型且未配置时返回 DefaultContextWindow，assumed 为 true
+func (b Budget) Window() (window int, assumed bool) {
+	if b.ContextWindow > 0 {
+		return b.ContextWindow, false
+	}
+	if window, ok := tokenizer.ContextWindow(b.Model); ok {
+		return window, false
+	}
+	return DefaultContextWindow, true
+}
+
+// Available 扣除回答预留和安全余量后可用于输入的 Token 数
+// 上下文窗口为假定值时按启发式估算的余量计算
+func (b Budget) Available() int {
+	window, assumed := b.Window()
+	margin := safetyMarginPercent
+	if assumed || !tokenizer.Exact(b.Model) {
+		margin = heuristicMarginPercent
+	}
+	available := window - b.MaxOutputTokens
+	return available - available*margin/100
+}
+
+// Omission 因预算不足未发送给模型的变更
+type Omission struct {
+	File   string `json:"file"`
+	Hunk   string `json:"hunk,omitempty"` // 如 "L12-L30" 或 "deleted lines"，为空表示整个文件
+	Lines  int    `json:"lines"`
+	Reason string `json:"reason"`
+}
+
+// BuildResult 按预算构建的用户提示词
+type BuildResult struct {
+	UserPrompt string
+	Tokens     int               // 系统提示词 + 用户提示词的估算 Token 数
+	Changes    []diff.FileChange // 实际发送给模型的变更（裁剪后）
+	Omitted    []Omission
+}
+
+// BuildUserPromptWithBudget 在 Token 预算内构建用户提示词
+// 超出预算时按文件优先级裁剪：先丢弃删除行，再按连续新增块逐块装入，
+// 测试文件和生成文件优先被裁剪，所有被省略的文件和代码块记录在 Omitted 中
+func (b *Builder) BuildUserPromptWithBudget(systemPrompt string, changes []diff.FileChange, budget Budget) (*BuildResult, error) {
+	est := tokenizer.ForModel(budget.Model)
+	limit := b.DiffTokenLimit(systemPrompt, budget)
+	if limit <= 0 {
+		return nil, fmt.Errorf("system prompt exceeds token budget of model %s", budget.Model)
+	}
+
+	if est.Count(diff.FormatChangesForPrompt(changes)) <= limit {
+		userPrompt, err := b.BuildUserPrompt(changes)
+		if err != nil {
+			return nil, err
+		}
+		return &BuildResult{
+			UserPrompt: userPrompt,
+			Tokens:     est.Count(systemPrompt) + est.Count(userPrompt),
+			Changes:    changes,
+		}, nil
+	}
+
+	packed, omitted := packChanges(changes, limit, est)
+	if len(packed) == 0 {
+		return nil, fmt.Errorf("no change fits in token budget of model %s", budget.Model)
+	}
+
+	userPrompt, err := b.BuildUserPrompt(packed)
+	if err != nil {
+		return nil, err
+	}
+
+	return &BuildResult{
+		UserPrompt: userPrompt,
+		Tokens:     est.Count(systemPrompt) + est.Count(userPrompt),
+		Changes:    packed,
+		Omitted:    omitted,
+	}, nil
+}
+
+// Fits 判断全部变更是否能在预算内一次性发送
+func (b *Builder) Fits(systemPrompt string, changes []diff.FileChange, budget Budget) bool {
+	est := tokenizer.ForModel(budget.Model)
+	return est.Count(diff.FormatChangesForPrompt(changes)) <= b.DiffTokenLimit(systemPrompt, budget)
+}
+
+// DiffTokenLimit 扣除系统提示词和模板开销后，Diff 内容可用的 Token 数
+func (b *Builder) DiffTokenLimit(systemPrompt string, budget Budget) int {
+	est := tokenizer.ForModel(budget.Model)
+	overhead, _ := b.BuildUserPrompt(nil)
+	return budget.Available() - est.Count(systemPrompt) - est.Count(overhead)
+}
+
+// packChanges 按优先级装箱，返回保持原顺序的装入结果和被省略的部分
+func packChanges(changes []diff.FileChange, limit int, est tokenizer.Estimator) ([]diff.FileChange, []Omission) {
+	order := make([]int, len(changes))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return filePriority(changes[order[i]].Filename) < filePriority(changes[order[j]].Filename)
+	})
+
+	included := make([]*diff.FileChange, len(changes))
+	var omitted []Omission
+	remaining := limit
+
+	for _, idx := range order {
+		change := changes[idx]
+
+		full := est.Count(diff.FormatChangesForPrompt([]diff.FileChange{change}))
+		if full <= remaining {
+			c := change
+			included[idx] = &c
+			remaining -= full
+			continue
+		}
+
+		// 部分装入：只保留能放下的新增代码块，删除行整体省略
+		partial := change
+		partial.Additions = nil
+		partial.Deletions = nil
+		header := est.Count(diff.FormatChangesForPrompt([]diff.FileChange{partial})) + blockOverhead
+		if len(change.Deletions) > 0 {
+			omitted = append(omitted, Omission{
+				File:   change.Filename,
+				Hunk:   "deleted lines",
+				Lines:  len(change.Deletions),
+				Reason: "token budget exceeded",
+			})
+		}
+
+		used := header
+		for _, block := range splitBlocks(change.Additions) {
+			cost := est.Count(formatBlock(block))
+			if used+cost <= remaining {
+				partial.Additions = append(partial.Additions, block...)
+				used += cost
+				continue
+			}
+
+			// 整块放不下时保留能放下的开头部分，只省略剩余行
+			kept := 0
+			for _, line := range block {
+				lineCost := est.Count(formatBlock([]diff.Line{line}))
+				if used+lineCost > remaining {
+					break
+				}
+				used += lineCost
+				kept++
+			}
+			partial.Additions = append(partial.Additions, block[:kept]...)
+
+			rest := block[kept:]
+			if len(rest) == 0 {
+				continue
+			}
+			omitted = append(omitted, Omission{
+				File:   change.Filename,
+				Hunk:   fmt.Sprintf("L%d-L%d", rest[0].Number, rest[len(rest)-1].Number),
+				Lines:  len(rest),
+				Reason: "token budget exceeded",
+			})
+		}
+
+		if len(partial.Additions) == 0 {
+			// 一块都放不下，整个文件省略（删除行已记录则不重复记录）
+			omitted = dropFileOmissions(omitted, change.Filename)
+			omitted = append(omitted, Omission{
+				File:   change.Filename,
+				Lines:  len(change.Additions) + len(change.Deletions),
+				Reason: "token budget exceeded",
+			})
+			continue
+		}
+
+		included[idx] = &partial
+		remaining -= used
+	}
+
+	var packed []diff.FileChange
+	for _, c := range included {
+		if c != nil {
+			packed = append(packed, *c)
+		}
+	}
+
+	return packed, omitted
+}
+
+// blockOverhead 代码块围栏等格式开销
+const blockOverhead = 8
+
+// splitBlocks 将新增行按行号连续性切分为代码块
+func splitBlocks(lines []diff.Line) [][]diff.Line {
+	var blocks [][]diff.Line
+	var current []diff.Line
+	for _, line := range lines {
+		if len(current) > 0 && line.Number != current[len(current)-1].Number+1 {
+			blocks = append(blocks, current)
+			current = nil
+		}
+		current = append(current, line)
+	}
+	if len(current) > 0 {
+		blocks = append(blocks, current)
+	}
+	return blocks
+}
+
+// formatBlock 与 diff.FormatChangesForPrompt 中新增行的格式保持一致，用于估算 Token
+func formatBlock(lines []diff.Line) string {
+	var sb strings.Builder
+	for _, line := range lines {
+		fmt.Fprintf(&sb, "// Line %d\n%s\n", line.Number, line.Content)
+	}
+	return sb.String()
+}
+
+// dropFileOmissions 移除指定文件已记录的省略项
+func dropFileOmissions(omitted []Omission, filename string) []Omission {
+	kept := omitted[:0]
+	for _, o := range omitted {
+		if o.File != filename {
+			kept = append(kept, o)
+		}
+	}
+	return kept
+}
+
+// filePriority 文件裁剪优先级，数值越大越先被裁剪
+func filePriority(filename string) int {
+	lower := strings.ToLower(filename)
+	switch {
+	case strings.Contains(lower, "vendor/"),
+		strings.Contains(lower, "node_modules/"),
+		strings.HasSuffix(lower, ".min.js"),
+		strings.HasSuffix(lower, ".pb.go"),
+		strings.HasSuffix(lower, "_gen.go"),
+		strings.HasSuffix(lower, ".lock"),
+		strings.HasSuffix(lower, "-lock.json"):
+		return 2
+	case strings.HasSuffix(lower, "_test.go"),
+		strings.Contains(lower, ".test."),
+		strings.Contains(lower, ".spec."),
+		strings.Contains(lower, "/test/"),
+		strings.Contains(lower, "/tests/"),
+		strings.HasPrefix(lower, "test/"),
+		strings.HasPrefix(lower, "tests/"),
+		strings.HasPrefix(lower, "test_"),
+		strings.Contains(lower, "/test_"):
+		return 1
+	default:
+		return 0
+	}
+}
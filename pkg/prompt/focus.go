@@ -0,0 +1,109 @@
+package prompt
+
+import (
+	"strings"
+)
+
+// 系统提示词中的占位符
+const (
+	FocusPlaceholder    = "{review_focus}" // 替换为所选审查重点的检查清单
+	CategoryPlaceholder = "{categories}"   // 替换为允许输出的问题类别，如 security|logic
+)
+
+// FocusArea 审查重点及其检查清单
+type FocusArea struct {
+	Category  string `json:"category"` // 与问题的 category 字段一致
+	Title     string `json:"title"`
+	Checklist string `json:"checklist"` // Markdown 列表形式的检查项
+}
+
+// focusAreas 内置审查重点，顺序即提示词中的顺序
+var focusAreas = []FocusArea{
+	{Category: "security", Title: "安全问题", Checklist: `- 注入：SQL 注入、命令注入、模板注入、路径穿越
+- XSS、CSRF、SSRF、开放重定向
+- 硬编码密钥、令牌、密码，敏感信息写入日志或返回给客户端
+- 认证和权限校验缺失或可被绕过
+- 不安全的加密算法、随机数、证书校验关闭
+- 反序列化不可信数据`},
+	{Category: "performance", Title: "性能问题", Checklist: `- 循环内查库、N+1 查询、缺少批量操作
+- 不必要的重复计算、在热路径上的大对象分配和拷贝
+- 内存泄漏、无界缓存、无界队列
+- 同步阻塞调用、缺少超时、锁粒度过大
+- 低效的数据结构和算法（如在大集合上线性查找）`},
+	{Category: "logic", Title: "逻辑错误", Checklist: `- 空指针、越界、除零等边界条件
+- 异常或错误未处理、被吞掉，错误路径上资源未释放
+- 死循环、竞态条件、死锁
+- 条件判断错误、状态未正确更新、与函数意图不符的实现
+- 时间、时区、精度、编码等处理错误`},
+	{Category: "style", Title: "代码风格", Checklist: `- 命名不清晰或不符合语言惯例
+- 注释缺失、过时或与代码不符
+- 过长函数、过深嵌套、重复代码
+- 魔法数字、未使用的变量和导入`},
+}
+
+// FocusAreas 返回内置审查重点
+func FocusAreas() []FocusArea {
+	return append([]FocusArea{}, focusAreas...)
+}
+
+// NormalizeFocus 规范化审查重点：忽略未知类别并去重，按内置顺序排列；为空时返回全部类别
+func NormalizeFocus(focus []string) []string {
+	selected := make(map[string]bool)
+	for _, f := range focus {
+		selected[strings.ToLower(strings.TrimSpace(f))] = true
+	}
+
+	var normalized []string
+	for _, area := range focusAreas {
+		if selected[area.Category] {
+			normalized = append(normalized, area.Category)
+		}
+	}
+	if len(normalized) == 0 {
+		for _, area := range focusAreas {
+			normalized = append(normalized, area.Category)
+		}
+	}
+	return normalized
+}
+
+// FocusSection 生成所选审查重点的检查清单
+func FocusSection(focus []string) string {
+	selected := make(map[string]bool)
+	for _, f := range NormalizeFocus(focus) {
+		selected[f] = true
+	}
+
+	var sb strings.Builder
+	sb.WriteString("只报告以下类别的问题，其他类别的问题不要输出：\n")
+	for _, area := range focusAreas {
+		if !selected[area.Category] {
+			continue
+		}
+		sb.WriteString("\n### ")
+		sb.WriteString(area.Title)
+		sb.WriteString("（")
+		sb.WriteString(area.Category)
+		sb.WriteString("）\n")
+		sb.WriteString(area.Checklist)
+		sb.WriteString("\n")
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// BuildSystemPrompt 按审查重点填充系统提示词模板
+// 模板中的 {review_focus} 替换为所选重点的检查清单，{categories} 替换为允许的类别；
+// 自定义提示词不含 {review_focus} 且只选择了部分重点时，在末尾追加审查重点说明
+func (b *Builder) BuildSystemPrompt(tmpl string, focus []string) string {
+	focus = NormalizeFocus(focus)
+	section := FocusSection(focus)
+
+	prompt := strings.ReplaceAll(tmpl, CategoryPlaceholder, strings.Join(focus, "|"))
+	if strings.Contains(prompt, FocusPlaceholder) {
+		return strings.ReplaceAll(prompt, FocusPlaceholder, section)
+	}
+	if len(focus) < len(focusAreas) {
+		prompt += "\n\n## 审查重点\n" + section
+	}
+	return prompt
+}
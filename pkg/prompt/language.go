@@ -0,0 +1,217 @@
+package prompt
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"code-sentinel/pkg/diff"
+)
+
+// maxLanguageSections 系统提示词中最多附加的语言专项检查数量（按变更行数取前几种）
+const maxLanguageSections = 5
+
+// LanguageSQL 嵌入在其他语言中的 SQL 语句也会触发 SQL 专项检查
+const LanguageSQL = "sql"
+
+// sqlStatementRegex 识别代码中拼接或内嵌的 SQL 语句
+var sqlStatementRegex = regexp.MustCompile(`(?i)\b(?:select\s+.+\s+from|insert\s+into|update\s+\w+\s+set|delete\s+from)\b`)
+
+// LanguageTemplate 语言专项审查清单
+type LanguageTemplate struct {
+	Language string `json:"language"` // 与 diff 识别的语言一致，如 go/java/python
+	Title    string `json:"title"`
+	Guidance string `json:"guidance"` // Markdown 列表形式的检查项
+}
+
+// defaultLanguageTemplates 内置的语言专项检查清单
+var defaultLanguageTemplates = []LanguageTemplate{
+	{Language: "go", Title: "Go", Guidance: `- error 是否被忽略；向上返回时是否用 fmt.Errorf("...: %w", err) 保留错误链，errors.Is/As 判断是否正确
+- goroutine 泄露：是否有退出条件，channel 发送/接收是否可能永久阻塞，是否监听 ctx.Done()
+- 并发安全：map、切片的并发读写是否加锁，sync.WaitGroup 的 Add 是否在 goroutine 启动前调用
+- defer 使用：循环内 defer、defer 中忽略 Close 的错误、resp.Body 是否关闭
+- 循环变量在闭包中被捕获（Go 1.22 之前）、nil map 写入、接口 nil 判断陷阱
+- context 是否正确传递，不应存入结构体或使用 context.Background() 代替调用方的 ctx`},
+	{Language: "java", Title: "Java", Guidance: `- 资源管理：流、连接、锁是否使用 try-with-resources 或在 finally 中释放
+- 空指针：返回值、集合元素、拆箱是否可能为 null，Optional 是否被滥用
+- 线程安全：共享可变状态、非线程安全集合（HashMap、SimpleDateFormat）、双重检查锁
+- 异常处理：是否吞掉异常、捕获过宽的 Exception/Throwable、丢失原始异常 cause
+- equals/hashCode 是否成对重写，字符串是否用 == 比较
+- 事务与 ORM：@Transactional 自调用失效、N+1 查询、懒加载在事务外访问`},
+	{Language: "python", Title: "Python", Guidance: `- 可变默认参数（def f(x=[])）、类属性中的可变对象被实例共享
+- 资源管理：文件、连接、锁是否使用 with 语句
+- 异常处理：裸 except、except Exception 后静默 pass、异常链丢失（raise ... from）
+- 类型提示是否与实际返回值一致，None 是否被正确处理
+- 迭代时修改集合、浅拷贝导致的共享修改
+- eval/exec/pickle/yaml.load 处理不可信输入，subprocess 使用 shell=True 拼接命令`},
+	{Language: "javascript", Title: "JavaScript", Guidance: `- 未处理的 Promise 拒绝、async 函数中遗漏 await、forEach 中使用 async
+- == 与 === 混用导致的隐式类型转换
+- XSS：innerHTML、document.write、未转义的模板插值
+- 原型污染：对不可信对象做深度合并、以用户输入作为属性名
+- 事件监听器、定时器未清理导致的内存泄漏`},
+	{Language: "typescript", Title: "TypeScript", Guidance: `- any、非空断言（!）和类型断言（as）绕过类型检查
+- 未处理的 Promise 拒绝、async 函数中遗漏 await
+- 可选属性和联合类型是否在使用前收窄
+- XSS：innerHTML、未转义的模板插值
+- 事件监听器、定时器、订阅未清理导致的内存泄漏`},
+	{Language: "react", Title: "React", Guidance: `- Hook 依赖数组是否完整，是否存在过期闭包
+- useEffect 中的订阅、定时器、请求是否在清理函数中取消
+- 列表渲染的 key 是否稳定唯一（不使用数组下标）
+- dangerouslySetInnerHTML 是否处理了不可信内容
+- 直接修改 state、在渲染中产生副作用`},
+	{Language: "rust", Title: "Rust", Guidance: `- unwrap/expect 在可失败路径上导致 panic
+- unsafe 块的安全前提是否成立并有注释说明
+- 持有锁跨越 .await、Mutex 中毒处理
+- 不必要的 clone 和内存分配`},
+	{Language: "c", Title: "C", Guidance: `- 缓冲区溢出：strcpy/sprintf/gets 等不安全函数，数组下标越界
+- 内存管理：malloc 返回值未检查、内存泄漏、重复释放、释放后使用
+- 整数溢出和有符号/无符号混用
+- 格式化字符串漏洞（printf(user_input)）`},
+	{Language: "cpp", Title: "C++", Guidance: `- 裸指针所有权不清，应优先使用 RAII 和智能指针
+- 迭代器失效、悬垂引用、返回局部变量的引用
+- 缓冲区越界、整数溢出、未初始化变量
+- 异常安全：析构函数抛出异常、资源在异常路径上泄漏`},
+	{Language: "php", Title: "PHP", Guidance: `- SQL 注入：是否使用预处理语句而非字符串拼接
+- XSS：输出是否使用 htmlspecialchars 转义
+- 文件包含、路径穿越、unserialize 处理不可信数据
+- 弱类型比较（==）导致的认证绕过`},
+	{Language: "ruby", Title: "Ruby", Guidance: `- SQL 注入：where 中使用字符串插值
+- 批量赋值未使用 strong parameters
+- N+1 查询，缺少 includes/preload
+- send/constantize/eval 处理不可信输入`},
+	{Language: "kotlin", Title: "Kotlin", Guidance: `- !! 非空断言导致的 NPE，平台类型的空安全
+- 协程：GlobalScope 泄露、未正确处理取消、在主线程执行阻塞调用
+- 资源是否使用 use 关闭`},
+	{Language: "csharp", Title: "C#", Guidance: `- IDisposable 是否使用 using 释放
+- async void、.Result/.Wait() 导致的死锁
+- 空引用，可空引用类型的警告是否被忽略
+- LINQ 多次枚举、在循环中查询数据库`},
+	{Language: "shell", Title: "Shell", Guidance: `- 变量未加引号导致分词和通配符展开
+- 缺少 set -euo pipefail，命令失败后继续执行
+- 拼接不可信输入执行命令、临时文件使用可预测路径
+- rm -rf 的路径变量可能为空`},
+	{Language: LanguageSQL, Title: "SQL", Guidance: `- SQL 注入：是否以字符串拼接或格式化方式构造语句，应使用参数化查询/预处理语句
+- 动态表名、列名、ORDER BY 是否经过白名单校验
+- UPDATE/DELETE 是否缺少 WHERE 条件
+- 缺少索引的查询条件、SELECT *、大表全表扫描
+- 迁移脚本是否可回滚，是否会长时间锁表`},
+	{Language: "yaml", Title: "YAML 配置", Guidance: `- 配置中是否包含明文密钥、令牌、密码
+- CI 工作流：是否使用未固定版本的第三方 Action，是否将不可信输入（如 PR 标题）拼入脚本
+- 容器配置：特权模式、以 root 运行、缺少资源限制`},
+}
+
+// TemplateRegistry 语言专项检查模板注册表
+type TemplateRegistry struct {
+	templates map[string]LanguageTemplate
+}
+
+// NewTemplateRegistry 创建包含内置模板的注册表
+func NewTemplateRegistry() *TemplateRegistry {
+	r := &TemplateRegistry{templates: make(map[string]LanguageTemplate, len(defaultLanguageTemplates))}
+	for _, t := range defaultLanguageTemplates {
+		r.templates[t.Language] = t
+	}
+	return r
+}
+
+// Templates 返回所有模板，按语言排序
+func (r *TemplateRegistry) Templates() []LanguageTemplate {
+	templates := make([]LanguageTemplate, 0, len(r.templates))
+	for _, t := range r.templates {
+		templates = append(templates, t)
+	}
+	sort.Slice(templates, func(i, j int) bool {
+		return templates[i].Language < templates[j].Language
+	})
+	return templates
+}
+
+// WithOverrides 返回应用仓库覆盖后的注册表副本
+// overrides 的键为语言，值为替换的检查清单；值为空字符串表示关闭该语言的专项检查
+func (r *TemplateRegistry) WithOverrides(overrides map[string]string) *TemplateRegistry {
+	if len(overrides) == 0 {
+		return r
+	}
+
+	merged := &TemplateRegistry{templates: make(map[string]LanguageTemplate, len(r.templates))}
+	for lang, t := range r.templates {
+		merged.templates[lang] = t
+	}
+	for lang, guidance := range overrides {
+		lang = strings.ToLower(strings.TrimSpace(lang))
+		if strings.TrimSpace(guidance) == "" {
+			delete(merged.templates, lang)
+			continue
+		}
+		t, ok := merged.templates[lang]
+		if !ok {
+			t = LanguageTemplate{Language: lang, Title: lang}
+		}
+		t.Guidance = strings.TrimSpace(guidance)
+		merged.templates[lang] = t
+	}
+	return merged
+}
+
+// Compose 按变更中涉及的语言组合专项检查，附加到系统提示词末尾
+// 语言按变更行数从多到少排列，最多 maxLanguageSections 种；代码中内嵌 SQL 语句时附加 SQL 检查
+func (r *TemplateRegistry) Compose(changes []diff.FileChange) string {
+	languages := r.Languages(changes)
+	if len(languages) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("\n\n## 语言专项检查\n除上述审查重点外，请针对本次变更涉及的语言额外关注：\n")
+	for _, lang := range languages {
+		t := r.templates[lang]
+		sb.WriteString("\n### ")
+		sb.WriteString(t.Title)
+		sb.WriteString("\n")
+		sb.WriteString(t.Guidance)
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// Languages 返回变更中涉及且有模板的语言，按变更行数从多到少排列
+func (r *TemplateRegistry) Languages(changes []diff.FileChange) []string {
+	weight := make(map[string]int)
+	for _, c := range changes {
+		lines := len(c.Additions) + len(c.Deletions)
+		if _, ok := r.templates[c.Language]; ok {
+			weight[c.Language] += lines
+		}
+		if c.Language != LanguageSQL && embedsSQL(c) {
+			if _, ok := r.templates[LanguageSQL]; ok {
+				weight[LanguageSQL] += lines
+			}
+		}
+	}
+
+	languages := make([]string, 0, len(weight))
+	for lang := range weight {
+		languages = append(languages, lang)
+	}
+	sort.Slice(languages, func(i, j int) bool {
+		if weight[languages[i]] != weight[languages[j]] {
+			return weight[languages[i]] > weight[languages[j]]
+		}
+		return languages[i] < languages[j]
+	})
+
+	if len(languages) > maxLanguageSections {
+		languages = languages[:maxLanguageSections]
+	}
+	return languages
+}
+
+// embedsSQL 新增代码中是否包含 SQL 语句
+func embedsSQL(change diff.FileChange) bool {
+	for _, line := range change.Additions {
+		if sqlStatementRegex.MatchString(line.Content) {
+			return true
+		}
+	}
+	return false
+}
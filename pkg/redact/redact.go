@@ -0,0 +1,401 @@
+package redact
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"code-sentinel/pkg/diff"
+)
+
+// 内置检测器名称
+const (
+	DetectorAWSAccessKey     = "aws_access_key"
+	DetectorAWSSecretKey     = "aws_secret_key"
+	DetectorPrivateKey       = "private_key"
+	DetectorJWT              = "jwt"
+	DetectorGitHubToken      = "github_token"
+	DetectorAPIKey           = "api_key"
+	DetectorConnectionString = "connection_string"
+	DetectorSecretAssignment = "secret_assignment"
+	DetectorHighEntropy      = "high_entropy"
+	DetectorEmail            = "email"
+	DetectorPhone            = "phone"
+)
+
+// Pattern 仓库自定义的脱敏规则
+type Pattern struct {
+	Name    string `json:"name"`
+	Pattern string `json:"pattern"` // 正则表达式，含捕获组时只替换第一个捕获组
+}
+
+// 与 pkg/secrets 共用的密钥格式，两处检测保持一致
+var (
+	// AWSAccessKey AWS 访问密钥 ID
+	AWSAccessKey = regexp.MustCompile(`\b(?:AKIA|ASIA|AGPA|AIDA|AROA|ANPA|ANVA|AIPA)[0-9A-Z]{16}\b`)
+	// AWSSecretKey AWS 私有访问密钥，第一个捕获组为密钥值
+	AWSSecretKey = regexp.MustCompile(`(?i)aws.{0,20}?(?:secret|private).{0,20}?["'=:\s]+([A-Za-z0-9/+]{40})\b`)
+	// GitHubToken GitHub 个人、OAuth、App 令牌和细粒度令牌
+	GitHubToken = regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,255}|github_pat_[A-Za-z0-9_]{40,255})\b`)
+
+	// PrivateKeyBegin 私钥块起始标记
+	PrivateKeyBegin = regexp.MustCompile(`-----BEGIN [A-Z0-9 ]*PRIVATE KEY-----`)
+	// PrivateKeyEnd 私钥块结束标记
+	PrivateKeyEnd = regexp.MustCompile(`-----END [A-Z0-9 ]*PRIVATE KEY-----`)
+	// PrivateKeyBody 私钥正文行：base64 数据或 Proc-Type 等头部
+	PrivateKeyBody = regexp.MustCompile("^[\\s\"'`+]*(?:[A-Za-z0-9+/=]{16,}|[A-Za-z-]+: \\S+)")
+)
+
+// SecretAssignmentPattern 密码、令牌等变量赋值的字符串字面量，第一个捕获组为至少 minLen 个字符的值
+func SecretAssignmentPattern(minLen int) *regexp.Regexp {
+	return regexp.MustCompile(fmt.Sprintf(`(?i)(?:passw(?:or)?d|pwd|secret|token|api[_-]?key|access[_-]?key|auth[_-]?key|credential)[a-z0-9_.-]*["']?\s*(?::=|=>|[:=])\s*["']([^"'\s]{%d,})["']`, minLen))
+}
+
+// detector 单个检测规则，re 含捕获组时只替换第一个捕获组
+type detector struct {
+	name  string
+	re    *regexp.Regexp
+	check func(value string) bool // 额外校验，返回 false 时不脱敏
+}
+
+// builtinDetectors 内置检测器，按顺序执行，先执行的规则优先
+var builtinDetectors = []detector{
+	{name: DetectorAWSAccessKey, re: AWSAccessKey},
+	{name: DetectorAWSSecretKey, re: AWSSecretKey},
+	{name: DetectorJWT, re: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}`)},
+	{name: DetectorGitHubToken, re: GitHubToken},
+	{name: DetectorAPIKey, re: regexp.MustCompile(`\b(?:sk-[A-Za-z0-9_-]{20,}|xox[abprs]-[A-Za-z0-9-]{10,}|AIza[0-9A-Za-z_-]{35})`)},
+	{name: DetectorConnectionString, re: regexp.MustCompile(`\b[a-zA-Z][a-zA-Z0-9+.-]*://[^\s:/@'"]+:([^\s@'"/]+)@`)},
+	{
+		name:  DetectorSecretAssignment,
+		re:    SecretAssignmentPattern(6),
+		check: notReference,
+	},
+	{
+		name:  DetectorHighEntropy,
+		re:    regexp.MustCompile("[\"'`]([A-Za-z0-9+/=_-]{20,})[\"'`]"),
+		check: randomToken,
+	},
+	{
+		name:  DetectorEmail,
+		re:    regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}\b`),
+		check: realEmail,
+	},
+	{name: DetectorPhone, re: regexp.MustCompile(`(?:^|\D)(1[3-9]\d{9})(?:\D|$)`)},
+	{name: DetectorPhone, re: regexp.MustCompile(`\+(?:\d{1,3}[ .-]\(?\d{1,4}\)?(?:[ .-]\d{2,4}){2,4}|\d{10,14})\b`)},
+	{name: DetectorPhone, re: regexp.MustCompile(`(?:^|[^\d.])(\(?\d{3}\)?[ .-]\d{3}[ .-]\d{4})\b`)},
+}
+
+var privateKeyBlock = regexp.MustCompile(`(?s)-----BEGIN [A-Z0-9 ]*PRIVATE KEY-----.*?-----END [A-Z0-9 ]*PRIVATE KEY-----`)
+
+// Redactor 将敏感信息替换为 [REDACTED:类型#序号] 形式的占位符
+// 同一个 Redactor 内相同的值始终替换为同一占位符，可在一次审查的多次调用间共享
+type Redactor struct {
+	detectors []detector
+
+	mu           sync.Mutex
+	placeholders map[string]string // 类型 + 原值 -> 占位符
+	seq          map[string]int    // 各类型已分配的序号
+	counts       map[string]int    // 各类型的替换次数
+}
+
+// New 创建脱敏器，自定义规则在内置规则之后执行
+// 无法编译的自定义规则被跳过并在 error 中返回，返回的 Redactor 始终可用
+func New(custom []Pattern) (*Redactor, error) {
+	detectors := append([]detector{}, builtinDetectors...)
+	var errs []error
+	for _, p := range custom {
+		if p.Pattern == "" {
+			continue
+		}
+		re, err := regexp.Compile(p.Pattern)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid redact pattern %q: %w", p.Name, err))
+			continue
+		}
+		name := p.Name
+		if name == "" {
+			name = "custom"
+		}
+		detectors = append(detectors, detector{name: name, re: re})
+	}
+
+	return &Redactor{
+		detectors:    detectors,
+		placeholders: make(map[string]string),
+		seq:          make(map[string]int),
+		counts:       make(map[string]int),
+	}, errors.Join(errs...)
+}
+
+// Changes 返回脱敏后的变更副本，跨行的私钥块整体替换
+func (r *Redactor) Changes(changes []diff.FileChange) []diff.FileChange {
+	redacted := make([]diff.FileChange, len(changes))
+	for i, change := range changes {
+		redacted[i] = change.MapLines(r.lines)
+	}
+	return redacted
+}
+
+// Text 对任意文本（如完整文件内容）脱敏
+func (r *Redactor) Text(text string) string {
+	text = privateKeyBlock.ReplaceAllStringFunc(text, func(block string) string {
+		return r.placeholder(DetectorPrivateKey, block)
+	})
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = r.line(line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Counts 返回各类型的替换次数
+func (r *Redactor) Counts() map[string]int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	counts := make(map[string]int, len(r.counts))
+	for k, v := range r.counts {
+		counts[k] = v
+	}
+	return counts
+}
+
+// Total 返回替换总次数
+func (r *Redactor) Total() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	total := 0
+	for _, v := range r.counts {
+		total += v
+	}
+	return total
+}
+
+// Summary 返回 "类型 x次数" 形式的摘要，按类型排序
+func (r *Redactor) Summary() string {
+	counts := r.Counts()
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, fmt.Sprintf("%s x%d", name, counts[name]))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// lines 逐行脱敏，从 BEGIN 行到 END 行（或私钥正文结束）的私钥块使用同一占位符
+func (r *Redactor) lines(lines []diff.Line) []diff.Line {
+	if len(lines) == 0 {
+		return lines
+	}
+
+	out := make([]diff.Line, len(lines))
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		loc := PrivateKeyBegin.FindStringIndex(line.Content)
+		if loc == nil {
+			line.Content = r.line(line.Content)
+			out[i] = line
+			continue
+		}
+
+		end := i
+		for !PrivateKeyEnd.MatchString(lines[end].Content) && end < len(lines)-1 {
+			next := lines[end+1].Content
+			if !PrivateKeyEnd.MatchString(next) && !PrivateKeyBody.MatchString(next) {
+				break
+			}
+			end++
+		}
+		var block strings.Builder
+		for _, l := range lines[i : end+1] {
+			block.WriteString(l.Content)
+			block.WriteString("\n")
+		}
+		placeholder := r.placeholder(DetectorPrivateKey, block.String())
+
+		line.Content = line.Content[:loc[0]] + placeholder
+		out[i] = line
+		for j := i + 1; j <= end; j++ {
+			out[j] = lines[j]
+			content := lines[j].Content
+			if loc := PrivateKeyEnd.FindStringIndex(content); loc != nil {
+				// 保留 END 标记之后的代码（如引号、括号）
+				out[j].Content = placeholder + r.line(content[loc[1]:])
+				continue
+			}
+			indent := content[:len(content)-len(strings.TrimLeft(content, " \t"))]
+			out[j].Content = indent + placeholder
+		}
+		i = end
+	}
+	return out
+}
+
+// line 对单行文本依次执行所有检测器
+func (r *Redactor) line(text string) string {
+	for _, d := range r.detectors {
+		text = r.apply(d, text)
+	}
+	return text
+}
+
+// apply 执行单个检测器，含捕获组时只替换第一个捕获组
+func (r *Redactor) apply(d detector, text string) string {
+	matches := d.re.FindAllStringSubmatchIndex(text, -1)
+	if len(matches) == 0 {
+		return text
+	}
+
+	var sb strings.Builder
+	last := 0
+	for _, m := range matches {
+		start, end := m[0], m[1]
+		if len(m) >= 4 && m[2] >= 0 {
+			start, end = m[2], m[3]
+		}
+		value := text[start:end]
+		if value == "" || strings.HasPrefix(value, "[REDACTED:") || (d.check != nil && !d.check(value)) {
+			continue
+		}
+		sb.WriteString(text[last:start])
+		sb.WriteString(r.placeholder(d.name, value))
+		last = end
+	}
+	sb.WriteString(text[last:])
+	return sb.String()
+}
+
+// placeholder 返回值对应的占位符并计数
+func (r *Redactor) placeholder(name, value string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.counts[name]++
+	key := name + "\x00" + value
+	if p, ok := r.placeholders[key]; ok {
+		return p
+	}
+	r.seq[name]++
+	p := fmt.Sprintf("[REDACTED:%s#%d]", name, r.seq[name])
+	r.placeholders[key] = p
+	return p
+}
+
+// notReference 排除环境变量、模板变量等引用形式的值
+func notReference(value string) bool {
+	for _, marker := range []string{"${", "{{", "%(", "<", "process.env", "os.getenv"} {
+		if strings.Contains(strings.ToLower(value), marker) {
+			return false
+		}
+	}
+	return true
+}
+
+// HighEntropy 判断字符串是否像随机生成的密钥：同时包含字母和数字，且香农熵足够高
+func HighEntropy(value string) bool {
+	hasDigit := strings.ContainsAny(value, "0123456789")
+	hasLetter := strings.IndexFunc(value, func(c rune) bool {
+		return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+	}) >= 0
+	if !hasDigit || !hasLetter {
+		return false
+	}
+
+	threshold := 4.0
+	if strings.Trim(strings.ToLower(value), "0123456789abcdef") == "" {
+		// 十六进制字符集较小，熵的上限为 4
+		if len(value) < 32 {
+			return false
+		}
+		threshold = 3.0
+	}
+	return shannonEntropy(value) >= threshold
+}
+
+// shannonEntropy 计算每个字符的香农熵（比特）
+func shannonEntropy(s string) float64 {
+	freq := make(map[rune]int)
+	n := 0
+	for _, c := range s {
+		freq[c]++
+		n++
+	}
+	entropy := 0.0
+	for _, count := range freq {
+		p := float64(count) / float64(n)
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+// digestLengths MD5、SHA-1、SHA-224、SHA-256、SHA-384、SHA-512 十六进制摘要的长度
+var digestLengths = map[int]bool{32: true, 40: true, 56: true, 64: true, 96: true, 128: true}
+
+// binaryMagics 常见测试数据（图片、压缩包、PDF）的文件头，base64 解码后以此开头的不是密钥
+var binaryMagics = []string{"\x89PNG", "GIF8", "\xff\xd8\xff", "%PDF", "\x1f\x8b", "PK\x03\x04"}
+
+// randomToken 高熵字符串中排除哈希摘要、SRI 完整性校验值和编码的测试数据
+func randomToken(value string) bool {
+	if !HighEntropy(value) {
+		return false
+	}
+	if digestLengths[len(value)] && strings.Trim(strings.ToLower(value), "0123456789abcdef") == "" {
+		return false
+	}
+	for _, prefix := range []string{"sha256-", "sha384-", "sha512-"} {
+		if strings.HasPrefix(value, prefix) {
+			return false
+		}
+	}
+	return !encodedFixture(value)
+}
+
+// encodedFixture 判断 base64 字符串解码后是否为可读文本或常见文件格式
+func encodedFixture(value string) bool {
+	var decoded []byte
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.URLEncoding, base64.RawStdEncoding, base64.RawURLEncoding} {
+		if b, err := enc.DecodeString(value); err == nil {
+			decoded = b
+			break
+		}
+	}
+	if len(decoded) == 0 {
+		return false
+	}
+	for _, magic := range binaryMagics {
+		if strings.HasPrefix(string(decoded), magic) {
+			return true
+		}
+	}
+	printable := 0
+	for _, c := range decoded {
+		if c == '\n' || c == '\t' || (c >= 0x20 && c < 0x7f) {
+			printable++
+		}
+	}
+	return printable*100 >= len(decoded)*95
+}
+
+// realEmail 排除 git 地址和示例域名
+func realEmail(value string) bool {
+	lower := strings.ToLower(value)
+	if strings.HasPrefix(lower, "git@") {
+		return false
+	}
+	for _, domain := range []string{"@example.com", "@example.org", "@example.net", "@users.noreply.github.com"} {
+		if strings.HasSuffix(lower, domain) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,190 @@
+package secrets
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"strings"
+
+	"code-sentinel/pkg/diff"
+	"code-sentinel/pkg/redact"
+)
+
+// 密钥类型
+const (
+	TypeGitHubToken       = "github_token"
+	TypeAWSAccessKey      = "aws_access_key"
+	TypeAWSSecretKey      = "aws_secret_key"
+	TypeGCPServiceAccount = "gcp_service_account"
+	TypeSlackToken        = "slack_token"
+	TypeSlackWebhook      = "slack_webhook"
+	TypePrivateKey        = "private_key"
+	TypeGeneric           = "generic_secret"
+)
+
+// Finding 新增行中检测到的密钥
+type Finding struct {
+	Type    string
+	File    string
+	Line    int
+	EndLine int // 私钥块的结束行，单行密钥与 Line 相同
+	// Secret 密钥原值，只用于遮盖和计算指纹，不得写入审查结果或发送给模型
+	Secret string
+	// Values 需要从发送给模型的内容中移除的原文，私钥块为块内各行
+	Values []string
+}
+
+// Fingerprint 密钥指纹，用于 allowlist，不可逆推原值
+func (f Finding) Fingerprint() string {
+	sum := sha256.Sum256([]byte(f.Type + ":" + f.Secret))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Masked 只保留前 4 个字符的密钥展示形式
+func (f Finding) Masked() string {
+	if f.Type == TypePrivateKey {
+		return "-----BEGIN ... PRIVATE KEY-----"
+	}
+	runes := []rune(f.Secret)
+	if len(runes) <= 8 {
+		return "****"
+	}
+	return string(runes[:4]) + "****"
+}
+
+// detector 单行检测规则，re 含捕获组时密钥为第一个捕获组
+type detector struct {
+	typ   string
+	re    *regexp.Regexp
+	check func(value string) bool // 额外校验，返回 false 时忽略
+}
+
+// detectors 按提供商格式检测，先执行的规则优先，同一值只报告一次
+var detectors = []detector{
+	{typ: TypeGitHubToken, re: redact.GitHubToken},
+	{typ: TypeAWSAccessKey, re: redact.AWSAccessKey},
+	{typ: TypeAWSSecretKey, re: redact.AWSSecretKey, check: redact.HighEntropy},
+	{typ: TypeGCPServiceAccount, re: regexp.MustCompile(`"private_key_id"\s*:\s*"([0-9a-f]{40})"`)},
+	{typ: TypeSlackToken, re: regexp.MustCompile(`\b(xox[abposr]-[0-9A-Za-z-]{10,})`)},
+	{typ: TypeSlackWebhook, re: regexp.MustCompile(`https://hooks\.slack\.com/services/T[A-Z0-9]+/B[A-Z0-9]+/[A-Za-z0-9]{16,}`)},
+	{
+		typ:   TypeGeneric,
+		re:    redact.SecretAssignmentPattern(16),
+		check: redact.HighEntropy,
+	},
+}
+
+// Scan 检测所有新增行中的密钥，私钥块从 BEGIN 行起按一处报告
+func Scan(changes []diff.FileChange) []Finding {
+	var findings []Finding
+	for _, change := range changes {
+		lines := change.Additions
+		for i := 0; i < len(lines); i++ {
+			if redact.PrivateKeyBegin.MatchString(lines[i].Content) {
+				end := privateKeyBlockEnd(lines, i)
+				findings = append(findings, privateKeyFinding(change.Filename, lines[i:end+1]))
+				i = end
+				continue
+			}
+			findings = append(findings, scanLine(change.Filename, lines[i])...)
+		}
+	}
+	return findings
+}
+
+// scanLine 对单行执行所有检测器
+func scanLine(file string, line diff.Line) []Finding {
+	var findings []Finding
+	seen := make(map[string]bool)
+	for _, d := range detectors {
+		for _, m := range d.re.FindAllStringSubmatch(line.Content, -1) {
+			value := m[0]
+			if len(m) > 1 && m[1] != "" {
+				value = m[1]
+			}
+			if seen[value] || (d.check != nil && !d.check(value)) {
+				continue
+			}
+			seen[value] = true
+			findings = append(findings, Finding{
+				Type:    d.typ,
+				File:    file,
+				Line:    line.Number,
+				EndLine: line.Number,
+				Secret:  value,
+				Values:  []string{value},
+			})
+		}
+	}
+	return findings
+}
+
+// privateKeyBlockEnd 返回私钥块最后一行的下标：END 标记所在行，或私钥正文结束处
+func privateKeyBlockEnd(lines []diff.Line, begin int) int {
+	end := begin
+	for !redact.PrivateKeyEnd.MatchString(lines[end].Content) && end < len(lines)-1 {
+		next := lines[end+1]
+		if next.Number != lines[end].Number+1 ||
+			(!redact.PrivateKeyEnd.MatchString(next.Content) && !redact.PrivateKeyBody.MatchString(next.Content)) {
+			break
+		}
+		end++
+	}
+	return end
+}
+
+// privateKeyFinding 私钥块整体作为一处发现，块内每行都需要遮盖
+func privateKeyFinding(file string, block []diff.Line) Finding {
+	f := Finding{
+		Type:    TypePrivateKey,
+		File:    file,
+		Line:    block[0].Number,
+		EndLine: block[len(block)-1].Number,
+	}
+	var sb strings.Builder
+	for _, l := range block {
+		content := strings.TrimSpace(l.Content)
+		if loc := redact.PrivateKeyBegin.FindStringIndex(content); loc != nil && len(block) > 1 {
+			// BEGIN 前的代码（如变量名）不需要遮盖
+			content = content[loc[0]:]
+		}
+		sb.WriteString(content)
+		sb.WriteString("\n")
+		if content != "" {
+			f.Values = append(f.Values, content)
+		}
+	}
+	f.Secret = sb.String()
+	return f
+}
+
+// Mask 将变更中的密钥原文替换为 placeholder，返回副本
+func Mask(changes []diff.FileChange, values []string, placeholder string) []diff.FileChange {
+	if len(values) == 0 {
+		return changes
+	}
+	masked := make([]diff.FileChange, len(changes))
+	for i, change := range changes {
+		masked[i] = change.MapLines(func(lines []diff.Line) []diff.Line {
+			return maskLines(lines, values, placeholder)
+		})
+	}
+	return masked
+}
+
+// MaskText 将文本中的密钥原文替换为 placeholder
+func MaskText(text string, values []string, placeholder string) string {
+	for _, v := range values {
+		text = strings.ReplaceAll(text, v, placeholder)
+	}
+	return text
+}
+
+func maskLines(lines []diff.Line, values []string, placeholder string) []diff.Line {
+	out := make([]diff.Line, len(lines))
+	for i, l := range lines {
+		l.Content = MaskText(l.Content, values, placeholder)
+		out[i] = l
+	}
+	return out
+}
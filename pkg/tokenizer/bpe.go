@@ -0,0 +1,269 @@
+package tokenizer
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkoukk/tiktoken-go"
+)
+
+const (
+	// vocabDownloadTimeout 从 OpenAI 下载单个词表的超时
+	vocabDownloadTimeout = 30 * time.Second
+	// vocabRetryInterval 词表加载失败后再次尝试的间隔
+	vocabRetryInterval = 10 * time.Minute
+)
+
+// errVocabLoading 词表正在另一个 goroutine 中加载
+var errVocabLoading = errors.New("vocabulary is loading")
+
+// bpeEncoding 编码族的加载状态，err 非空表示最近一次加载失败，failedAt 之后 vocabRetryInterval 内不再重试
+type bpeEncoding struct {
+	tk       *tiktoken.Tiktoken
+	err      error
+	failedAt time.Time
+	loading  bool
+}
+
+var (
+	bpeMu        sync.Mutex
+	bpeEncodings = make(map[Encoding]*bpeEncoding)
+)
+
+// vocab 替换 tiktoken 默认的加载器：默认加载器会无超时地下载词表，并缓存在共享的临时目录中不做校验
+var vocab = &vocabLoader{client: &http.Client{Timeout: vocabDownloadTimeout}}
+
+func init() {
+	tiktoken.SetBpeLoader(vocab)
+}
+
+// SetVocabDir 从本地目录读取词表（cl100k_base.tiktoken、o200k_base.tiktoken），文件须与 tiktoken 发布的版本一致
+// 离线部署预先把词表放入该目录即可，不访问网络；需在首次计数前调用
+func SetVocabDir(dir string) {
+	vocab.dir = dir
+}
+
+// EnableVocabDownload 允许从 OpenAI 公开地址下载本地目录中缺失的词表（超时 vocabDownloadTimeout），
+// 校验通过后保存到 SetVocabDir 设置的目录；未启用时不访问网络。需在首次计数前调用
+func EnableVocabDownload() {
+	vocab.download = true
+}
+
+// Preload 加载模型使用的词表，返回加载失败的原因；未知编码族的模型不需要词表
+// 启用下载时可能需要访问网络，应在后台调用
+func Preload(model string) error {
+	enc := EncodingForModel(model)
+	if enc == EncodingUnknown {
+		return nil
+	}
+	_, err := loadEncoding(enc)
+	if errors.Is(err, errVocabLoading) {
+		return nil
+	}
+	return err
+}
+
+// loadEncoding 同步加载编码族的词表，加载成功后不再重新加载
+// 已有其他调用正在加载时不等待，直接返回 errVocabLoading
+func loadEncoding(enc Encoding) (*tiktoken.Tiktoken, error) {
+	bpeMu.Lock()
+	e, ok := bpeEncodings[enc]
+	if !ok {
+		e = &bpeEncoding{}
+		bpeEncodings[enc] = e
+	}
+	switch {
+	case e.tk != nil:
+		bpeMu.Unlock()
+		return e.tk, nil
+	case e.loading:
+		bpeMu.Unlock()
+		return nil, errVocabLoading
+	case e.err != nil && time.Since(e.failedAt) < vocabRetryInterval:
+		bpeMu.Unlock()
+		return nil, e.err
+	}
+	e.loading = true
+	bpeMu.Unlock()
+
+	tk, err := tiktoken.GetEncoding(string(enc))
+	if err != nil {
+		err = fmt.Errorf("load %s vocabulary: %w", enc, err)
+	}
+
+	bpeMu.Lock()
+	defer bpeMu.Unlock()
+	e.loading = false
+	e.tk, e.err = tk, err
+	if err != nil {
+		e.failedAt = time.Now()
+	}
+	return tk, err
+}
+
+// cachedEncoding 返回已加载的词表；尚未加载或可以重试时在后台加载，本次返回 nil
+// 计数不会因为下载词表而阻塞
+func cachedEncoding(enc Encoding) *tiktoken.Tiktoken {
+	bpeMu.Lock()
+	e, ok := bpeEncodings[enc]
+	if ok && e.tk != nil {
+		bpeMu.Unlock()
+		return e.tk
+	}
+	start := !ok || (!e.loading && time.Since(e.failedAt) >= vocabRetryInterval)
+	bpeMu.Unlock()
+
+	if start {
+		go loadEncoding(enc)
+	}
+	return nil
+}
+
+// bpeCounter 按 tiktoken 词表做真实的 BPE 分词计数，特殊 Token 按普通文本处理
+type bpeCounter struct {
+	tk *tiktoken.Tiktoken
+}
+
+func (c bpeCounter) Count(text string) int {
+	if text == "" {
+		return 0
+	}
+	return len(c.tk.EncodeOrdinary(text))
+}
+
+// vocabHashes 词表文件的 SHA-256，与 tiktoken 发布的值一致；校验不通过的文件不使用也不缓存
+var vocabHashes = map[string]string{
+	"cl100k_base.tiktoken": "223921b76ee99bde995b7ff738513eef100fb51d18c93597a113bcffe865b2a7",
+	"o200k_base.tiktoken":  "446a9538cb6c348e3516120d7c08b09f57c36495e2acfffe59a5bf8b0cfb1a2d",
+}
+
+// errNoVocabSource 既没有本地词表目录，也没有启用下载
+var errNoVocabSource = errors.New("no tokenizer vocabulary source: set llm.tokenizer_dir or enable llm.tokenizer_download")
+
+// vocabLoader 从本地目录读取词表，启用下载时从 OpenAI 下载缺失的词表并保存到本地目录
+type vocabLoader struct {
+	dir      string
+	download bool
+	client   *http.Client
+}
+
+func (l *vocabLoader) LoadTiktokenBpe(url string) (map[string]int, error) {
+	name := path.Base(url)
+	if l.dir != "" {
+		data, err := os.ReadFile(filepath.Join(l.dir, name))
+		if err == nil {
+			verr := verifyVocab(name, data)
+			if verr == nil {
+				return parseVocab(data)
+			}
+			if !l.download {
+				return nil, verr
+			}
+			// 本地文件校验失败且允许下载时重新下载并覆盖
+		} else if !errors.Is(err, os.ErrNotExist) || !l.download {
+			return nil, err
+		}
+	}
+	if !l.download {
+		return nil, errNoVocabSource
+	}
+
+	data, err := l.fetch(url)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyVocab(name, data); err != nil {
+		return nil, err
+	}
+	ranks, err := parseVocab(data)
+	if err != nil {
+		return nil, err
+	}
+	if l.dir != "" {
+		// 保存失败不影响本次使用
+		_ = writeFileAtomic(filepath.Join(l.dir, name), data)
+	}
+	return ranks, nil
+}
+
+// fetch 下载词表，超时为 vocabDownloadTimeout
+func (l *vocabLoader) fetch(url string) ([]byte, error) {
+	resp, err := l.client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download %s: %s", url, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// verifyVocab 校验词表文件的 SHA-256
+func verifyVocab(name string, data []byte) error {
+	want, ok := vocabHashes[name]
+	if !ok {
+		return fmt.Errorf("unknown vocabulary %s", name)
+	}
+	sum := sha256.Sum256(data)
+	if got := hex.EncodeToString(sum[:]); got != want {
+		return fmt.Errorf("vocabulary %s checksum mismatch: got %s, want %s", name, got, want)
+	}
+	return nil
+}
+
+// writeFileAtomic 先写入同目录下随机命名的临时文件再重命名，避免留下不完整的文件
+func writeFileAtomic(file string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(file), filepath.Base(file)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
+
+// parseVocab 解析 tiktoken 词表：每行为 base64 编码的字节序列和合并优先级
+func parseVocab(data []byte) (map[string]int, error) {
+	ranks := make(map[string]int)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		token, rank, ok := bytes.Cut(line, []byte(" "))
+		if !ok {
+			return nil, fmt.Errorf("invalid vocabulary line %q", line)
+		}
+		decoded, err := base64.StdEncoding.DecodeString(string(token))
+		if err != nil {
+			return nil, err
+		}
+		n, err := strconv.Atoi(string(rank))
+		if err != nil {
+			return nil, err
+		}
+		ranks[string(decoded)] = n
+	}
+	return ranks, scanner.Err()
+}
@@ -0,0 +1,51 @@
+package tokenizer
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVocabLoaderRejectsTamperedFile(t *testing.T) {
+	dir := t.TempDir()
+	// 能被解析但内容被篡改的词表
+	if err := os.WriteFile(filepath.Join(dir, "cl100k_base.tiktoken"), []byte("IQ== 0\nIg== 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	l := &vocabLoader{dir: dir}
+	_, err := l.LoadTiktokenBpe("https://openaipublic.blob.core.windows.net/encodings/cl100k_base.tiktoken")
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("err = %v, want checksum mismatch", err)
+	}
+}
+
+func TestVocabLoaderOffline(t *testing.T) {
+	tests := []struct {
+		name string
+		dir  string
+	}{
+		{"no directory", ""},
+		{"missing file", t.TempDir()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := &vocabLoader{dir: tt.dir}
+			_, err := l.LoadTiktokenBpe("https://openaipublic.blob.core.windows.net/encodings/o200k_base.tiktoken")
+			if err == nil {
+				t.Fatal("expected error without a download")
+			}
+			if tt.dir == "" && !errors.Is(err, errNoVocabSource) {
+				t.Errorf("err = %v, want errNoVocabSource", err)
+			}
+		})
+	}
+}
+
+func TestVerifyVocabUnknownFile(t *testing.T) {
+	if err := verifyVocab("p50k_base.tiktoken", nil); err == nil {
+		t.Error("expected error for a vocabulary without a known checksum")
+	}
+}
@@ -0,0 +1,78 @@
+package tokenizer
+
+import "strings"
+
+// modelInfo 模型元数据
+type modelInfo struct {
+	prefix        string
+	contextWindow int
+	encoding      Encoding
+}
+
+// modelTable 模型上下文窗口表，按前缀匹配，越具体的前缀越靠前
+var modelTable = []modelInfo{
+	// OpenAI
+	{"gpt-5", 400000, EncodingO200K},
+	{"gpt-4o-mini", 128000, EncodingO200K},
+	{"gpt-4o", 128000, EncodingO200K},
+	{"gpt-4.1", 1047576, EncodingO200K},
+	{"o1", 200000, EncodingO200K},
+	{"o3", 200000, EncodingO200K},
+	{"o4-mini", 200000, EncodingO200K},
+	{"gpt-4-turbo", 128000, EncodingCL100K},
+	{"gpt-4-1106", 128000, EncodingCL100K},
+	{"gpt-4-0125", 128000, EncodingCL100K},
+	{"gpt-4-32k", 32768, EncodingCL100K},
+	{"gpt-4", 8192, EncodingCL100K},
+	{"gpt-3.5-turbo-instruct", 4096, EncodingCL100K},
+	{"gpt-3.5-turbo", 16385, EncodingCL100K},
+
+	// 通义千问
+	{"qwen-long", 1000000, EncodingUnknown},
+	{"qwen-max", 32768, EncodingUnknown},
+	{"qwen-plus", 131072, EncodingUnknown},
+	{"qwen-turbo", 1000000, EncodingUnknown},
+	{"qwen2.5-coder", 131072, EncodingUnknown},
+	{"qwen3", 131072, EncodingUnknown},
+
+	// 其他常见模型
+	{"deepseek-chat", 65536, EncodingUnknown},
+	{"deepseek-coder", 65536, EncodingUnknown},
+	{"deepseek-reasoner", 65536, EncodingUnknown},
+	{"claude", 200000, EncodingUnknown},
+	{"llama3.1", 131072, EncodingUnknown},
+	{"llama3", 8192, EncodingUnknown},
+	{"codellama", 16384, EncodingUnknown},
+}
+
+// lookup 按表中顺序返回第一个前缀匹配的模型信息，因此更具体的前缀须排在前面
+func lookup(model string) (modelInfo, bool) {
+	name := strings.ToLower(strings.TrimSpace(model))
+	// Azure 等部署名可能带有路径前缀，如 "openai/gpt-4o"
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+
+	for _, info := range modelTable {
+		if strings.HasPrefix(name, info.prefix) {
+			return info, true
+		}
+	}
+	return modelInfo{}, false
+}
+
+// ContextWindow 返回模型上下文窗口大小，未知模型返回 false，需要在配置中指定 context_window
+func ContextWindow(model string) (int, bool) {
+	if info, ok := lookup(model); ok {
+		return info.contextWindow, true
+	}
+	return 0, false
+}
+
+// EncodingForModel 返回模型使用的 BPE 编码族，未知时返回 EncodingUnknown
+func EncodingForModel(model string) Encoding {
+	if info, ok := lookup(model); ok {
+		return info.encoding
+	}
+	return EncodingUnknown
+}
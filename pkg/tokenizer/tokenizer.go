@@ -0,0 +1,133 @@
+package tokenizer
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Estimator Token 数估算器
+type Estimator interface {
+	Count(text string) int
+}
+
+// Encoding BPE 编码族
+type Encoding string
+
+const (
+	EncodingCL100K  Encoding = "cl100k_base" // gpt-4 / gpt-3.5
+	EncodingO200K   Encoding = "o200k_base"  // gpt-4o / o1
+	EncodingUnknown Encoding = ""
+)
+
+// preTokenizeRegex 与 tiktoken cl100k/o200k 一致的预分词规则（去掉了 Go 不支持的前瞻分支）
+var preTokenizeRegex = regexp.MustCompile(`(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+`)
+
+// ForModel 返回模型对应的计数器：已知编码族的模型使用 tiktoken 词表分词；
+// 未知模型、词表尚未加载完成或加载失败时退回启发式估算，调用方需预留更多余量（见 Exact 和 prompt.Budget）
+func ForModel(model string) Estimator {
+	switch enc := EncodingForModel(model); enc {
+	case EncodingCL100K, EncodingO200K:
+		if tk := cachedEncoding(enc); tk != nil {
+			return bpeCounter{tk: tk}
+		}
+		return &pieceEstimator{encoding: enc}
+	default:
+		return charEstimator{}
+	}
+}
+
+// Exact 模型的 Token 数是否按真实词表计算
+func Exact(model string) bool {
+	_, ok := ForModel(model).(bpeCounter)
+	return ok
+}
+
+// Count 使用模型对应的估算器计算 Token 数
+func Count(model, text string) int {
+	return ForModel(model).Count(text)
+}
+
+// pieceEstimator 词表加载失败时按预分词片段估算的启发式估算器
+// 先按 tiktoken 的预分词规则切分，再按片段长度粗略估算 BPE 合并后的 Token 数，
+// 与真实分词结果的偏差未经测量，可能偏高也可能偏低
+type pieceEstimator struct {
+	encoding Encoding
+}
+
+func (e *pieceEstimator) Count(text string) int {
+	if text == "" {
+		return 0
+	}
+
+	total := 0
+	for _, piece := range preTokenizeRegex.FindAllString(text, -1) {
+		total += e.countPiece(piece)
+	}
+	return total
+}
+
+// countPiece 估算单个预分词片段的 Token 数
+func (e *pieceEstimator) countPiece(piece string) int {
+	ascii, wide := 0, 0
+	for _, r := range piece {
+		if r < utf8.RuneSelf {
+			ascii++
+		} else {
+			wide++
+		}
+	}
+
+	tokens := 0
+	if ascii > 0 {
+		trimmed := strings.TrimSpace(piece)
+		switch {
+		case trimmed == "":
+			// 连续空白（缩进）通常合并为 1 个 Token
+			tokens += 1 + ascii/16
+		case isWordPiece(trimmed):
+			// 常见单词和标识符片段：6 个字符以内基本是 1 个 Token
+			tokens += 1 + (ascii-1)/6
+		default:
+			// 标点、运算符和数字
+			tokens += 1 + (ascii-1)/3
+		}
+	}
+
+	if wide > 0 {
+		if e.encoding == EncodingO200K {
+			// o200k 词表对中文等非拉丁文字压缩率更高
+			tokens += (wide*7 + 9) / 10
+		} else {
+			tokens += wide
+		}
+	}
+
+	return tokens
+}
+
+// charEstimator 未知模型的启发式估算：ASCII 约 4 字符 1 Token，其他字符 1 Token
+type charEstimator struct{}
+
+func (charEstimator) Count(text string) int {
+	ascii, wide := 0, 0
+	for _, r := range text {
+		if r < utf8.RuneSelf {
+			ascii++
+		} else {
+			wide++
+		}
+	}
+	return (ascii+3)/4 + wide
+}
+
+// isWordPiece 片段是否为字母组成的单词
+func isWordPiece(s string) bool {
+	for _, r := range s {
+		if !unicode.IsLetter(r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,21 @@
+import { client } from './client';
+import type { Advisory, AdvisoryImportResult, PaginatedData } from '@/types';
+
+export interface AdvisoryListParams {
+  page?: number;
+  page_size?: number;
+  ecosystem?: string;
+  package?: string;
+  search?: string;
+}
+
+export const advisoriesApi = {
+  list: (params: AdvisoryListParams = {}) =>
+    client.get<unknown, PaginatedData<Advisory>>('/advisories', { params }),
+
+  // 请求体为 OSV JSON（单个或数组）或 OSV 按生态导出的 zip
+  import: (data: Blob) =>
+    client.post<unknown, AdvisoryImportResult>('/advisories/import', data, {
+      headers: { 'Content-Type': 'application/octet-stream' },
+    }),
+};
@@ -1,3 +1,5 @@
 export { reposApi } from './repos';
 export { reviewsApi } from './reviews';
 export { feedbacksApi } from './feedbacks';
+export { promptsApi } from './prompts';
+export { advisoriesApi } from './advisories';
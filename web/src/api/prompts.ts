@@ -0,0 +1,49 @@
+import { client } from './client';
+import type { PromptVersion, PromptExperiment, ExperimentVariant, ExperimentReport, PlaygroundResult, RepoConfig } from '@/types';
+
+export interface CreatePromptVersionRequest {
+  name: string;
+  content: string;
+  note?: string;
+}
+
+export interface CreateExperimentRequest {
+  name: string;
+  variants: ExperimentVariant[];
+}
+
+// review_id 与 diff 二选一，config 为空时使用仓库当前配置
+export interface PlaygroundRequest {
+  review_id?: number;
+  diff?: string;
+  repo?: string;
+  config?: Partial<RepoConfig>;
+}
+
+export const promptsApi = {
+  // 提示词版本不可修改，同名创建时版本号加一
+  listVersions: (name?: string) =>
+    client.get<unknown, PromptVersion[]>('/prompt-versions', { params: { name } }),
+
+  getVersion: (id: number) =>
+    client.get<unknown, PromptVersion>(`/prompt-versions/${id}`),
+
+  createVersion: (data: CreatePromptVersionRequest) =>
+    client.post<unknown, PromptVersion>('/prompt-versions', data),
+
+  listExperiments: (repoId: number) =>
+    client.get<unknown, PromptExperiment[]>(`/repos/${repoId}/experiments`),
+
+  createExperiment: (repoId: number, data: CreateExperimentRequest) =>
+    client.post<unknown, PromptExperiment>(`/repos/${repoId}/experiments`, data),
+
+  stopExperiment: (id: number) =>
+    client.post<unknown, PromptExperiment>(`/experiments/${id}/stop`),
+
+  getReport: (id: number) =>
+    client.get<unknown, ExperimentReport>(`/experiments/${id}/report`),
+
+  // 按候选配置试运行审查，不发布评论
+  runPlayground: (data: PlaygroundRequest) =>
+    client.post<unknown, PlaygroundResult>('/playground/run', data),
+};
@@ -1,5 +1,5 @@
 import { client } from './client';
-import type { Repo, PaginatedData, RepoConfig, ConfigTemplate } from '@/types';
+import type { Repo, PaginatedData, RepoConfig, ConfigTemplate, LanguageTemplate, ReviewRule, RuleStats, BuiltinPatternRule } from '@/types';
 
 export interface RepoListParams {
   page?: number;
@@ -20,6 +20,19 @@ export interface UpdateRepoRequest {
   config?: RepoConfig;
 }
 
+// 创建或更新审查规则，更新时 rule_id 不可修改
+export interface RuleRequest {
+  rule_id?: string;
+  description: string;
+  severity: string;
+  category: string;
+  paths?: string[];
+  examples?: string;
+  pattern?: string;
+  languages?: string[];
+  enabled?: boolean;
+}
+
 export const reposApi = {
   list: (params: RepoListParams = {}) =>
     client.get<unknown, PaginatedData<Repo>>('/repos', { params }),
@@ -41,4 +54,28 @@ export const reposApi = {
 
   getTemplates: () =>
     client.get<unknown, { templates: ConfigTemplate[] }>('/config-templates'),
+
+  // 语言专项检查模板，传入 repo 时返回应用仓库覆盖后的模板
+  getLanguageTemplates: (repo?: string) =>
+    client.get<unknown, { templates: LanguageTemplate[] }>('/language-templates', { params: { repo } }),
+
+  // 仓库审查规则
+  listRules: (id: number) =>
+    client.get<unknown, ReviewRule[]>(`/repos/${id}/rules`),
+
+  createRule: (id: number, data: RuleRequest) =>
+    client.post<unknown, ReviewRule>(`/repos/${id}/rules`, data),
+
+  updateRule: (ruleId: number, data: RuleRequest) =>
+    client.put<unknown, ReviewRule>(`/rules/${ruleId}`, data),
+
+  deleteRule: (ruleId: number) =>
+    client.delete(`/rules/${ruleId}`),
+
+  getRuleStats: (id: number) =>
+    client.get<unknown, RuleStats[]>(`/repos/${id}/rules/stats`),
+
+  // 内置模式规则
+  listBuiltinRules: () =>
+    client.get<unknown, BuiltinPatternRule[]>('/builtin-rules'),
 };
@@ -1,5 +1,5 @@
 import { client } from './client';
-import type { Review, PaginatedData } from '@/types';
+import type { Review, PaginatedData, ComparisonReport, ToolCallTrace } from '@/types';
 
 export interface ReviewListParams {
   page?: number;
@@ -17,4 +17,19 @@ export const reviewsApi = {
 
   get: (id: number) =>
     client.get<unknown, Review>(`/reviews/${id}`),
+
+  // 手动重新审查，bypass_cache 为 true 时强制重新调用模型
+  rerun: (id: number, bypassCache = false) =>
+    client.post<unknown, Review>(`/reviews/${id}/rerun`, { bypass_cache: bypassCache }),
+
+  // 多模型对比报告
+  comparison: (id: number) =>
+    client.get<unknown, ComparisonReport>(`/reviews/${id}/comparison`),
+
+  // 工具调用审查的调用记录
+  toolCalls: (id: number) =>
+    client.get<unknown, ToolCallTrace[]>(`/reviews/${id}/tool-calls`),
+
+  // SSE 进度流地址（EventSource 不经过 axios）
+  eventsUrl: (id: number) => `/api/v1/reviews/${id}/events`,
 };
@@ -11,6 +11,7 @@ const statusConfig: Record<ReviewStatus, { label: string; variant: 'success' | '
   completed: { label: '已完成', variant: 'success' },
   failed: { label: '失败', variant: 'error' },
   skipped: { label: '已跳过', variant: 'secondary' },
+  degraded: { label: '已降级', variant: 'warning' },
 };
 
 export function StatusBadge({ status }: StatusBadgeProps) {
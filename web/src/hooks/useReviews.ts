@@ -1,5 +1,7 @@
-import { useQuery } from '@tanstack/react-query';
+import { useEffect, useState } from 'react';
+import { useQuery, useQueryClient } from '@tanstack/react-query';
 import { reviewsApi, type ReviewListParams } from '@/api/reviews';
+import type { ReviewEvent, ReviewStage } from '@/types';
 
 export const reviewKeys = {
   all: ['reviews'] as const,
@@ -25,3 +27,52 @@ export function useReview(id: number) {
     enabled: !!id,
   });
 }
+
+export interface ReviewProgress {
+  stage: ReviewStage | null;
+  message: string;
+  output: string;
+  done: boolean;
+}
+
+// 订阅审查进度 SSE，审查结束后刷新列表和详情
+export function useReviewEvents(id: number, enabled: boolean) {
+  const queryClient = useQueryClient();
+  const [progress, setProgress] = useState<ReviewProgress>({ stage: null, message: '', output: '', done: false });
+
+  useEffect(() => {
+    if (!id || !enabled) return;
+
+    setProgress({ stage: null, message: '', output: '', done: false });
+    const source = new EventSource(reviewsApi.eventsUrl(id));
+
+    const handle = (e: MessageEvent) => {
+      const event = JSON.parse(e.data) as ReviewEvent;
+      setProgress((prev) => {
+        switch (event.type) {
+          case 'stage':
+            return { ...prev, stage: event.stage ?? prev.stage, message: event.message ?? '' };
+          case 'token':
+            return { ...prev, output: prev.output + (event.delta ?? '') };
+          case 'done':
+            return { ...prev, message: event.message ?? '', done: true };
+          default:
+            return prev;
+        }
+      });
+      if (event.type === 'done') {
+        source.close();
+        queryClient.invalidateQueries({ queryKey: reviewKeys.all });
+      }
+    };
+
+    source.addEventListener('stage', handle);
+    source.addEventListener('token', handle);
+    source.addEventListener('done', handle);
+    source.onerror = () => source.close();
+
+    return () => source.close();
+  }, [id, enabled, queryClient]);
+
+  return progress;
+}
@@ -1,10 +1,10 @@
 import { useState } from 'react';
 import { Eye, Search } from 'lucide-react';
-import { useReviews } from '@/hooks';
+import { useReviews, useReviewEvents } from '@/hooks';
 import { Button, Input, Select, Table, TableHeader, TableBody, TableRow, TableHead, TableCell, Dialog, DialogHeader, DialogContent } from '@/components/ui';
 import { PageHeader, PageLoading, EmptyState, ErrorMessage, Pagination, StatusBadge, SeverityBadge } from '@/components/common';
 import { formatRelativeTime } from '@/lib/utils';
-import type { Review, ReviewResult, ReviewIssue } from '@/types';
+import type { Review, ReviewResult, ReviewIssue, ReviewStage } from '@/types';
 
 export function ReviewListPage() {
   const [page, setPage] = useState(1);
@@ -56,6 +56,7 @@ export function ReviewListPage() {
           <option value="completed">已完成</option>
           <option value="failed">失败</option>
           <option value="skipped">已跳过</option>
+          <option value="degraded">已降级</option>
           <option value="running">运行中</option>
         </Select>
       </div>
@@ -136,7 +137,19 @@ interface ReviewDetailDialogProps {
   onClose: () => void;
 }
 
+const stageLabels: Record<ReviewStage, string> = {
+  fetching_diff: '获取 Diff',
+  filtering: '过滤文件',
+  prompting: '构建提示词',
+  generating: '模型生成中',
+  verifying: '复核高危问题',
+  posting: '发布评论',
+};
+
 function ReviewDetailDialog({ review, onClose }: ReviewDetailDialogProps) {
+  const inProgress = review?.status === 'pending' || review?.status === 'running';
+  const progress = useReviewEvents(review?.id ?? 0, inProgress);
+
   if (!review) return null;
 
   let result: ReviewResult | null = null;
@@ -164,6 +177,21 @@ function ReviewDetailDialog({ review, onClose }: ReviewDetailDialogProps) {
             </div>
           </div>
 
+          {/* Live Progress */}
+          {inProgress && (
+            <div className="mb-6">
+              <h4 className="font-medium mb-2">
+                审查进度：{progress.stage ? stageLabels[progress.stage] : '等待中'}
+                {progress.message && <span className="ml-2 text-sm text-gray-500">{progress.message}</span>}
+              </h4>
+              {progress.output && (
+                <pre className="p-3 bg-gray-50 rounded-lg text-xs text-gray-700 whitespace-pre-wrap max-h-64 overflow-auto">
+                  {progress.output}
+                </pre>
+              )}
+            </div>
+          )}
+
           {/* Result */}
           {result ? (
             <div className="space-y-4">
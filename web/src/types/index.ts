@@ -24,6 +24,69 @@ export interface RepoConfig {
   max_diff_lines: number;
   auto_review: boolean;
 
+  // 使用指定的提示词版本，仓库有运行中的实验时按实验分配
+  prompt_version_id?: number;
+
+  // 大 Diff 分批审查配置（可选）
+  batch_max_tokens?: number;
+  batch_concurrency?: number;
+  max_batches?: number;
+  context_window?: number;
+
+  // 高严重程度问题复核（可选）
+  verify_enabled?: boolean;
+  verify_model?: string;
+  verify_action?: 'drop' | 'downgrade';
+  // 复核使用服务端 llm.endpoints 中定义的端点名
+  verify_endpoint?: string;
+
+  // 多模型对比（可选），只写模型名时沿用审查使用的提供商和凭据
+  compare_models?: (string | LLMEndpoint)[];
+
+  // 自洽性投票（可选）
+  sample_count?: number;
+  sample_min_votes?: number;
+  sample_temperature?: number;
+
+  // 工具调用审查（可选）
+  agent_enabled?: boolean;
+  agent_max_tool_calls?: number;
+  agent_max_tool_tokens?: number;
+
+  // PR 上下文：PR 标题和描述、提交信息、关联 Issue（长度上限为字符数，0 表示默认值）
+  include_description?: boolean;
+  include_commits?: boolean;
+  include_linked_issues?: boolean;
+  description_max_chars?: number;
+  commits_max_chars?: number;
+  linked_issues_max_chars?: number;
+  // 允许获取关联 Issue 的其他仓库（支持 owner/*），默认只获取本仓库
+  linked_issue_repos?: string[];
+
+  // 仓库编码规范文件（从目标分支读取），guideline_max_tokens 为总 Token 上限
+  guideline_files?: string[];
+  guideline_max_tokens?: number;
+
+  // 语言专项检查覆盖：值为空字符串表示关闭该语言的检查
+  language_prompts?: Record<string, string>;
+
+  // 关闭的内置模式规则 ID
+  disabled_builtin_rules?: string[];
+
+  // 敏感信息脱敏（默认启用）
+  disable_redaction?: boolean;
+  redact_patterns?: RedactPattern[];
+
+  // 密钥扫描（默认启用），allowlist 文件从目标分支读取
+  disable_secret_scan?: boolean;
+  secret_allowlist_file?: string;
+
+  // 依赖变更分析（默认启用），对照导入的 OSV 漏洞库
+  disable_dependency_check?: boolean;
+
+  // 外部检查工具：服务端 linters.tools 中配置的工具名
+  linters?: string[];
+
   // 仓库级 LLM 配置（可选，覆盖全局配置）
   llm_api_key?: string;
   llm_base_url?: string;
@@ -32,10 +95,22 @@ export interface RepoConfig {
   github_token?: string;
 }
 
+// 自定义脱敏规则，pattern 含捕获组时只替换第一个捕获组
+export interface RedactPattern {
+  name: string;
+  pattern: string;
+}
+
+// LLMEndpoint 对比模型，endpoint 为服务端 llm.endpoints 中定义的端点名
+export interface LLMEndpoint {
+  endpoint?: string;
+  model?: string;
+}
+
 export type LLMProvider = 'openai' | 'qwen' | 'azure' | 'ollama';
 export type Severity = 'P0' | 'P1' | 'P2';
 export type ReviewFocus = 'security' | 'performance' | 'logic' | 'style';
-export type ReviewStatus = 'pending' | 'running' | 'completed' | 'failed' | 'skipped';
+export type ReviewStatus = 'pending' | 'running' | 'completed' | 'failed' | 'skipped' | 'degraded';
 
 // 审查相关类型
 export interface Review {
@@ -46,14 +121,39 @@ export interface Review {
   pr_title: string;
   pr_author: string;
   commit_sha: string;
+  base_sha?: string;
   status: ReviewStatus;
   result: string;
   token_used: number;
+  prompt_tokens: number;
+  completion_tokens: number;
+  cost: number;
+  provider: string;
+  model: string;
   duration_ms: number;
+  redactions: number;
+  review_focus: string;
+  issue_count: number;
+  prompt_version_id: number;
+  experiment_id?: number;
+  cache_hit: boolean;
   error_msg?: string;
   created_at: string;
 }
 
+// 审查进度事件（SSE）
+export type ReviewStage = 'fetching_diff' | 'filtering' | 'prompting' | 'generating' | 'verifying' | 'posting';
+
+export interface ReviewEvent {
+  type: 'stage' | 'token' | 'done';
+  review_id: number;
+  stage?: ReviewStage;
+  status?: ReviewStatus;
+  message?: string;
+  delta?: string;
+  time: string;
+}
+
 export interface ReviewResult {
   summary: string;
   issues: ReviewIssue[];
@@ -61,6 +161,96 @@ export interface ReviewResult {
   score?: number;
   model?: string;
   duration_ms?: number;
+  omitted?: OmittedChange[];
+  degraded?: boolean;
+  validation_errors?: string[];
+  raw_output?: string;
+  guard?: GuardReport;
+  verifications?: IssueVerification[];
+  samples?: number;
+  min_votes?: number;
+  redactions?: Record<string, number>;
+  focus?: ReviewFocus[];
+  focus_filtered?: number;
+  guidelines?: GuidelineRef[];
+  rules?: string[];
+  llm_error?: string;
+  dependencies?: DependencyChange[];
+  linters?: LinterRun[];
+}
+
+// 外部检查工具的执行情况
+export interface LinterRun {
+  tool: string;
+  findings: number;
+  kept: number;
+  duration_ms: number;
+  error?: string;
+}
+
+// 依赖清单或锁文件的版本变更
+export type DependencyKind = 'added' | 'removed' | 'upgraded' | 'downgraded' | 'changed';
+
+export interface DependencyChange {
+  ecosystem: string;
+  name: string;
+  file: string;
+  line?: number;
+  old_version?: string;
+  new_version?: string;
+  kind: DependencyKind;
+  direct: boolean;
+  advisories?: string[];
+}
+
+// 注入提示词的仓库规范文件
+export interface GuidelineRef {
+  file: string;
+  ref: string;
+  summarized: boolean;
+}
+
+// 高严重程度问题复核结论
+export interface IssueVerification {
+  file: string;
+  line: number;
+  title: string;
+  severity: Severity;
+  verdict: 'confirmed' | 'refuted' | 'uncertain';
+  reasoning: string;
+  model: string;
+  action: 'kept' | 'dropped' | 'downgraded';
+  error?: string;
+}
+
+// 问题定位校验记录
+export interface GuardReport {
+  checked: number;
+  kept: number;
+  corrected: number;
+  demoted: number;
+  dropped: number;
+  actions?: GuardAction[];
+}
+
+export interface GuardAction {
+  action: 'file_corrected' | 'snapped' | 'file_level' | 'demoted' | 'dropped';
+  title: string;
+  file: string;
+  line: number;
+  new_file?: string;
+  new_line?: number;
+  severity?: string;
+  new_severity?: string;
+  reason: string;
+}
+
+// 因超出 Token 预算未审查的变更
+export interface OmittedChange {
+  file: string;
+  hunk?: string;
+  lines: number;
+  reason: string;
 }
 
 export interface ReviewIssue {
@@ -72,8 +262,16 @@ export interface ReviewIssue {
   description: string;
   suggestion?: string;
   code_fix?: string;
+  guideline?: string;
+  rule_id?: string;
+  votes?: number;
+  source?: IssueSource;
+  tool?: string;
 }
 
+// 问题来源：模型审查 / 模式规则匹配 / 密钥扫描 / 依赖变更分析 / 外部检查工具
+export type IssueSource = 'llm' | 'rule' | 'secret' | 'dependency' | 'linter';
+
 export interface ReviewStats {
   p0_count: number;
   p1_count: number;
@@ -97,6 +295,7 @@ export interface Feedback {
   is_false_positive: boolean;
   reason: string;
   reporter: string;
+  rule_id?: string;
   created_at: string;
 }
 
@@ -120,9 +319,219 @@ export interface PaginatedData<T> {
   page_size: number;
 }
 
+// 语言专项检查模板
+export interface LanguageTemplate {
+  language: string;
+  title: string;
+  guidance: string;
+}
+
 // 配置模板
 export interface ConfigTemplate {
   name: string;
   description: string;
   config: RepoConfig;
 }
+
+// 多模型对比报告
+export interface ComparisonReport {
+  review_id: number;
+  models: ComparisonModel[];
+  findings: ComparisonFinding[];
+  agreement: ModelAgreement[];
+}
+
+export interface ComparisonModel {
+  model: string;
+  provider: string;
+  primary: boolean;
+  status: ReviewStatus;
+  stats: ReviewStats;
+  issue_count: number;
+  unique_count: number;
+  token_used: number;
+  prompt_tokens: number;
+  completion_tokens: number;
+  cost: number;
+  duration_ms: number;
+  error_msg?: string;
+}
+
+export interface ComparisonFinding {
+  file: string;
+  line: number;
+  reports: FindingReport[];
+  agreement: number;
+}
+
+export interface FindingReport {
+  model: string;
+  severity: Severity;
+  category: string;
+  line: number;
+  title: string;
+}
+
+export interface ModelAgreement {
+  model_a: string;
+  model_b: string;
+  shared: number;
+  jaccard: number;
+}
+
+// 工具调用审查的调用记录
+export interface ToolCallTrace {
+  id: number;
+  review_id: number;
+  model: string;
+  round: number;
+  call_id: string;
+  tool: 'read_file' | 'read_base_file' | 'list_directory' | 'search_code';
+  arguments: string;
+  output: string;
+  tokens: number;
+  error?: string;
+  duration_ms: number;
+  created_at: string;
+}
+
+// 仓库自定义审查规则
+export interface ReviewRule {
+  id: number;
+  repo_full_name: string;
+  rule_id: string;
+  description: string;
+  severity: Severity;
+  category: string;
+  paths: string[] | null;
+  examples: string;
+  enabled: boolean;
+  created_at: string;
+  updated_at: string;
+  pattern?: string;
+  languages?: string[] | null;
+}
+
+// 内置模式规则
+export interface BuiltinPatternRule extends ReviewRule {
+  exclude?: string;
+  suggestion: string;
+}
+
+// 审查规则的命中和误报统计
+export interface RuleStats {
+  rule_id: string;
+  hits: number;
+  reviews: number;
+  feedbacks: number;
+  false_positives: number;
+  false_positive_rate: number;
+}
+
+// 不可变的系统提示词版本
+export interface PromptVersion {
+  id: number;
+  name: string;
+  version: number;
+  content: string;
+  note: string;
+  created_at: string;
+}
+
+// 提示词 A/B 实验，prompt_version_id 为 0 表示仓库当前的提示词
+export interface ExperimentVariant {
+  prompt_version_id: number;
+  percent: number;
+}
+
+export interface PromptExperiment {
+  id: number;
+  repo_full_name: string;
+  name: string;
+  status: 'running' | 'stopped';
+  variants: ExperimentVariant[];
+  created_at: string;
+  updated_at: string;
+  stopped_at?: string;
+}
+
+export interface ExperimentReport {
+  experiment: PromptExperiment;
+  variants: VariantStats[];
+}
+
+export interface VariantStats {
+  prompt_version_id: number;
+  prompt_name?: string;
+  prompt_version?: number;
+  percent: number;
+  reviews: number;
+  issues: number;
+  issues_per_pr: number;
+  false_positives: number;
+  feedback_rate: number;
+  avg_tokens: number;
+  avg_duration_ms: number;
+  avg_cost: number;
+}
+
+// 提示词演练
+export interface LLMMessage {
+  role: string;
+  content: string;
+  tool_calls?: unknown[];
+  tool_call_id?: string;
+}
+
+export interface LLMExchange {
+  model: string;
+  messages: LLMMessage[];
+  response: string;
+  usage: { prompt_tokens: number; completion_tokens: number; total_tokens: number };
+}
+
+export interface PlaygroundResult {
+  system_prompt: string;
+  exchanges: LLMExchange[];
+  result: ReviewResult | null;
+  usage: { prompt_tokens: number; completion_tokens: number; total_tokens: number };
+  cost: number;
+  files: number;
+  lines: number;
+  duration_ms: number;
+  skipped?: string;
+}
+
+// OSV 漏洞公告（按受影响的包拆分）
+export interface AdvisoryEvent {
+  introduced?: string;
+  fixed?: string;
+  last_affected?: string;
+  limit?: string;
+}
+
+export interface AdvisoryRange {
+  type: string;
+  events: AdvisoryEvent[];
+}
+
+export interface Advisory {
+  id: number;
+  advisory_id: string;
+  ecosystem: string;
+  package: string;
+  summary: string;
+  aliases?: string[];
+  severity?: string;
+  ranges?: AdvisoryRange[];
+  versions?: string[];
+  modified: string;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface AdvisoryImportResult {
+  entries: number;
+  imported: number;
+  withdrawn: number;
+}